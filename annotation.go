@@ -0,0 +1,356 @@
+package pgs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+const (
+	// annotation markers are private use code points, which pass through both
+	// text/template and html/template unescaped and are removed before any
+	// PostProcessors are applied.
+	annotationOpen  = '\uE000'
+	annotationSep   = '\uE001'
+	annotationClose = '\uE002'
+)
+
+// An Annotator records GeneratedCodeInfo annotations for the contents of a
+// GeneratorFile or GeneratorTemplateFile, mapping spans of the generated code
+// to the descriptors of the Entities they were generated from. IDEs and code
+// indexers use these annotations to navigate from generated code to its proto
+// definition.
+//
+// Spans are marked while building the contents via Annotate, or the
+// "annotate" template function provided by FuncMap:
+//
+//	a := &pgs.Annotator{}
+//	tpl := template.New("file").Funcs(a.FuncMap())
+//	// {{ annotate .Message (name .Message) }}
+//	return pgs.GeneratorTemplateFile{Annotator: a, ...}
+//
+// Annotations are located after all PostProcessors are applied, by diffing
+// the content before and after post-processing. Formatters that only change
+// whitespace keep every annotation, and those that insert or remove content,
+// such as a license header, keep the annotations of unchanged text, while an
+// annotation is dropped if its text was modified.
+//
+// An Annotator is safe for concurrent use, but should only be used with a
+// single Artifact.
+type Annotator struct {
+	mu       sync.Mutex
+	entities []Entity
+}
+
+// Annotate returns text marked as generated from Entity e. The marked text
+// must be included in the contents of the Artifact the Annotator is attached
+// to. Annotations may be nested.
+func (a *Annotator) Annotate(e Entity, text string) string {
+	a.mu.Lock()
+	id := len(a.entities)
+	a.entities = append(a.entities, e)
+	a.mu.Unlock()
+
+	return string(annotationOpen) + strconv.Itoa(id) + string(annotationSep) + text + string(annotationClose)
+}
+
+// FuncMap returns the "annotate" template function, which calls Annotate with
+// the string form of its second argument, such as a Name. The map can be
+// passed to the Funcs method of either a text/template or html/template
+// Template.
+func (a *Annotator) FuncMap() map[string]interface{} {
+	return map[string]interface{}{
+		"annotate": func(e Entity, v interface{}) string { return a.Annotate(e, fmt.Sprint(v)) },
+	}
+}
+
+// strip removes the annotation markers from content, returning the
+// annotations of the marked spans ordered by their offsets.
+func (a *Annotator) strip(content string) (string, *descriptor.GeneratedCodeInfo, error) {
+	if a == nil {
+		return content, nil, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	type open struct {
+		id    int
+		begin int
+	}
+
+	var (
+		buf   strings.Builder
+		stack []open
+		info  = &descriptor.GeneratedCodeInfo{}
+	)
+
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRuneInString(content[i:])
+
+		switch r {
+		case annotationOpen:
+			end := strings.IndexRune(content[i:], annotationSep)
+			if end < 0 {
+				return "", nil, fmt.Errorf("malformed annotation marker at offset %d", i)
+			}
+
+			id, err := strconv.Atoi(content[i+size : i+end])
+			if err != nil || id < 0 || id >= len(a.entities) {
+				return "", nil, fmt.Errorf("unknown annotation %q at offset %d", content[i+size:i+end], i)
+			}
+
+			stack = append(stack, open{id: id, begin: buf.Len()})
+			i += end + utf8.RuneLen(annotationSep)
+			continue
+		case annotationClose:
+			if len(stack) == 0 {
+				return "", nil, fmt.Errorf("unbalanced annotation marker at offset %d", i)
+			}
+
+			o := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			e := a.entities[o.id]
+			info.Annotation = append(info.Annotation, &descriptor.GeneratedCodeInfo_Annotation{
+				Path:       DescriptorPath(e),
+				SourceFile: proto.String(e.File().InputPath().String()),
+				Begin:      proto.Int32(int32(o.begin)),
+				End:        proto.Int32(int32(buf.Len())),
+			})
+		default:
+			buf.WriteString(content[i : i+size])
+		}
+
+		i += size
+	}
+
+	if len(stack) > 0 {
+		return "", nil, fmt.Errorf("%d unterminated annotations", len(stack))
+	}
+
+	sort.SliceStable(info.Annotation, func(i, j int) bool {
+		return info.Annotation[i].GetBegin() < info.Annotation[j].GetBegin()
+	})
+
+	return buf.String(), info, nil
+}
+
+// DescriptorPath returns the path to Entity e's descriptor within its File's
+// FileDescriptorProto, as used by SourceCodeInfo and GeneratedCodeInfo. The
+// path of a File is empty.
+func DescriptorPath(e Entity) []int32 {
+	switch e := e.(type) {
+	case File:
+		return []int32{}
+	case Message:
+		if p, ok := e.Parent().(Message); ok {
+			return indexPath(DescriptorPath(p), messageTypeNestedTypePath, p.Descriptor().GetNestedType(), e.Descriptor())
+		}
+		return indexPath(nil, messageTypePath, e.File().Descriptor().GetMessageType(), e.Descriptor())
+	case Enum:
+		if p, ok := e.Parent().(Message); ok {
+			return indexPath(DescriptorPath(p), messageTypeEnumTypePath, p.Descriptor().GetEnumType(), e.Descriptor())
+		}
+		return indexPath(nil, enumTypePath, e.File().Descriptor().GetEnumType(), e.Descriptor())
+	case Extension:
+		if p, ok := e.DefinedIn().(Message); ok {
+			return indexPath(DescriptorPath(p), messageTypeExtensionPath, p.Descriptor().GetExtension(), e.Descriptor())
+		}
+		return indexPath(nil, extensionPath, e.File().Descriptor().GetExtension(), e.Descriptor())
+	case EnumValue:
+		return indexPath(DescriptorPath(e.Enum()), enumTypeValuePath, e.Enum().Descriptor().GetValue(), e.Descriptor())
+	case Field:
+		return indexPath(DescriptorPath(e.Message()), messageTypeFieldPath, e.Message().Descriptor().GetField(), e.Descriptor())
+	case OneOf:
+		return indexPath(DescriptorPath(e.Message()), messageTypeOneofDeclPath, e.Message().Descriptor().GetOneofDecl(), e.Descriptor())
+	case Service:
+		return indexPath(nil, servicePath, e.File().Descriptor().GetService(), e.Descriptor())
+	case Method:
+		return indexPath(DescriptorPath(e.Service()), serviceTypeMethodPath, e.Service().Descriptor().GetMethod(), e.Descriptor())
+	default:
+		return nil
+	}
+}
+
+// indexPath appends field and the index of desc within list to base. If desc
+// is not found, nil is returned.
+func indexPath[T proto.Message](base []int32, field int32, list []T, desc T) []int32 {
+	for i, d := range list {
+		if proto.Message(d) == proto.Message(desc) {
+			return subpath(base, field, int32(i))
+		}
+	}
+	return nil
+}
+
+// relocateAnnotations updates the annotations of f, which were recorded
+// against the before content, to the after content produced by the
+// PostProcessors. Offsets are mapped through a diff of the non-whitespace
+// bytes of both, so an annotation is kept if its text is only moved or
+// re-indented, and dropped if its text was changed. All offsets are then
+// moved by shift, accounting for any header prepended to the final content.
+func relocateAnnotations(f *plugin_go.CodeGeneratorResponse_File, before, after string, shift int) {
+	info := f.GetGeneratedCodeInfo()
+	if info == nil || (before == after && shift == 0) {
+		return
+	}
+
+	var m *offsetMap
+	if before != after {
+		m = newOffsetMap(before, after)
+	}
+
+	annotations := info.Annotation[:0]
+	for _, a := range info.Annotation {
+		begin, end := int(a.GetBegin()), int(a.GetEnd())
+
+		if m != nil {
+			var ok bool
+			if begin, end, ok = m.span(begin, end); !ok {
+				continue
+			}
+		}
+
+		a.Begin, a.End = proto.Int32(int32(begin+shift)), proto.Int32(int32(end+shift))
+		annotations = append(annotations, a)
+	}
+	info.Annotation = annotations
+}
+
+// offsetMap maps spans of the content before post-processing to the content
+// after it, using the runs of non-whitespace bytes left unchanged between the
+// two.
+type offsetMap struct {
+	before, after string
+
+	// beforeIdx and afterIdx are the offsets of the non-whitespace bytes of
+	// before and after, respectively.
+	beforeIdx, afterIdx []int
+
+	// runs are the unchanged runs of non-whitespace bytes, ordered by their
+	// index in beforeIdx.
+	runs []diffRun
+}
+
+// diffRun is a run of n equal elements at index a of the first sequence, and
+// index b of the second.
+type diffRun struct{ a, b, n int }
+
+func newOffsetMap(before, after string) *offsetMap {
+	m := &offsetMap{before: before, after: after}
+
+	var a, b []byte
+	a, m.beforeIdx = nonSpace(before)
+	b, m.afterIdx = nonSpace(after)
+
+	m.runs = diffRuns(a, b)
+	return m
+}
+
+// nonSpace returns the non-whitespace bytes of s, along with their offsets.
+func nonSpace(s string) ([]byte, []int) {
+	var (
+		out []byte
+		idx []int
+	)
+
+	for i := 0; i < len(s); i++ {
+		if !isSpace(s[i]) {
+			out = append(out, s[i])
+			idx = append(idx, i)
+		}
+	}
+
+	return out, idx
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\f', '\v':
+		return true
+	}
+	return false
+}
+
+// span returns the location in after of the span [begin, end) in before. The
+// non-whitespace bytes of the span must be within a single unchanged run.
+// Whitespace at the edges of the span is kept if it is also unchanged.
+func (m *offsetMap) span(begin, end int) (int, int, bool) {
+	if begin < 0 || end <= begin || end > len(m.before) {
+		return 0, 0, false
+	}
+
+	first := sort.SearchInts(m.beforeIdx, begin)
+	last := sort.SearchInts(m.beforeIdx, end) - 1
+	if first > last {
+		return 0, 0, false
+	}
+
+	r := sort.Search(len(m.runs), func(i int) bool { return m.runs[i].a+m.runs[i].n > first })
+	if r == len(m.runs) || m.runs[r].a > first || last >= m.runs[r].a+m.runs[r].n {
+		return 0, 0, false
+	}
+
+	newBegin := m.afterIdx[m.runs[r].b+first-m.runs[r].a]
+	newEnd := m.afterIdx[m.runs[r].b+last-m.runs[r].a] + 1
+
+	if lead := m.before[begin:m.beforeIdx[first]]; strings.HasSuffix(m.after[:newBegin], lead) {
+		newBegin -= len(lead)
+	}
+	if trail := m.before[m.beforeIdx[last]+1 : end]; strings.HasPrefix(m.after[newEnd:], trail) {
+		newEnd += len(trail)
+	}
+
+	return newBegin, newEnd, true
+}
+
+// diffRuns returns the runs of equal bytes in a shortest edit script between a
+// and b. The diff has no time limit, so the result only depends on its input;
+// its cost grows with the product of the input length and the number of
+// edits, which stays small for typical PostProcessors.
+func diffRuns(a, b []byte) []diffRun {
+	dmp := diffmatchpatch.New()
+	dmp.DiffTimeout = 0
+
+	var (
+		runs []diffRun
+		x, y int
+	)
+
+	for _, d := range dmp.DiffMainRunes(byteRunes(a), byteRunes(b), false) {
+		n := utf8.RuneCountInString(d.Text)
+
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			if n > 0 {
+				runs = append(runs, diffRun{x, y, n})
+			}
+			x, y = x+n, y+n
+		case diffmatchpatch.DiffDelete:
+			x += n
+		case diffmatchpatch.DiffInsert:
+			y += n
+		}
+	}
+
+	return runs
+}
+
+// byteRunes converts each byte of b to a rune, so that b is diffed bytewise
+// regardless of its encoding.
+func byteRunes(b []byte) []rune {
+	out := make([]rune, len(b))
+	for i, c := range b {
+		out[i] = rune(c)
+	}
+	return out
+}
@@ -0,0 +1,318 @@
+package pgs
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func dummyAnnotationAST(t *testing.T) AST {
+	enum := func(name string, values ...string) *descriptor.EnumDescriptorProto {
+		ed := &descriptor.EnumDescriptorProto{Name: proto.String(name)}
+		for i, v := range values {
+			ed.Value = append(ed.Value, &descriptor.EnumValueDescriptorProto{Name: proto.String(v), Number: proto.Int32(int32(i))})
+		}
+		return ed
+	}
+
+	fld := func(name string, num int32, extendee string) *descriptor.FieldDescriptorProto {
+		fd := &descriptor.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(num),
+			Label:  Optional.ProtoPtr(),
+			Type:   StringT.ProtoPtr(),
+		}
+		if extendee != "" {
+			fd.Extendee = proto.String(extendee)
+		}
+		return fd
+	}
+
+	oneof := fld("b", 2, "")
+	oneof.OneofIndex = proto.Int32(0)
+
+	d := InitMockDebugger()
+	ast := ProcessFileDescriptorSet(d, &descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{{
+		Name:    proto.String("foo/foo.proto"),
+		Package: proto.String("foo"),
+		Syntax:  proto.String("proto2"),
+		MessageType: []*descriptor.DescriptorProto{
+			{Name: proto.String("First")},
+			{
+				Name:           proto.String("Second"),
+				Field:          []*descriptor.FieldDescriptorProto{fld("a", 1, ""), oneof},
+				OneofDecl:      []*descriptor.OneofDescriptorProto{{Name: proto.String("choice")}},
+				NestedType:     []*descriptor.DescriptorProto{{Name: proto.String("Skipped")}, {Name: proto.String("Nested")}},
+				EnumType:       []*descriptor.EnumDescriptorProto{enum("Inner", "X", "Y")},
+				Extension:      []*descriptor.FieldDescriptorProto{fld("inner_ext", 101, ".foo.First")},
+				ExtensionRange: []*descriptor.DescriptorProto_ExtensionRange{{Start: proto.Int32(100), End: proto.Int32(200)}},
+			},
+		},
+		EnumType:  []*descriptor.EnumDescriptorProto{enum("Top", "A")},
+		Extension: []*descriptor.FieldDescriptorProto{fld("top_ext", 100, ".foo.Second")},
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name: proto.String("Svc"),
+			Method: []*descriptor.MethodDescriptorProto{
+				{Name: proto.String("Skipped"), InputType: proto.String(".foo.First"), OutputType: proto.String(".foo.First")},
+				{Name: proto.String("Do"), InputType: proto.String(".foo.First"), OutputType: proto.String(".foo.Second")},
+			},
+		}},
+	}}})
+	require.False(t, d.Failed())
+	return ast
+}
+
+func TestDescriptorPath(t *testing.T) {
+	t.Parallel()
+
+	ast := dummyAnnotationAST(t)
+
+	tests := []struct {
+		name     string
+		expected []int32
+	}{
+		{".foo.First", []int32{messageTypePath, 0}},
+		{".foo.Second.Nested", []int32{messageTypePath, 1, messageTypeNestedTypePath, 1}},
+		{".foo.Second.b", []int32{messageTypePath, 1, messageTypeFieldPath, 1}},
+		{".foo.Second.choice", []int32{messageTypePath, 1, messageTypeOneofDeclPath, 0}},
+		{".foo.Second.Inner", []int32{messageTypePath, 1, messageTypeEnumTypePath, 0}},
+		{".foo.Second.Inner.Y", []int32{messageTypePath, 1, messageTypeEnumTypePath, 0, enumTypeValuePath, 1}},
+		{".foo.Second.inner_ext", []int32{messageTypePath, 1, messageTypeExtensionPath, 0}},
+		{".foo.Top", []int32{enumTypePath, 0}},
+		{".foo.top_ext", []int32{extensionPath, 0}},
+		{".foo.Svc", []int32{servicePath, 0}},
+		{".foo.Svc.Do", []int32{servicePath, 0, serviceTypeMethodPath, 1}},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e, ok := ast.Lookup(tc.name)
+			require.True(t, ok)
+			assert.Equal(t, tc.expected, DescriptorPath(e))
+		})
+	}
+
+	assert.Empty(t, DescriptorPath(ast.Targets()["foo/foo.proto"]))
+	assert.Nil(t, DescriptorPath(nil))
+}
+
+func TestAnnotator(t *testing.T) {
+	t.Parallel()
+
+	ast := dummyAnnotationAST(t)
+	msg, _ := ast.Lookup(".foo.Second")
+	fld, _ := ast.Lookup(".foo.Second.a")
+
+	a := &Annotator{}
+	tpl := template.Must(template.New("file").Funcs(a.FuncMap()).Parse(
+		`type {{ annotate . .Name }} struct {
+	{{ annotate (index .Fields 0) "A string" }}
+}
+`))
+
+	f, err := GeneratorTemplateFile{
+		Name:             "foo.go",
+		TemplateArtifact: TemplateArtifact{Template: tpl, Data: msg},
+		Annotator:        a,
+	}.ProtoFile()
+	require.NoError(t, err)
+
+	content := "type Second struct {\n\tA string\n}\n"
+	assert.Equal(t, content, f.GetContent())
+
+	info := f.GetGeneratedCodeInfo()
+	require.Len(t, info.GetAnnotation(), 2)
+
+	m, fd := info.Annotation[0], info.Annotation[1]
+	assert.Equal(t, "foo/foo.proto", m.GetSourceFile())
+	assert.Equal(t, DescriptorPath(msg), m.GetPath())
+	assert.Equal(t, "Second", content[m.GetBegin():m.GetEnd()])
+	assert.Equal(t, DescriptorPath(fld), fd.GetPath())
+	assert.Equal(t, "A string", content[fd.GetBegin():fd.GetEnd()])
+
+	t.Run("nested", func(t *testing.T) {
+		t.Parallel()
+
+		a := &Annotator{}
+		f, err := GeneratorFile{
+			Name:      "foo.go",
+			Contents:  "é" + a.Annotate(msg, "x"+a.Annotate(fld, "y")+"z"),
+			Annotator: a,
+		}.ProtoFile()
+		require.NoError(t, err)
+		assert.Equal(t, "éxyz", f.GetContent())
+
+		info := f.GetGeneratedCodeInfo()
+		require.Len(t, info.GetAnnotation(), 2)
+		assert.Equal(t, int32(2), info.Annotation[0].GetBegin())
+		assert.Equal(t, int32(5), info.Annotation[0].GetEnd())
+		assert.Equal(t, int32(3), info.Annotation[1].GetBegin())
+		assert.Equal(t, int32(4), info.Annotation[1].GetEnd())
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		t.Parallel()
+
+		a := &Annotator{}
+		marked := a.Annotate(msg, "x")
+
+		for _, content := range []string{
+			marked[:len(marked)-3],
+			marked + marked[len(marked)-3:],
+			string(annotationOpen) + "99" + string(annotationSep) + "x" + string(annotationClose),
+			string(annotationOpen) + "x",
+		} {
+			_, err := GeneratorFile{Name: "foo.go", Contents: content, Annotator: a}.ProtoFile()
+			assert.Error(t, err, content)
+		}
+	})
+
+	t.Run("without annotator", func(t *testing.T) {
+		t.Parallel()
+
+		f, err := GeneratorFile{Name: "foo.go", Contents: "foo"}.ProtoFile()
+		require.NoError(t, err)
+		assert.Nil(t, f.GetGeneratedCodeInfo())
+	})
+}
+
+func TestPersister_Persist_Annotations(t *testing.T) {
+	t.Parallel()
+
+	ast := dummyAnnotationAST(t)
+	first, _ := ast.Lookup(".foo.First")
+	second, _ := ast.Lookup(".foo.Second")
+
+	a := &Annotator{}
+	contents := "type " + a.Annotate(first, "First") + "  struct{}\n" +
+		"type " + a.Annotate(second, "Second") + "  struct{}\n" +
+		"var _ = " + a.Annotate(first, "First") + "{}\n" +
+		a.Annotate(second, "removed") + "\n"
+
+	formatted := "type First struct{}\ntype Second struct{}\nvar _ = First{}\n"
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	p.AddPostProcessor(mockPP{match: true, out: []byte(formatted)})
+	p.SetProvenance(dummyProvenance())
+
+	resp := p.Persist(GeneratorFile{Name: "foo.go", Contents: contents, Annotator: a})
+	require.False(t, d.Failed())
+	require.Len(t, resp.File, 1)
+
+	out := resp.File[0].GetContent()
+	assert.True(t, strings.HasSuffix(out, formatted))
+
+	info := resp.File[0].GetGeneratedCodeInfo()
+	require.Len(t, info.GetAnnotation(), 3)
+
+	span := func(i int) string { return out[info.Annotation[i].GetBegin():info.Annotation[i].GetEnd()] }
+	assert.Equal(t, "First", span(0))
+	assert.Equal(t, "Second", span(1))
+	assert.Equal(t, "First", span(2))
+	assert.Equal(t, len(out)-len(formatted)+strings.LastIndex(formatted, "First"), int(info.Annotation[2].GetBegin()))
+}
+
+func TestPersister_Persist_AnnotationsHeader(t *testing.T) {
+	t.Parallel()
+
+	ast := dummyAnnotationAST(t)
+	first, _ := ast.Lookup(".foo.First")
+	second, _ := ast.Lookup(".foo.Second")
+
+	a := &Annotator{}
+	contents := "type " + a.Annotate(first, "First") + " struct{}\n" +
+		"type " + a.Annotate(second, "Second") + " struct{}\n"
+
+	header := "// First and Second are generated.\n\n"
+	body := "type First struct{}\ntype Second struct{}\n"
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	p.AddPostProcessor(mockPP{match: true, out: []byte(header + body)})
+
+	resp := p.Persist(GeneratorFile{Name: "foo.go", Contents: contents, Annotator: a})
+	require.False(t, d.Failed())
+
+	info := resp.File[0].GetGeneratedCodeInfo()
+	require.Len(t, info.GetAnnotation(), 2)
+
+	assert.Equal(t, int32(len(header)+len("type ")), info.Annotation[0].GetBegin())
+	assert.Equal(t, int32(len(header)+strings.Index(body, "Second")), info.Annotation[1].GetBegin())
+}
+
+func TestDiffRuns(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		runs []diffRun
+	}{
+		{"abc", "abc", []diffRun{{0, 0, 3}}},
+		{"abc", "xabc", []diffRun{{0, 1, 3}}},
+		{"abcd", "acd", []diffRun{{0, 0, 1}, {2, 1, 2}}},
+		{"abxcd", "abycd", []diffRun{{0, 0, 2}, {3, 3, 2}}},
+		{"", "ab", nil},
+		{"é", "aé", []diffRun{{0, 1, 2}}},
+		{
+			"FirstSecond",
+			strings.Repeat("h", 2000) + "First" + strings.Repeat("m", 2000) + "Second" + strings.Repeat("f", 2000),
+			[]diffRun{{0, 2000, 5}, {5, 4005, 6}},
+		},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.runs, diffRuns([]byte(tc.a), []byte(tc.b)), "%q -> %q", tc.a, tc.b)
+	}
+
+	// the runs must form a longest common subsequence
+	rnd := rand.New(rand.NewSource(1))
+	gen := func() []byte {
+		b := make([]byte, rnd.Intn(20))
+		for i := range b {
+			b[i] = "abc"[rnd.Intn(3)]
+		}
+		return b
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := gen(), gen()
+		runs := diffRuns(a, b)
+
+		total, ai, bi := 0, 0, 0
+		for _, r := range runs {
+			require.True(t, r.a >= ai && r.b >= bi && r.n > 0)
+			require.Equal(t, a[r.a:r.a+r.n], b[r.b:r.b+r.n])
+			ai, bi, total = r.a+r.n, r.b+r.n, total+r.n
+		}
+		assert.Equal(t, lcsLen(a, b), total, "%q -> %q", a, b)
+	}
+}
+
+func lcsLen(a, b []byte) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else if dp[i+1][j] > dp[i][j+1] {
+				dp[i][j] = dp[i+1][j]
+			} else {
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+	return dp[0][0]
+}
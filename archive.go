@@ -0,0 +1,178 @@
+package pgs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+const generatorFilePerms os.FileMode = 0644
+
+// archiveModTime is the modification time recorded for every archive entry,
+// keeping archives reproducible. It is the earliest time representable in a
+// zip file.
+var archiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type archiveEntry struct {
+	name    string
+	content []byte
+	perms   os.FileMode
+}
+
+// archive collects the files generated by the Modules into a single zip or
+// tar.gz file, instead of emitting them via protoc or writing them directly
+// to the file system.
+type archive struct {
+	path    string
+	entries []archiveEntry
+}
+
+func newArchive(path string) *archive { return &archive{path: path} }
+
+// add records a CustomFile or CustomTemplateFile in the archive. Absolute
+// names are stored relative to the root of the archive, and names that escape
+// it are rejected. As when writing to the file system, an entry with the same
+// name is replaced if overwrite is true, and otherwise kept, in which case
+// false is returned.
+func (ar *archive) add(name string, content []byte, perms os.FileMode, overwrite bool) (bool, error) {
+	clean := strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return false, fmt.Errorf("%s is outside of the archive root", name)
+	}
+
+	e := archiveEntry{name: clean, content: content, perms: perms}
+	for i := range ar.entries {
+		if ar.entries[i].name != clean {
+			continue
+		}
+		if !overwrite {
+			return false, nil
+		}
+		ar.entries[i] = e
+		return true, nil
+	}
+
+	ar.entries = append(ar.entries, e)
+	return true, nil
+}
+
+// addResponse moves the files from resp into the archive, ahead of any custom
+// files. Appends and insertion points are resolved as protoc would against
+// the files in the same response. Since protoc writes its files after the
+// plugin exits, they replace any custom files with the same name.
+func (ar *archive) addResponse(resp *plugin_go.CodeGeneratorResponse) error {
+	assembled, unresolved, err := assembleResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	if len(unresolved) > 0 {
+		f := unresolved[0]
+		if f.GetName() == "" {
+			return fmt.Errorf("append without a preceding file")
+		}
+		return fmt.Errorf("insertion point %q targets unknown file %s", f.GetInsertionPoint(), f.GetName())
+	}
+
+	files := make([]archiveEntry, len(assembled))
+	names := make(map[string]struct{}, len(assembled))
+	for i, f := range assembled {
+		files[i] = archiveEntry{name: f.file.GetName(), content: f.content, perms: generatorFilePerms}
+		names[f.file.GetName()] = struct{}{}
+	}
+
+	for _, e := range ar.entries {
+		if _, ok := names[e.name]; !ok {
+			files = append(files, e)
+		}
+	}
+
+	ar.entries = files
+	resp.File = nil
+
+	return nil
+}
+
+// write encodes the archive to fs, using the format indicated by the
+// extension of its path.
+func (ar *archive) write(fs afero.Fs) error {
+	if err := fs.MkdirAll(filepath.Dir(ar.path), 0755); err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+
+	var err error
+	switch name := strings.ToLower(ar.path); {
+	case strings.HasSuffix(name, ".zip"):
+		err = ar.writeZip(buf)
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		gz := gzip.NewWriter(buf)
+		if err = ar.writeTar(gz); err == nil {
+			err = gz.Close()
+		}
+	case strings.HasSuffix(name, ".tar"):
+		err = ar.writeTar(buf)
+	default:
+		return fmt.Errorf("unsupported archive format: %s", path.Base(ar.path))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, ar.path, buf.Bytes(), 0644)
+}
+
+func (ar *archive) writeZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	for _, e := range ar.entries {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: archiveModTime}
+		h.SetMode(e.perms)
+
+		fw, err := zw.CreateHeader(h)
+		if err != nil {
+			return err
+		}
+		if _, err = fw.Write(e.content); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func (ar *archive) writeTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	for _, e := range ar.entries {
+		h := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     e.name,
+			Mode:     int64(e.perms.Perm()),
+			Size:     int64(len(e.content)),
+			ModTime:  archiveModTime,
+		}
+
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if _, err := tw.Write(e.content); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
@@ -0,0 +1,208 @@
+package pgs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestApplyInsertion(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("package foo\n\nfunc init() {\n\t// @@protoc_insertion_point(init)\n}\n")
+
+	out, err := applyInsertion(content, "init", "a()\n\nb()\n")
+	require.NoError(t, err)
+	assert.Equal(t, "package foo\n\nfunc init() {\n\ta()\n\n\tb()\n\t// @@protoc_insertion_point(init)\n}\n", string(out))
+
+	_, err = applyInsertion(content, "missing", "a()\n")
+	assert.Error(t, err)
+}
+
+func TestArchive_AddResponse(t *testing.T) {
+	t.Parallel()
+
+	ar := newArchive("out.zip")
+	_, err := ar.add("/abs/run.sh", []byte("#!/bin/sh\n"), 0755, false)
+	require.NoError(t, err)
+	_, err = ar.add("foo.go", []byte("package custom\n"), 0644, true)
+	require.NoError(t, err)
+
+	resp := &plugin_go.CodeGeneratorResponse{File: []*plugin_go.CodeGeneratorResponse_File{
+		{Name: proto.String("foo.go"), Content: proto.String("// @@protoc_insertion_point(x)\n")},
+		{Content: proto.String("// appended\n")},
+		{Name: proto.String("foo.go"), InsertionPoint: proto.String("x"), Content: proto.String("// injected\n")},
+		{Name: proto.String("bar.go"), Content: proto.String("package bar\n")},
+	}}
+
+	require.NoError(t, ar.addResponse(resp))
+	assert.Empty(t, resp.File)
+	assert.Equal(t, []archiveEntry{
+		{name: "foo.go", content: []byte("// injected\n// @@protoc_insertion_point(x)\n// appended\n"), perms: 0644},
+		{name: "bar.go", content: []byte("package bar\n"), perms: 0644},
+		{name: "abs/run.sh", content: []byte("#!/bin/sh\n"), perms: 0755},
+	}, ar.entries)
+
+	assert.Error(t, newArchive("out.zip").addResponse(&plugin_go.CodeGeneratorResponse{
+		File: []*plugin_go.CodeGeneratorResponse_File{{Content: proto.String("orphan")}},
+	}))
+
+	assert.Error(t, newArchive("out.zip").addResponse(&plugin_go.CodeGeneratorResponse{
+		File: []*plugin_go.CodeGeneratorResponse_File{{
+			Name:           proto.String("other.go"),
+			InsertionPoint: proto.String("x"),
+		}},
+	}))
+}
+
+func readArchive(t *testing.T, name string, b []byte) map[string]archiveEntry {
+	out := map[string]archiveEntry{}
+
+	if name == "out.zip" {
+		zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		require.NoError(t, err)
+		for _, f := range zr.File {
+			r, err := f.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(r)
+			require.NoError(t, err)
+			out[f.Name] = archiveEntry{name: f.Name, content: content, perms: f.Mode()}
+		}
+		return out
+	}
+
+	var r io.Reader = bytes.NewReader(b)
+	if name != "out.tar" {
+		gz, err := gzip.NewReader(r)
+		require.NoError(t, err)
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		out[h.Name] = archiveEntry{name: h.Name, content: content, perms: os.FileMode(h.Mode)}
+	}
+	return out
+}
+
+func TestArchive_Write(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"out.zip", "out.tar", "out.tar.gz", "out.tgz"} {
+		n := name
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+			ar := newArchive("dist/" + n)
+			_, err := ar.add("foo.go", []byte("package foo\n"), 0644, false)
+			require.NoError(t, err)
+			_, err = ar.add("bin/run.sh", []byte("#!/bin/sh\n"), 0755, false)
+			require.NoError(t, err)
+			require.NoError(t, ar.write(fs))
+
+			b, err := afero.ReadFile(fs, "dist/"+n)
+			require.NoError(t, err)
+
+			entries := readArchive(t, n, b)
+			require.Len(t, entries, 2)
+			assert.Equal(t, "package foo\n", string(entries["foo.go"].content))
+			assert.Equal(t, os.FileMode(0755), entries["bin/run.sh"].perms)
+
+			again := afero.NewMemMapFs()
+			require.NoError(t, ar.write(again))
+			b2, err := afero.ReadFile(again, "dist/"+n)
+			require.NoError(t, err)
+			assert.Equal(t, b, b2, "archives should be reproducible")
+		})
+	}
+
+	assert.Error(t, newArchive("out.rar").write(afero.NewMemMapFs()))
+}
+
+func TestArchive_Add(t *testing.T) {
+	t.Parallel()
+
+	ar := newArchive("out.zip")
+
+	added, err := ar.add("foo", []byte("a"), 0644, false)
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = ar.add("/foo", []byte("b"), 0644, false)
+	require.NoError(t, err)
+	assert.False(t, added)
+	assert.Equal(t, "a", string(ar.entries[0].content))
+
+	added, err = ar.add("./bar/../foo", []byte("c"), 0600, true)
+	require.NoError(t, err)
+	assert.True(t, added)
+	require.Len(t, ar.entries, 1)
+	assert.Equal(t, archiveEntry{name: "foo", content: []byte("c"), perms: 0600}, ar.entries[0])
+
+	for _, name := range []string{"../foo", "bar/../../foo", "..", "."} {
+		_, err = ar.add(name, nil, 0644, true)
+		assert.Error(t, err, name)
+	}
+	assert.Len(t, ar.entries, 1)
+}
+
+func TestPersister_Persist_Archive(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	fs := afero.NewMemMapFs()
+	p.SetFS(fs)
+	p.SetArchive("out.zip")
+
+	resp := p.Persist(
+		GeneratorFile{Name: "foo.go", Contents: "package foo\n"},
+		GeneratorAppend{FileName: "foo.go", Contents: "// appended\n"},
+		GeneratorBytesFile{Name: "foo.bin", Contents: []byte{0xff}},
+		CustomFile{Name: "scripts/run.sh", Contents: "#!/bin/sh\n", Perms: 0755},
+		CustomTemplateFile{
+			Name:             "docs/foo.md",
+			Perms:            0600,
+			TemplateArtifact: TemplateArtifact{Template: genTpl, Data: "fizz"},
+			Stream:           true,
+		},
+	)
+
+	require.False(t, d.Failed())
+	require.NoError(t, d.Err())
+	assert.Empty(t, resp.File)
+
+	exists, err := afero.Exists(fs, "scripts/run.sh")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	b, err := afero.ReadFile(fs, "out.zip")
+	require.NoError(t, err)
+
+	entries := readArchive(t, "out.zip", b)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "package foo\n// appended\n", string(entries["foo.go"].content))
+	assert.Equal(t, []byte{0xff}, entries["foo.bin"].content)
+	assert.Equal(t, os.FileMode(0755), entries["scripts/run.sh"].perms)
+	assert.Equal(t, os.FileMode(0600), entries["docs/foo.md"].perms)
+	assert.Equal(t, "fizz", string(entries["docs/foo.md"].content))
+	assert.Empty(t, p.archive.entries)
+}
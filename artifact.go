@@ -25,8 +25,8 @@ type Template interface {
 }
 
 // GeneratorArtifact describes an Artifact that uses protoc for code generation.
-// GeneratorArtifacts must be valid UTF8. To create binary files, use one of
-// the "custom" Artifact types.
+// With the exception of GeneratorBytesFile, GeneratorArtifacts must be valid
+// UTF8.
 type GeneratorArtifact interface {
 	Artifact
 
@@ -60,6 +60,7 @@ func (ta TemplateArtifact) render() (string, error) {
 // A GeneratorFile Artifact describes a file to be generated using protoc.
 type GeneratorFile struct {
 	GeneratorArtifact
+	PostProcessing
 
 	// Name of the file to generate, relative to the protoc-plugin's generation
 	// output directory.
@@ -71,6 +72,10 @@ type GeneratorFile struct {
 	// Overwrite specifies whether or not this file should replace another file
 	// with the same name if a prior Plugin or Module has created one.
 	Overwrite bool
+
+	// Annotator, if set, records GeneratedCodeInfo annotations for the spans
+	// of the contents marked with it.
+	Annotator *Annotator
 }
 
 // ProtoFile satisfies the GeneratorArtifact interface. An error is returned if
@@ -82,9 +87,42 @@ func (f GeneratorFile) ProtoFile() (*plugin_go.CodeGeneratorResponse_File, error
 		return nil, err
 	}
 
+	return annotatedFile(name, f.Contents, f.Annotator)
+}
+
+// A GeneratorBytesFile Artifact describes a file with binary contents to be
+// generated using protoc, such as a serialized descriptor set or an image.
+// Only PostProcessors that implement BinaryPostProcessor are applied to it.
+type GeneratorBytesFile struct {
+	GeneratorArtifact
+	PostProcessing
+
+	// Name of the file to generate, relative to the protoc-plugin's generation
+	// output directory.
+	Name string
+
+	// Contents are the body of the file.
+	Contents []byte
+
+	// Overwrite specifies whether or not this file should replace another file
+	// with the same name if a prior Plugin or Module has created one.
+	Overwrite bool
+}
+
+// ProtoFile satisfies the GeneratorArtifact interface. An error is returned if
+// the name field is not a path relative to and within the protoc-plugin's
+// generation output directory.
+func (f GeneratorBytesFile) ProtoFile() (*plugin_go.CodeGeneratorResponse_File, error) {
+	name, err := cleanGeneratorFileName(f.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// content is a proto2 string field, which protoc writes to disk verbatim
+	// without validating it as UTF8.
 	return &plugin_go.CodeGeneratorResponse_File{
 		Name:    proto.String(name),
-		Content: proto.String(f.Contents),
+		Content: proto.String(string(f.Contents)),
 	}, nil
 }
 
@@ -93,6 +131,7 @@ func (f GeneratorFile) ProtoFile() (*plugin_go.CodeGeneratorResponse_File, error
 type GeneratorTemplateFile struct {
 	GeneratorArtifact
 	TemplateArtifact
+	PostProcessing
 
 	// Name of the file to generate, relative to the protoc-plugin's generation
 	// output directory.
@@ -101,6 +140,10 @@ type GeneratorTemplateFile struct {
 	// Overwrite specifies whether or not this file should replace another file
 	// with the same name if a prior Plugin or Module has created one.
 	Overwrite bool
+
+	// Annotator, if set, records GeneratedCodeInfo annotations for the spans
+	// of the contents marked with it.
+	Annotator *Annotator
 }
 
 // ProtoFile satisfies the GeneratorArtifact interface. An error is returned if
@@ -117,10 +160,28 @@ func (f GeneratorTemplateFile) ProtoFile() (*plugin_go.CodeGeneratorResponse_Fil
 		return nil, err
 	}
 
-	return &plugin_go.CodeGeneratorResponse_File{
+	return annotatedFile(name, content, f.Annotator)
+}
+
+// annotatedFile creates a CodeGeneratorResponse_File with the annotation
+// markers of a removed from content, and the resulting GeneratedCodeInfo
+// attached.
+func annotatedFile(name, content string, a *Annotator) (*plugin_go.CodeGeneratorResponse_File, error) {
+	content, info, err := a.strip(content)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &plugin_go.CodeGeneratorResponse_File{
 		Name:    proto.String(name),
 		Content: proto.String(content),
-	}, nil
+	}
+
+	if len(info.GetAnnotation()) > 0 {
+		f.GeneratedCodeInfo = info
+	}
+
+	return f, nil
 }
 
 // A GeneratorAppend Artifact appends content to the end of the specified protoc
@@ -128,6 +189,7 @@ func (f GeneratorTemplateFile) ProtoFile() (*plugin_go.CodeGeneratorResponse_Fil
 // file with the same name.
 type GeneratorAppend struct {
 	GeneratorArtifact
+	PostProcessing
 
 	// Filename of the file to append to, relative to the protoc-plugin's generation
 	// output directory.
@@ -155,6 +217,7 @@ func (f GeneratorAppend) ProtoFile() (*plugin_go.CodeGeneratorResponse_File, err
 type GeneratorTemplateAppend struct {
 	GeneratorArtifact
 	TemplateArtifact
+	PostProcessing
 
 	// Filename of the file to append to, relative to the protoc-plugin's generation
 	// output directory.
@@ -185,6 +248,7 @@ func (f GeneratorTemplateAppend) ProtoFile() (*plugin_go.CodeGeneratorResponse_F
 // executed by protoc.
 type GeneratorInjection struct {
 	GeneratorArtifact
+	PostProcessing
 
 	// Filename of the file to inject into, relative to the protoc-plugin's
 	// generation output directory.
@@ -220,6 +284,7 @@ func (f GeneratorInjection) ProtoFile() (*plugin_go.CodeGeneratorResponse_File,
 type GeneratorTemplateInjection struct {
 	GeneratorArtifact
 	TemplateArtifact
+	PostProcessing
 
 	// Filename of the file to inject into, relative to the protoc-plugin's
 	// generation output directory.
@@ -257,6 +322,7 @@ func (f GeneratorTemplateInjection) ProtoFile() (*plugin_go.CodeGeneratorRespons
 // of the protoc-plugin's generation output directory.
 type CustomFile struct {
 	Artifact
+	PostProcessing
 
 	// Name of the file to generate. If relative, the file is created relative to
 	// the directory in which protoc is executed. If absolute, the file is
@@ -275,6 +341,30 @@ type CustomFile struct {
 	Overwrite bool
 }
 
+// CustomBytesFile Artifacts are files with binary contents generated directly
+// against the file system. Only PostProcessors that implement
+// BinaryPostProcessor are applied to them.
+type CustomBytesFile struct {
+	Artifact
+	PostProcessing
+
+	// Name of the file to generate. If relative, the file is created relative to
+	// the directory in which protoc is executed. If absolute, the file is
+	// created as specified.
+	Name string
+
+	// Contents are the body of the file.
+	Contents []byte
+
+	// Perms are the file permission to generate the file with. Note that the
+	// umask of the process will be applied against these permissions.
+	Perms os.FileMode
+
+	// Overwrite indicates if an existing file on disk should be overwritten by
+	// this file.
+	Overwrite bool
+}
+
 // CustomTemplateFile Artifacts are files generated from a Template directly
 // against the file system, and do not use protoc for the generation.
 // CustomFiles should be used over GeneratorFiles when custom permissions need
@@ -284,6 +374,7 @@ type CustomFile struct {
 type CustomTemplateFile struct {
 	Artifact
 	TemplateArtifact
+	PostProcessing
 
 	// Name of the file to generate. If relative, the file is created relative to
 	// the directory in which protoc is executed. If absolute, the file is
@@ -297,6 +388,11 @@ type CustomTemplateFile struct {
 	// Overwrite indicates if an existing file on disk should be overwritten by
 	// this file.
 	Overwrite bool
+
+	// Stream renders the Template directly to the file, without holding the
+	// full output in memory. PostProcessors and provenance headers are not
+	// applied to streamed files.
+	Stream bool
 }
 
 func cleanGeneratorFileName(name string) (string, error) {
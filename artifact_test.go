@@ -6,6 +6,8 @@ import (
 	"text/template"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
 )
 
 var (
@@ -34,6 +36,32 @@ func TestGeneratorFile_ProtoFile(t *testing.T) {
 	assert.Equal(t, f.Contents, pb.GetContent())
 }
 
+func TestGeneratorBytesFile_ProtoFile(t *testing.T) {
+	t.Parallel()
+
+	f := GeneratorBytesFile{
+		Name:     "..",
+		Contents: []byte{0xff, 0x00, 0xfe},
+	}
+
+	pb, err := f.ProtoFile()
+	assert.Error(t, err)
+	assert.Nil(t, pb)
+
+	f.Name = fName
+	pb, err = f.ProtoFile()
+	assert.NoError(t, err)
+	assert.Equal(t, f.Name, pb.GetName())
+	assert.Equal(t, f.Contents, []byte(pb.GetContent()))
+
+	b, err := proto.Marshal(pb)
+	assert.NoError(t, err)
+
+	out := new(plugin_go.CodeGeneratorResponse_File)
+	assert.NoError(t, proto.Unmarshal(b, out))
+	assert.Equal(t, f.Contents, []byte(out.GetContent()))
+}
+
 func TestGeneratorTemplateFile_ProtoFile(t *testing.T) {
 	t.Parallel()
 
@@ -1,6 +1,9 @@
 package pgs
 
 import (
+	"strings"
+	"sync"
+
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 	plugin_go "google.golang.org/protobuf/types/pluginpb"
 )
@@ -19,10 +22,35 @@ type AST interface {
 
 	// Lookup allows getting an Entity from the graph by its fully-qualified name
 	// (FQN). The FQN uses dot notation of the form ".{package}.{entity}", or the
-	// input path for Files.
+	// input path for Files. Packages are not Entities; use LookupPackage to
+	// resolve them.
 	Lookup(name string) (Entity, bool)
 }
 
+// LookupPackage gets a Package from the AST by its name. The name may be
+// provided with or without a leading dot (ie, ".foo.bar" or "foo.bar").
+func LookupPackage(ast AST, name string) (Package, bool) {
+	if l, ok := ast.(interface {
+		LookupPackage(name string) (Package, bool)
+	}); ok {
+		return l.LookupPackage(name)
+	}
+
+	p, ok := ast.Packages()[strings.TrimPrefix(name, ".")]
+	return p, ok
+}
+
+// PackageGraphOf returns the dependency graph between all the Packages in the
+// AST. The graph is computed once and cached by the AST returned from
+// ProcessCodeGeneratorRequest; other implementations build it on each call.
+func PackageGraphOf(ast AST) PackageGraph {
+	if pg, ok := ast.(interface{ PackageGraph() PackageGraph }); ok {
+		return pg.PackageGraph()
+	}
+
+	return NewPackageGraph(ast.Packages())
+}
+
 type graph struct {
 	d Debugger
 
@@ -30,6 +58,9 @@ type graph struct {
 	packages   map[string]Package
 	entities   map[string]Entity
 	extensions []Extension
+
+	pkgGraphOnce sync.Once
+	pkgGraph     PackageGraph
 }
 
 func (g *graph) Targets() map[string]File { return g.targets }
@@ -41,6 +72,20 @@ func (g *graph) Lookup(name string) (Entity, bool) {
 	return e, ok
 }
 
+// LookupPackage implements the optional method used by the LookupPackage
+// function.
+func (g *graph) LookupPackage(name string) (Package, bool) {
+	p, ok := g.packages[strings.TrimPrefix(name, ".")]
+	return p, ok
+}
+
+// PackageGraph implements the optional method used by PackageGraphOf,
+// caching the graph for the lifetime of the AST.
+func (g *graph) PackageGraph() PackageGraph {
+	g.pkgGraphOnce.Do(func() { g.pkgGraph = NewPackageGraph(g.packages) })
+	return g.pkgGraph
+}
+
 // ProcessDescriptors is deprecated; use ProcessCodeGeneratorRequest instead
 func ProcessDescriptors(debug Debugger, req *plugin_go.CodeGeneratorRequest) AST {
 	return ProcessCodeGeneratorRequest(debug, req)
@@ -199,7 +244,7 @@ func (g *graph) hydrateFile(pkg Package, f *descriptor.FileDescriptorProto) File
 
 func (g *graph) hydrateSourceCodeInfo(f File, fd *descriptor.FileDescriptorProto) {
 	for _, loc := range fd.GetSourceCodeInfo().GetLocation() {
-		info := sci{desc: loc}
+		info := sci{desc: loc, file: fd.GetName()}
 		path := loc.GetPath()
 
 		if len(path) == 1 {
@@ -215,10 +260,32 @@ func (g *graph) hydrateSourceCodeInfo(f File, fd *descriptor.FileDescriptorProto
 
 		if e := f.childAtPath(path); e != nil {
 			e.addSourceCodeInfo(info)
+		} else if r := rangeAtPath(f, path); r != nil {
+			r.addSourceCodeInfo(info)
+		} else if e, optPath := optionAtPath(f, path); e != nil {
+			e.addOptionSourceCodeInfo(optPath, info)
 		}
 	}
 }
 
+// rangeAtPath resolves the reserved or extension range of a Message or Enum
+// described by path, returning nil if path does not refer to a range.
+func rangeAtPath(f File, path []int32) NumberRange {
+	n := len(path)
+	if n < 4 || n%2 != 0 {
+		return nil
+	}
+
+	switch e := f.childAtPath(path[:n-2]).(type) {
+	case Message:
+		return e.rangeAtPath(path[n-2:])
+	case Enum:
+		return e.rangeAtPath(path[n-2:])
+	default:
+		return nil
+	}
+}
+
 func (g *graph) hydrateEnum(p ParentEntity, ed *descriptor.EnumDescriptorProto) Enum {
 	e := &enum{
 		desc:   ed,
@@ -233,6 +300,11 @@ func (g *graph) hydrateEnum(p ParentEntity, ed *descriptor.EnumDescriptorProto)
 		e.addValue(g.hydrateEnumValue(e, vd))
 	}
 
+	for _, rd := range ed.GetReservedRange() {
+		r := newInclusiveRange(rd.GetStart(), rd.GetEnd())
+		e.addReservedRange(&r)
+	}
+
 	return e
 }
 
@@ -319,6 +391,19 @@ func (g *graph) hydrateMessage(p ParentEntity, md *descriptor.DescriptorProto) M
 		m.addDefExtension(e)
 	}
 
+	for _, rd := range md.GetReservedRange() {
+		r := newExclusiveRange(rd.GetStart(), rd.GetEnd())
+		m.addReservedRange(&r)
+	}
+
+	for _, rd := range md.GetExtensionRange() {
+		m.addExtensionRange(&extRange{
+			numRange: newExclusiveRange(rd.GetStart(), rd.GetEnd()),
+			desc:     rd,
+			msg:      m,
+		})
+	}
+
 	return m
 }
 
@@ -359,15 +444,18 @@ func (g *graph) hydrateExtension(parent ParentEntity, fd *descriptor.FieldDescri
 func (g *graph) hydrateFieldType(fld Field) FieldType {
 	s := &scalarT{fld: fld}
 
+	if s.ProtoType() == GroupT {
+		if m, ok := g.mustSeen(fld.Descriptor().GetTypeName()).(Message); ok {
+			m.setGroup()
+		}
+	}
+
 	switch {
-	case s.ProtoType() == GroupT:
-		g.d.Fail("group types are deprecated and unsupported. Use an embedded message instead.")
-		return nil
 	case s.ProtoLabel() == Repeated:
 		return g.hydrateRepeatedFieldType(s)
 	case s.ProtoType() == EnumT:
 		return g.hydrateEnumFieldType(s)
-	case s.ProtoType() == MessageT:
+	case s.ProtoType() == MessageT, s.ProtoType() == GroupT:
 		return g.hydrateEmbedFieldType(s)
 	default:
 		return s
@@ -403,7 +491,7 @@ func (g *graph) hydrateRepeatedFieldType(s *scalarT) FieldType {
 			scalarE: r.el.(*scalarE),
 			enum:    g.mustSeen(s.fld.Descriptor().GetTypeName()).(Enum),
 		}
-	case MessageT:
+	case MessageT, GroupT:
 		m := g.mustSeen(s.fld.Descriptor().GetTypeName()).(Message)
 		if m.IsMapEntry() {
 			return g.hydrateMapFieldType(r, m)
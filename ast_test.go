@@ -3,6 +3,7 @@ package pgs
 import (
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
@@ -253,6 +254,170 @@ func TestGraph_SourceCodeInfo(t *testing.T) {
 	})
 }
 
+func TestGraph_Ranges(t *testing.T) {
+	t.Parallel()
+
+	loc := func(comment string, path ...int32) *descriptor.SourceCodeInfo_Location {
+		return &descriptor.SourceCodeInfo_Location{Path: path, LeadingComments: proto.String(comment)}
+	}
+
+	fd := &descriptor.FileDescriptorProto{
+		Name:    proto.String("ranges.proto"),
+		Package: proto.String("ranges"),
+		MessageType: []*descriptor.DescriptorProto{{
+			Name:           proto.String("Msg"),
+			ReservedRange:  []*descriptor.DescriptorProto_ReservedRange{{Start: proto.Int32(2), End: proto.Int32(5)}},
+			ReservedName:   []string{"foo"},
+			ExtensionRange: []*descriptor.DescriptorProto_ExtensionRange{{Start: proto.Int32(100), End: proto.Int32(200)}},
+		}},
+		EnumType: []*descriptor.EnumDescriptorProto{{
+			Name:          proto.String("Enum"),
+			Value:         []*descriptor.EnumValueDescriptorProto{{Name: proto.String("ZERO"), Number: proto.Int32(0)}},
+			ReservedRange: []*descriptor.EnumDescriptorProto_EnumReservedRange{{Start: proto.Int32(1), End: proto.Int32(3)}},
+		}},
+		SourceCodeInfo: &descriptor.SourceCodeInfo{Location: []*descriptor.SourceCodeInfo_Location{
+			loc("message reserved", messageTypePath, 0, messageTypeReservedPath, 0),
+			loc("message reserved start", messageTypePath, 0, messageTypeReservedPath, 0, 1),
+			loc("extension range", messageTypePath, 0, messageTypeExtRangePath, 0),
+			loc("enum reserved", enumTypePath, 0, enumTypeReservedPath, 0),
+		}},
+	}
+
+	d := InitMockDebugger()
+	g := ProcessFileDescriptorSet(d, &descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{fd}})
+	require.False(t, d.Failed())
+
+	ent, ok := g.Lookup(".ranges.Msg")
+	require.True(t, ok)
+	m := ent.(Message)
+
+	require.Len(t, m.ReservedRanges(), 1)
+	assert.Equal(t, int32(2), m.ReservedRanges()[0].Start())
+	assert.Equal(t, int32(5), m.ReservedRanges()[0].End())
+	assert.Equal(t, "message reserved", m.ReservedRanges()[0].SourceCodeInfo().LeadingComments())
+	assert.True(t, m.IsNameReserved("foo"))
+
+	require.Len(t, m.ExtensionRanges(), 1)
+	assert.Equal(t, m, m.ExtensionRanges()[0].Message())
+	assert.Equal(t, "extension range", m.ExtensionRanges()[0].SourceCodeInfo().LeadingComments())
+	assert.Equal(t, int32(1), m.NextAvailableFieldNumber())
+
+	ent, ok = g.Lookup(".ranges.Enum")
+	require.True(t, ok)
+	e := ent.(Enum)
+
+	require.Len(t, e.ReservedRanges(), 1)
+	assert.True(t, e.IsNumberReserved(3))
+	assert.Equal(t, int32(4), e.ReservedRanges()[0].End())
+	assert.Equal(t, "enum reserved", e.ReservedRanges()[0].SourceCodeInfo().LeadingComments())
+}
+
+func TestGraph_SourcePositions(t *testing.T) {
+	t.Parallel()
+
+	loc := func(comment string, span []int32, path ...int32) *descriptor.SourceCodeInfo_Location {
+		return &descriptor.SourceCodeInfo_Location{Path: path, Span: span, LeadingComments: proto.String(comment)}
+	}
+
+	fd := &descriptor.FileDescriptorProto{
+		Name:    proto.String("pos/pos.proto"),
+		Package: proto.String("pos"),
+		Syntax:  proto.String("proto2"),
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("Msg"),
+			Field: []*descriptor.FieldDescriptorProto{{
+				Name:     proto.String("grp"),
+				Number:   proto.Int32(1),
+				Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptor.FieldDescriptorProto_TYPE_GROUP.Enum(),
+				TypeName: proto.String(".pos.Msg.Grp"),
+			}},
+			NestedType: []*descriptor.DescriptorProto{{
+				Name: proto.String("Grp"),
+				Field: []*descriptor.FieldDescriptorProto{{
+					Name:       proto.String("val"),
+					Number:     proto.Int32(2),
+					Label:      descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:       descriptor.FieldDescriptorProto_TYPE_INT32.Enum(),
+					OneofIndex: proto.Int32(0),
+				}},
+				OneofDecl: []*descriptor.OneofDescriptorProto{{Name: proto.String("choice")}},
+			}},
+			ExtensionRange: []*descriptor.DescriptorProto_ExtensionRange{{Start: proto.Int32(100), End: proto.Int32(200)}},
+			Extension: []*descriptor.FieldDescriptorProto{{
+				Name:     proto.String("nested_ext"),
+				Number:   proto.Int32(101),
+				Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptor.FieldDescriptorProto_TYPE_BOOL.Enum(),
+				Extendee: proto.String(".pos.Msg"),
+			}},
+		}},
+		Extension: []*descriptor.FieldDescriptorProto{{
+			Name:     proto.String("file_ext"),
+			Number:   proto.Int32(100),
+			Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptor.FieldDescriptorProto_TYPE_BOOL.Enum(),
+			Extendee: proto.String(".pos.Msg"),
+		}},
+		SourceCodeInfo: &descriptor.SourceCodeInfo{Location: []*descriptor.SourceCodeInfo_Location{
+			loc("oneof in group", []int32{5, 4, 8, 5}, messageTypePath, 0, messageTypeNestedTypePath, 0, messageTypeOneofDeclPath, 0),
+			loc("file extension", []int32{12, 2, 30}, extensionPath, 0),
+			loc("message extension", []int32{9, 4, 35}, messageTypePath, 0, messageTypeExtensionPath, 0),
+		}},
+	}
+
+	d := InitMockDebugger()
+	g := ProcessFileDescriptorSet(d, &descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{fd}})
+	require.False(t, d.Failed())
+
+	ent, ok := g.Lookup(".pos.Msg.Grp.choice")
+	require.True(t, ok)
+	info := ent.SourceCodeInfo()
+	require.NotNil(t, info)
+	assert.Equal(t, "oneof in group", info.LeadingComments())
+	assert.Equal(t, Position{File: "pos/pos.proto", StartLine: 6, StartCol: 5, EndLine: 9, EndCol: 5}, info.Position())
+
+	ent, ok = g.Lookup(".pos.file_ext")
+	require.True(t, ok)
+	info = ent.SourceCodeInfo()
+	require.NotNil(t, info)
+	assert.Equal(t, "file extension", info.LeadingComments())
+	assert.Equal(t, "pos/pos.proto:13:3", info.Position().String())
+
+	ent, ok = g.Lookup(".pos.Msg.nested_ext")
+	require.True(t, ok)
+	info = ent.SourceCodeInfo()
+	require.NotNil(t, info)
+	assert.Equal(t, "message extension", info.LeadingComments())
+}
+
+func TestGraph_LookupPackage(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPkg()
+	g := &graph{packages: map[string]Package{"pkg_name": p}}
+
+	found, ok := g.LookupPackage("pkg_name")
+	assert.True(t, ok)
+	assert.Equal(t, p, found)
+
+	found, ok = g.LookupPackage(".pkg_name")
+	assert.True(t, ok)
+	assert.Equal(t, p, found)
+
+	_, ok = g.LookupPackage("foo")
+	assert.False(t, ok)
+
+	for _, ast := range []AST{g, struct{ AST }{g}} {
+		found, ok = LookupPackage(ast, ".pkg_name")
+		assert.True(t, ok)
+		assert.Equal(t, p, found)
+
+		_, ok = LookupPackage(ast, "foo")
+		assert.False(t, ok)
+	}
+}
+
 func TestGraph_MustSeen(t *testing.T) {
 	t.Parallel()
 
@@ -273,14 +438,52 @@ func TestGraph_MustSeen(t *testing.T) {
 func TestGraph_HydrateFieldType_Group(t *testing.T) {
 	t.Parallel()
 
-	md := InitMockDebugger()
-	g := &graph{d: md}
+	grp := func(name string, lbl ProtoLabel) *descriptor.FieldDescriptorProto {
+		return &descriptor.FieldDescriptorProto{
+			Name:     proto.String(strings.ToLower(name)),
+			Number:   proto.Int32(1),
+			Label:    lbl.ProtoPtr(),
+			Type:     GroupT.ProtoPtr(),
+			TypeName: proto.String(".groups.Msg." + name),
+		}
+	}
 
-	f := dummyField()
-	f.Descriptor().Type = GroupT.ProtoPtr()
+	fd := &descriptor.FileDescriptorProto{
+		Name:    proto.String("groups.proto"),
+		Package: proto.String("groups"),
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("Msg"),
+			NestedType: []*descriptor.DescriptorProto{
+				{Name: proto.String("MyGroup")},
+				{Name: proto.String("RepGroup")},
+			},
+			Field: []*descriptor.FieldDescriptorProto{
+				grp("MyGroup", Optional),
+				grp("RepGroup", Repeated),
+			},
+		}},
+	}
 
-	assert.Nil(t, g.hydrateFieldType(f))
-	assert.True(t, md.Failed())
+	d := InitMockDebugger()
+	g := ProcessFileDescriptorSet(d, &descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{fd}})
+	require.False(t, d.Failed())
+
+	ent, _ := g.Lookup(".groups.Msg.mygroup")
+	fld := ent.(Field)
+	assert.True(t, fld.IsGroup())
+	assert.True(t, fld.IsDelimited())
+	require.True(t, fld.Type().IsEmbed())
+	assert.True(t, fld.Type().Embed().IsGroup())
+	assert.Equal(t, "MyGroup", fld.Type().Embed().Name().String())
+
+	ent, _ = g.Lookup(".groups.Msg.repgroup")
+	fld = ent.(Field)
+	assert.True(t, fld.IsGroup())
+	require.True(t, fld.Type().IsRepeated())
+	assert.True(t, fld.Type().Element().Embed().IsGroup())
+
+	ent, _ = g.Lookup(".groups.Msg")
+	assert.False(t, ent.(Message).IsGroup())
 }
 
 func TestGraph_Packageless(t *testing.T) {
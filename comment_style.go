@@ -0,0 +1,272 @@
+package pgs
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A CommentStyle describes how comment blocks are rendered for a target
+// language. See the predefined styles (eg, SlashCommentStyle) for examples.
+type CommentStyle struct {
+	// Prefix is prepended to each line of the comment, separated from the text
+	// by a single space (eg, "//", "#", or " *"). It may be empty.
+	Prefix string
+
+	// Open and Close, if set, are emitted on their own lines before and after
+	// the comment (eg, "/**" and " */").
+	Open, Close string
+
+	// Indent is prepended to every line, including the Open and Close lines.
+	Indent string
+
+	// Wrap is the maximum width of each line, including the Indent and Prefix.
+	// Paragraphs and list items are wrapped to fit, while code blocks are
+	// always preserved as-is. If zero, lines are not wrapped.
+	Wrap int
+
+	// Escape, if set, is applied to the text of each line, preventing any
+	// sequences from prematurely closing the comment.
+	Escape *strings.Replacer
+}
+
+var (
+	// SlashCommentStyle renders `//` line comments, as used by Go, C++, Java,
+	// and JavaScript.
+	SlashCommentStyle = CommentStyle{Prefix: "//", Wrap: 80}
+
+	// TripleSlashCommentStyle renders `///` doc comments, as used by Rust, C#,
+	// and Swift.
+	TripleSlashCommentStyle = CommentStyle{Prefix: "///", Wrap: 80}
+
+	// HashCommentStyle renders `#` line comments, as used by Python, Ruby, and
+	// YAML.
+	HashCommentStyle = CommentStyle{Prefix: "#", Wrap: 80}
+
+	// BlockCommentStyle renders `/** */` doc comments, as used by JSDoc,
+	// Javadoc, and PHPDoc.
+	BlockCommentStyle = CommentStyle{
+		Open:   "/**",
+		Prefix: " *",
+		Close:  " */",
+		Wrap:   80,
+		Escape: strings.NewReplacer("*/", `*\/`),
+	}
+
+	// DocstringCommentStyle renders Python docstrings.
+	DocstringCommentStyle = CommentStyle{
+		Open:   `"""`,
+		Close:  `"""`,
+		Wrap:   80,
+		Escape: strings.NewReplacer(`"""`, `\"\"\"`),
+	}
+
+	// MarkdownCommentStyle renders the comment as plain Markdown text.
+	MarkdownCommentStyle = CommentStyle{Wrap: 80}
+)
+
+// Render returns text as a comment block in this style, ending with a
+// newline. Common leading whitespace, such as the space protoc retains after
+// each `//`, is removed, as are leading and trailing blank lines. Fenced
+// (```) and indented code blocks are preserved verbatim, and bullet or
+// numbered list items (including nested items) are wrapped with a hanging
+// indent. If text contains no content, an empty string is returned.
+func (s CommentStyle) Render(text string) string {
+	lines := normalizeCommentLines(text)
+	if len(lines) == 0 {
+		return ""
+	}
+
+	width := 0
+	if s.Wrap > 0 {
+		width = s.Wrap - utf8.RuneCountInString(s.Indent)
+		if s.Prefix != "" {
+			width -= utf8.RuneCountInString(s.Prefix) + 1
+		}
+		if width < 1 {
+			width = 1
+		}
+	}
+
+	buf := &strings.Builder{}
+	if s.Open != "" {
+		buf.WriteString(s.Indent + s.Open + "\n")
+	}
+
+	for _, l := range layoutCommentLines(lines, width) {
+		s.writeLine(buf, l)
+	}
+
+	if s.Close != "" {
+		buf.WriteString(s.Indent + s.Close + "\n")
+	}
+
+	return buf.String()
+}
+
+// RenderLeading renders the leading comments of info in this style. If info
+// is nil or has no leading comments, an empty string is returned.
+func (s CommentStyle) RenderLeading(info SourceCodeInfo) string {
+	if info == nil {
+		return ""
+	}
+	return s.Render(info.LeadingComments())
+}
+
+func (s CommentStyle) writeLine(buf *strings.Builder, l string) {
+	if s.Escape != nil {
+		l = s.Escape.Replace(l)
+	}
+
+	switch {
+	case s.Prefix == "":
+		l = s.Indent + l
+	case l == "":
+		l = s.Indent + s.Prefix
+	default:
+		l = s.Indent + s.Prefix + " " + l
+	}
+
+	buf.WriteString(strings.TrimRightFunc(l, unicode.IsSpace) + "\n")
+}
+
+// normalizeCommentLines splits text into lines, removing the whitespace
+// common to all non-blank lines as well as any leading and trailing blank
+// lines.
+func normalizeCommentLines(text string) []string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	common := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+
+		n := len(l) - len(strings.TrimLeftFunc(l, unicode.IsSpace))
+		if common == -1 || n < common {
+			common = n
+		}
+	}
+
+	for i, l := range lines {
+		if len(l) >= common && strings.TrimSpace(l[:common]) == "" {
+			lines[i] = strings.TrimRightFunc(l[common:], unicode.IsSpace)
+		} else {
+			lines[i] = strings.TrimSpace(l)
+		}
+	}
+
+	return lines
+}
+
+// layoutCommentLines groups lines into paragraphs, list items, and code
+// blocks, wrapping the former two to width (if positive).
+func layoutCommentLines(lines []string, width int) (out []string) {
+	var (
+		para   []string
+		marker string
+		fenced bool
+	)
+
+	flush := func() {
+		if len(para) > 0 {
+			out = append(out, wrapCommentText(strings.Join(para, " "), marker, width)...)
+		}
+		para, marker = nil, ""
+	}
+
+	for _, l := range lines {
+		trimmed := strings.TrimSpace(l)
+
+		switch {
+		case fenced || strings.HasPrefix(trimmed, "```"):
+			flush()
+			out = append(out, l)
+			if strings.HasPrefix(trimmed, "```") {
+				fenced = !fenced
+			}
+		case trimmed == "":
+			flush()
+			if len(out) > 0 && out[len(out)-1] != "" {
+				out = append(out, "")
+			}
+		case isCommentCodeLine(l) && len(para) == 0:
+			flush()
+			out = append(out, l)
+		case commentListMarker(trimmed) != "":
+			flush()
+			m := commentListMarker(trimmed)
+			marker = l[:len(l)-len(strings.TrimLeftFunc(l, unicode.IsSpace))] + m
+			para = append(para, strings.TrimSpace(trimmed[len(m):]))
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	flush()
+
+	return out
+}
+
+// isCommentCodeLine returns true if l is indented as a code block.
+func isCommentCodeLine(l string) bool {
+	return strings.HasPrefix(l, "    ") || strings.HasPrefix(l, "\t")
+}
+
+// commentListMarker returns the bullet ("- ", "* ", "+ ") or number ("1. ")
+// that starts l, including the following space, or an empty string if l is
+// not a list item.
+func commentListMarker(l string) string {
+	if len(l) >= 2 && strings.ContainsRune("-*+", rune(l[0])) && l[1] == ' ' {
+		return l[:2]
+	}
+
+	i := 0
+	for i < len(l) && l[i] >= '0' && l[i] <= '9' {
+		i++
+	}
+
+	if i > 0 && i+1 < len(l) && (l[i] == '.' || l[i] == ')') && l[i+1] == ' ' {
+		return l[:i+2]
+	}
+
+	return ""
+}
+
+// wrapCommentText wraps the words of text to width, starting the first line
+// with marker and indenting the rest to align with it.
+func wrapCommentText(text, marker string, width int) (out []string) {
+	words := strings.Fields(text)
+	hanging := strings.Repeat(" ", utf8.RuneCountInString(marker))
+
+	line := marker
+	lineLen := utf8.RuneCountInString(marker)
+	empty := true
+
+	for _, w := range words {
+		wLen := utf8.RuneCountInString(w)
+
+		if !empty && width > 0 && lineLen+1+wLen > width {
+			out = append(out, line)
+			line, lineLen, empty = hanging, len(hanging), true
+		}
+
+		if !empty {
+			line += " "
+			lineLen++
+		}
+
+		line += w
+		lineLen += wLen
+		empty = false
+	}
+
+	return append(out, line)
+}
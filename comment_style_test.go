@@ -0,0 +1,135 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+const styleComment = ` Foo does the thing and then does the other thing.
+
+ Usage:
+
+     foo := NewFoo()
+     foo.Do()
+
+ - first item that is long enough to wrap
+ - second
+   1. nested
+`
+
+func TestCommentStyle_Render(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		style CommentStyle
+		ex    string
+	}{
+		{
+			name:  "slash",
+			style: CommentStyle{Prefix: "//", Wrap: 30},
+			ex: `// Foo does the thing and then
+// does the other thing.
+//
+// Usage:
+//
+//     foo := NewFoo()
+//     foo.Do()
+//
+// - first item that is long
+//   enough to wrap
+// - second
+//   1. nested
+`,
+		},
+		{
+			name:  "block",
+			style: CommentStyle{Open: "/**", Prefix: " *", Close: " */", Indent: "  ", Wrap: 40},
+			ex: `  /**
+   * Foo does the thing and then does
+   * the other thing.
+   *
+   * Usage:
+   *
+   *     foo := NewFoo()
+   *     foo.Do()
+   *
+   * - first item that is long enough to
+   *   wrap
+   * - second
+   *   1. nested
+   */
+`,
+		},
+		{
+			name:  "markdown no wrap",
+			style: MarkdownCommentStyle,
+			ex: `Foo does the thing and then does the other thing.
+
+Usage:
+
+    foo := NewFoo()
+    foo.Do()
+
+- first item that is long enough to wrap
+- second
+  1. nested
+`,
+		},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.ex, tc.style.Render(styleComment))
+		})
+	}
+}
+
+func TestCommentStyle_Render_Fenced(t *testing.T) {
+	t.Parallel()
+
+	in := " Example:\n ```\n a   b\n\n c\n ```\n after"
+	ex := "# Example:\n# ```\n# a   b\n#\n# c\n# ```\n# after\n"
+	assert.Equal(t, ex, HashCommentStyle.Render(in))
+}
+
+func TestCommentStyle_Render_Escape(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "/**\n * a/*\\/b\n */\n", BlockCommentStyle.Render(" a/*/b\n"))
+	assert.Equal(t, "/**\n * a *\\/ b\n */\n", BlockCommentStyle.Render("a */ b"))
+	assert.Equal(t, "\"\"\"\nsay \\\"\\\"\\\" hi\n\"\"\"\n", DocstringCommentStyle.Render(`say """ hi`))
+}
+
+func TestCommentStyle_Render_Empty(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, SlashCommentStyle.Render(""))
+	assert.Empty(t, BlockCommentStyle.Render(" \n\n  "))
+}
+
+func TestCommentStyle_RenderLeading(t *testing.T) {
+	t.Parallel()
+
+	info := sci{desc: &descriptor.SourceCodeInfo_Location{LeadingComments: proto.String(" hello\n world\n")}}
+
+	assert.Equal(t, "/// hello world\n", TripleSlashCommentStyle.RenderLeading(info))
+	assert.Empty(t, SlashCommentStyle.RenderLeading(nil))
+}
+
+func TestCommentListMarker(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "- ", commentListMarker("- foo"))
+	assert.Equal(t, "* ", commentListMarker("* foo"))
+	assert.Equal(t, "12. ", commentListMarker("12. foo"))
+	assert.Equal(t, "3) ", commentListMarker("3) foo"))
+	assert.Empty(t, commentListMarker("-foo"))
+	assert.Empty(t, commentListMarker("12 foo"))
+	assert.Empty(t, commentListMarker("foo"))
+}
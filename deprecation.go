@@ -0,0 +1,180 @@
+package pgs
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DeprecatedEntities returns all deprecated Entities defined within the build
+// target Files of the AST, including the Files themselves. Files are walked in
+// lexical order of their names, and their Entities in the order they are
+// visited by Walk.
+func DeprecatedEntities(ast AST) []Entity {
+	targets := ast.Targets()
+
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []Entity
+	for _, name := range names {
+		out = append(out, Collect(targets[name], Entity.Deprecated)...)
+	}
+
+	return out
+}
+
+// DeprecatedReferences returns the deprecated Entities transitively
+// referenced by Message m: m itself, its Fields, and the Messages and Enums
+// reachable through the types of those Fields. An EnumValue is only included
+// if it is the default of one of the Fields, or is set in the options of m or
+// its Fields. Each Entity is returned at most once, in depth-first order. This
+// is useful for checking if a Method's input or output touches any deprecated
+// API:
+//
+//	if refs := pgs.DeprecatedReferences(method.Input()); len(refs) > 0 { ... }
+func DeprecatedReferences(m Message) []Entity {
+	r := &deprecationRefs{seen: make(map[Entity]struct{})}
+	r.message(m)
+	return r.out
+}
+
+type deprecationRefs struct {
+	seen map[Entity]struct{}
+	out  []Entity
+}
+
+// visit returns false if e has already been visited, recording it if it is
+// deprecated otherwise.
+func (r *deprecationRefs) visit(e Entity) bool {
+	if _, ok := r.seen[e]; ok {
+		return false
+	}
+	r.seen[e] = struct{}{}
+
+	if e.Deprecated() {
+		r.out = append(r.out, e)
+	}
+
+	return true
+}
+
+func (r *deprecationRefs) message(m Message) {
+	if m == nil || !r.visit(m) {
+		return
+	}
+
+	r.options(m.File(), m.Descriptor().GetOptions().ProtoReflect())
+
+	for _, f := range m.Fields() {
+		if !r.visit(f) {
+			continue
+		}
+
+		r.options(f.File(), f.Descriptor().GetOptions().ProtoReflect())
+
+		t := f.Type()
+		switch {
+		case t.IsMap():
+			r.elem(t.Key())
+			r.elem(t.Element())
+		case t.IsRepeated():
+			r.elem(t.Element())
+		case t.IsEmbed():
+			r.message(t.Embed())
+		case t.IsEnum():
+			r.enum(t.Enum())
+			r.defaultValue(f, t.Enum())
+		}
+	}
+}
+
+func (r *deprecationRefs) elem(el FieldTypeElem) {
+	switch {
+	case el == nil:
+	case el.IsEmbed():
+		r.message(el.Embed())
+	case el.IsEnum():
+		r.enum(el.Enum())
+	}
+}
+
+func (r *deprecationRefs) enum(e Enum) {
+	if e != nil {
+		r.visit(e)
+	}
+}
+
+// defaultValue visits the EnumValue of e used as the default of Field f, if
+// one is set.
+func (r *deprecationRefs) defaultValue(f Field, e Enum) {
+	if e == nil || !f.HasDefaultValue() {
+		return
+	}
+
+	for _, ev := range e.Values() {
+		if ev.Name().String() == f.Descriptor().GetDefaultValue() {
+			r.visit(ev)
+			return
+		}
+	}
+}
+
+// options visits the EnumValues set in opts, including within nested
+// messages. The Enums are resolved from f and its transitive imports, and
+// values of Enums that cannot be found are ignored.
+func (r *deprecationRefs) options(f File, opts protoreflect.Message) {
+	opts.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				r.optionValue(f, fd.MapValue(), mv)
+				return true
+			})
+		case fd.IsList():
+			for i, l := 0, v.List(); i < l.Len(); i++ {
+				r.optionValue(f, fd, l.Get(i))
+			}
+		default:
+			r.optionValue(f, fd, v)
+		}
+		return true
+	})
+}
+
+func (r *deprecationRefs) optionValue(f File, fd protoreflect.FieldDescriptor, v protoreflect.Value) {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := findEnumValue(f, fd.Enum().FullName(), v.Enum()); ev != nil {
+			r.visit(ev)
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		r.options(f, v.Message())
+	}
+}
+
+// findEnumValue returns the EnumValue numbered n of the Enum with the given
+// full name, declared in f or any of its transitive imports.
+func findEnumValue(f File, name protoreflect.FullName, n protoreflect.EnumNumber) EnumValue {
+	fqn := "." + string(name)
+
+	for _, fl := range append([]File{f}, f.TransitiveImports()...) {
+		for _, e := range fl.AllEnums() {
+			if e.FullyQualifiedName() != fqn {
+				continue
+			}
+
+			for _, ev := range e.Values() {
+				if ev.Value() == int32(n) {
+					return ev
+				}
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
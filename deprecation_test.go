@@ -0,0 +1,198 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+func dummyDeprecatedAST(t *testing.T) AST {
+	dep := proto.Bool(true)
+
+	fld := func(name string, typ ProtoType, typeName string, fo *descriptor.FieldOptions) *descriptor.FieldDescriptorProto {
+		fd := &descriptor.FieldDescriptorProto{
+			Name:    proto.String(name),
+			Number:  proto.Int32(1),
+			Label:   Optional.ProtoPtr(),
+			Type:    typ.ProtoPtr(),
+			Options: fo,
+		}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		return fd
+	}
+
+	other := &descriptor.FileDescriptorProto{
+		Name:    proto.String("other.proto"),
+		Package: proto.String("other"),
+		Options: &descriptor.FileOptions{Deprecated: dep},
+		MessageType: []*descriptor.DescriptorProto{{
+			Name:    proto.String("Imported"),
+			Options: &descriptor.MessageOptions{Deprecated: dep},
+		}},
+	}
+
+	// a copy of descriptor.proto with a deprecated value referenced by options
+	desc := protodesc.ToFileDescriptorProto(descriptor.File_google_protobuf_descriptor_proto)
+	for _, m := range desc.GetMessageType() {
+		if m.GetName() == "FieldOptions" {
+			m.EnumType[0].Value[1].Options = &descriptor.EnumValueOptions{Deprecated: dep}
+		}
+	}
+
+	defaulted := fld("defaulted", EnumT, ".dep.Enum", nil)
+	defaulted.DefaultValue = proto.String("OLD")
+
+	target := &descriptor.FileDescriptorProto{
+		Name:       proto.String("dep.proto"),
+		Package:    proto.String("dep"),
+		Dependency: []string{"other.proto", desc.GetName()},
+		EnumType: []*descriptor.EnumDescriptorProto{
+			{
+				Name: proto.String("Enum"),
+				Value: []*descriptor.EnumValueDescriptorProto{
+					{Name: proto.String("ZERO"), Number: proto.Int32(0)},
+					{Name: proto.String("OLD"), Number: proto.Int32(1), Options: &descriptor.EnumValueOptions{Deprecated: dep}},
+				},
+			},
+			{
+				Name:    proto.String("OldEnum"),
+				Options: &descriptor.EnumOptions{Deprecated: dep},
+				Value:   []*descriptor.EnumValueDescriptorProto{{Name: proto.String("UNUSED"), Number: proto.Int32(0)}},
+			},
+		},
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Req"),
+				Field: []*descriptor.FieldDescriptorProto{
+					fld("inner", MessageT, ".dep.Inner", nil),
+					fld("enum", EnumT, ".dep.Enum", nil),
+					fld("imported", MessageT, ".other.Imported", nil),
+					fld("old_enum", EnumT, ".dep.OldEnum", nil),
+					fld("cord", StringT, "", &descriptor.FieldOptions{Ctype: descriptor.FieldOptions_CORD.Enum()}),
+				},
+			},
+			{
+				Name:  proto.String("Defaults"),
+				Field: []*descriptor.FieldDescriptorProto{defaulted},
+			},
+			{
+				Name: proto.String("Inner"),
+				Field: []*descriptor.FieldDescriptorProto{
+					fld("old", StringT, "", &descriptor.FieldOptions{Deprecated: dep}),
+					fld("self", MessageT, ".dep.Inner", nil),
+				},
+			},
+			{
+				Name:    proto.String("Old"),
+				Options: &descriptor.MessageOptions{Deprecated: dep},
+			},
+		},
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name: proto.String("Svc"),
+			Method: []*descriptor.MethodDescriptorProto{{
+				Name:       proto.String("Do"),
+				InputType:  proto.String(".dep.Req"),
+				OutputType: proto.String(".dep.Old"),
+				Options:    &descriptor.MethodOptions{Deprecated: dep},
+			}},
+		}},
+	}
+
+	d := InitMockDebugger()
+	ast := ProcessCodeGeneratorRequest(d, &plugin_go.CodeGeneratorRequest{
+		FileToGenerate: []string{"dep.proto"},
+		ProtoFile:      []*descriptor.FileDescriptorProto{desc, other, target},
+	})
+	require.False(t, d.Failed())
+
+	return ast
+}
+
+func entityNames(ents []Entity) []string {
+	out := make([]string, len(ents))
+	for i, e := range ents {
+		out[i] = e.FullyQualifiedName()
+	}
+	return out
+}
+
+func TestEntity_Deprecated(t *testing.T) {
+	t.Parallel()
+
+	ast := dummyDeprecatedAST(t)
+
+	tests := []struct {
+		name       string
+		deprecated bool
+	}{
+		{"other.proto", true},
+		{"dep.proto", false},
+		{".other.Imported", true},
+		{".dep.Req", false},
+		{".dep.Old", true},
+		{".dep.Inner.old", true},
+		{".dep.Inner.self", false},
+		{".dep.Enum", false},
+		{".dep.Enum.OLD", true},
+		{".dep.Svc", false},
+		{".dep.Svc.Do", true},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e, ok := ast.Lookup(tc.name)
+			require.True(t, ok)
+			assert.Equal(t, tc.deprecated, e.Deprecated())
+		})
+	}
+
+	assert.False(t, (&oneof{}).Deprecated())
+}
+
+func TestDeprecatedEntities(t *testing.T) {
+	t.Parallel()
+
+	ast := dummyDeprecatedAST(t)
+
+	assert.Equal(t, []string{
+		".dep.Enum.OLD",
+		".dep.OldEnum",
+		".dep.Inner.old",
+		".dep.Old",
+		".dep.Svc.Do",
+	}, entityNames(DeprecatedEntities(ast)))
+}
+
+func TestDeprecatedReferences(t *testing.T) {
+	t.Parallel()
+
+	ast := dummyDeprecatedAST(t)
+
+	e, ok := ast.Lookup(".dep.Svc.Do")
+	require.True(t, ok)
+	mtd := e.(Method)
+
+	assert.Equal(t, []string{
+		".dep.Inner.old",
+		".other.Imported",
+		".dep.OldEnum",
+		".google.protobuf.FieldOptions.CType.CORD",
+	}, entityNames(DeprecatedReferences(mtd.Input())))
+
+	assert.Equal(t, []string{".dep.Old"}, entityNames(DeprecatedReferences(mtd.Output())))
+
+	e, ok = ast.Lookup(".dep.Defaults")
+	require.True(t, ok)
+	assert.Equal(t, []string{".dep.Enum.OLD"}, entityNames(DeprecatedReferences(e.(Message))))
+	assert.Empty(t, DeprecatedReferences(nil))
+}
@@ -0,0 +1,243 @@
+package pgs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// A Directive is a machine-readable instruction embedded in a comment line,
+// of the form `<prefix>key[=value]`. For example, with the prefix "+", the
+// comment line `+gen:skip` produces a Directive with the Key "gen:skip", and
+// with the prefix "pgs:", the line `pgs:tag=x` produces a Directive with the
+// Key "tag" and the Value "x". Whitespace around the "=" is ignored, and the
+// value may also be separated from the key by whitespace alone, such that
+// `@deprecated use Foo` (with the prefix "@") has the Value "use Foo".
+type Directive struct {
+	// Key is the name of the directive, excluding the prefix.
+	Key string
+
+	// Value is the (whitespace trimmed) text following the key, if any.
+	Value string
+
+	// HasValue is true if the directive includes a value or an explicit "=".
+	HasValue bool
+}
+
+// Directives are the result of parsing the comments of an entity for
+// Directive lines.
+type Directives struct {
+	// List contains each Directive in order of appearance, starting with those
+	// in the leading comments.
+	List []Directive
+
+	// LeadingComments is the leading comment with all directive lines removed.
+	LeadingComments string
+
+	// TrailingComments is the trailing comment with all directive lines
+	// removed.
+	TrailingComments string
+}
+
+// Lookup returns the first Directive with the given key, and whether or not it
+// is present.
+func (d Directives) Lookup(key string) (Directive, bool) {
+	for _, dir := range d.List {
+		if dir.Key == key {
+			return dir, true
+		}
+	}
+	return Directive{}, false
+}
+
+// Has returns true if a Directive with the given key is present.
+func (d Directives) Has(key string) bool {
+	_, ok := d.Lookup(key)
+	return ok
+}
+
+// A DirectiveError describes a malformed Directive in a comment, as reported
+// by StrictDirectives.
+type DirectiveError struct {
+	// Location is the source location of the entity the comment is attached
+	// to. The span is zero-based, per the SourceCodeInfo documentation.
+	Location *descriptor.SourceCodeInfo_Location
+
+	// Comment identifies which comment contains the directive, either
+	// "leading" or "trailing".
+	Comment string
+
+	// Line is the one-based line within the comment containing the directive.
+	Line int
+
+	// SourceLine is the one-based line of the proto file containing the
+	// directive, or zero if the Location has no span. It is derived from the
+	// entity's span, assuming a leading comment ends on the line before the
+	// entity, and a trailing comment begins on the line the entity ends.
+	SourceLine int
+
+	// Text is the content of the offending line, whitespace trimmed.
+	Text string
+
+	// Reason describes why the directive is malformed.
+	Reason string
+}
+
+// Error satisfies the error interface. If the source line is known, the
+// message is prefixed with it.
+func (e DirectiveError) Error() string {
+	msg := fmt.Sprintf("malformed directive %q on line %d of %s comment: %s",
+		e.Text, e.Line, e.Comment, e.Reason)
+
+	if e.SourceLine > 0 {
+		return fmt.Sprintf("line %d: %s", e.SourceLine, msg)
+	}
+
+	return msg
+}
+
+// parseDirectives extracts the Directives with the given prefix from the
+// leading and trailing comments of loc. Leading detached comments are not
+// considered, as they do not describe the entity. Lines starting with the
+// prefix that are not well-formed directives are left in the comments, unless
+// strict is true, in which case a DirectiveError is returned.
+func parseDirectives(loc *descriptor.SourceCodeInfo_Location, prefix string, strict bool) (out Directives, err error) {
+	if prefix == "" {
+		return out, errors.New("directive prefix cannot be empty")
+	}
+
+	var dirs []Directive
+
+	if out.LeadingComments, dirs, err = stripDirectives(loc, "leading", loc.GetLeadingComments(), prefix, strict); err != nil {
+		return Directives{}, err
+	}
+	out.List = append(out.List, dirs...)
+
+	if out.TrailingComments, dirs, err = stripDirectives(loc, "trailing", loc.GetTrailingComments(), prefix, strict); err != nil {
+		return Directives{}, err
+	}
+	out.List = append(out.List, dirs...)
+
+	return out, nil
+}
+
+func stripDirectives(loc *descriptor.SourceCodeInfo_Location, comment, text, prefix string, strict bool) (string, []Directive, error) {
+	if text == "" {
+		return "", nil, nil
+	}
+
+	var (
+		dirs []Directive
+		kept []string
+	)
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if !strings.HasPrefix(trimmed, prefix) {
+			kept = append(kept, l)
+			continue
+		}
+
+		d, reason := parseDirective(strings.TrimPrefix(trimmed, prefix))
+		if reason == "" {
+			dirs = append(dirs, d)
+			continue
+		}
+
+		if strict {
+			return "", nil, DirectiveError{
+				Location:   loc,
+				Comment:    comment,
+				Line:       i + 1,
+				SourceLine: commentSourceLine(loc, comment, len(lines), i),
+				Text:       trimmed,
+				Reason:     reason,
+			}
+		}
+		kept = append(kept, l)
+	}
+
+	for len(kept) > 0 && strings.TrimSpace(kept[0]) == "" {
+		kept = kept[1:]
+	}
+
+	for len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+		kept = kept[:len(kept)-1]
+	}
+
+	if len(kept) == 0 {
+		return "", dirs, nil
+	}
+
+	out := strings.Join(kept, "\n")
+	if strings.HasSuffix(text, "\n") {
+		out += "\n"
+	}
+
+	return out, dirs, nil
+}
+
+// commentSourceLine returns the one-based line of the proto file containing
+// line i of an n line comment attached to the entity at loc, or zero if loc
+// has no span.
+func commentSourceLine(loc *descriptor.SourceCodeInfo_Location, comment string, n, i int) int {
+	span := loc.GetSpan()
+
+	switch {
+	case len(span) < 3:
+		return 0
+	case comment == "leading":
+		return int(span[0]) - n + i + 1
+	case len(span) == 4:
+		return int(span[2]) + i + 1
+	default:
+		return int(span[0]) + i + 1
+	}
+}
+
+// parseDirective parses the key[=value] portion of a directive line. A non-
+// empty reason is returned if the directive is malformed.
+func parseDirective(s string) (d Directive, reason string) {
+	end := strings.IndexFunc(s, func(r rune) bool { return !isDirectiveKeyRune(r) })
+	if end == -1 {
+		end = len(s)
+	}
+
+	if d.Key = s[:end]; d.Key == "" {
+		return d, "missing key"
+	}
+
+	rest := s[end:]
+	if rest == "" {
+		return d, ""
+	}
+
+	switch r, _ := utf8.DecodeRuneInString(rest); {
+	case r == '=':
+		d.Value, d.HasValue = strings.TrimSpace(rest[1:]), true
+	case unicode.IsSpace(r):
+		if rest = strings.TrimSpace(rest); strings.HasPrefix(rest, "=") {
+			d.Value, d.HasValue = strings.TrimSpace(rest[1:]), true
+			break
+		}
+		d.Value, d.HasValue = rest, rest != ""
+	default:
+		return d, fmt.Sprintf("unexpected character %q after key %q", r, d.Key)
+	}
+
+	return d, ""
+}
+
+func isDirectiveKeyRune(r rune) bool {
+	switch r {
+	case '_', '-', '.', ':', '/':
+		return true
+	default:
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+}
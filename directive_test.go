@@ -0,0 +1,177 @@
+package pgs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestSourceCodeInfo_Directives(t *testing.T) {
+	t.Parallel()
+
+	info := sci{desc: &descriptor.SourceCodeInfo_Location{
+		LeadingComments:  proto.String(" Foo does things.\n\n +gen:skip\n +gen:tag=x\n"),
+		TrailingComments: proto.String(" trailing\n +gen:name = bar \n"),
+	}}
+
+	d, err := info.Directives("+")
+	require.NoError(t, err)
+
+	assert.Equal(t, " Foo does things.\n", d.LeadingComments)
+	assert.Equal(t, " trailing\n", d.TrailingComments)
+	assert.Equal(t, []Directive{
+		{Key: "gen:skip"},
+		{Key: "gen:tag", Value: "x", HasValue: true},
+		{Key: "gen:name", Value: "bar", HasValue: true},
+	}, d.List)
+
+	assert.True(t, d.Has("gen:skip"))
+	assert.False(t, d.Has("skip"))
+
+	dir, ok := d.Lookup("gen:tag")
+	assert.True(t, ok)
+	assert.Equal(t, "x", dir.Value)
+
+	_, err = info.Directives("")
+	assert.Error(t, err)
+}
+
+func TestParseDirective(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in       string
+		expected Directive
+		reason   bool
+	}{
+		{"skip", Directive{Key: "skip"}, false},
+		{"tag=x", Directive{Key: "tag", Value: "x", HasValue: true}, false},
+		{"tag=", Directive{Key: "tag", HasValue: true}, false},
+		{"deprecated use Foo", Directive{Key: "deprecated", Value: "use Foo", HasValue: true}, false},
+		{"deprecated   ", Directive{Key: "deprecated"}, false},
+		{"a.b/c-d_e:f", Directive{Key: "a.b/c-d_e:f"}, false},
+		{"", Directive{}, true},
+		{"=x", Directive{}, true},
+		{"tag!", Directive{}, true},
+		{"tag→x", Directive{}, true},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+
+			d, reason := parseDirective(tc.in)
+			if tc.reason {
+				assert.NotEmpty(t, reason)
+				return
+			}
+			assert.Empty(t, reason)
+			assert.Equal(t, tc.expected, d)
+		})
+	}
+}
+
+func TestParseDirectives_OnlyDirectives(t *testing.T) {
+	t.Parallel()
+
+	d, err := parseDirectives(&descriptor.SourceCodeInfo_Location{
+		LeadingComments: proto.String(" pgs:skip\n"),
+	}, "pgs:", false)
+	require.NoError(t, err)
+
+	assert.Empty(t, d.LeadingComments)
+	assert.Empty(t, d.TrailingComments)
+	assert.Equal(t, []Directive{{Key: "skip"}}, d.List)
+}
+
+func TestParseDirectives_Malformed(t *testing.T) {
+	t.Parallel()
+
+	info := sci{desc: &descriptor.SourceCodeInfo_Location{
+		LeadingComments: proto.String(" Options:\n + item one\n +gen:skip\n +bad!\n"),
+	}}
+
+	d, err := info.Directives("+")
+	require.NoError(t, err)
+	assert.Equal(t, " Options:\n + item one\n +bad!\n", d.LeadingComments)
+	assert.Equal(t, []Directive{{Key: "gen:skip"}}, d.List)
+
+	_, err = info.StrictDirectives("+")
+	var de DirectiveError
+	require.True(t, errors.As(err, &de))
+	assert.Equal(t, 2, de.Line)
+	assert.Equal(t, "+ item one", de.Text)
+}
+
+func TestParseDirectives_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		loc     *descriptor.SourceCodeInfo_Location
+		comment string
+		line    int
+		src     int
+	}{
+		{
+			name: "leading",
+			loc: &descriptor.SourceCodeInfo_Location{
+				Span:            []int32{9, 2, 30},
+				LeadingComments: proto.String(" ok\n @bad!\n more\n"),
+			},
+			comment: "leading",
+			line:    2,
+			src:     8,
+		},
+		{
+			name: "trailing",
+			loc: &descriptor.SourceCodeInfo_Location{
+				Span:             []int32{9, 2, 12, 3},
+				LeadingComments:  proto.String(" ok\n"),
+				TrailingComments: proto.String(" fine\n @bad!\n"),
+			},
+			comment: "trailing",
+			line:    2,
+			src:     14,
+		},
+		{
+			name: "no span",
+			loc: &descriptor.SourceCodeInfo_Location{
+				LeadingComments: proto.String(" @bad!\n"),
+			},
+			comment: "leading",
+			line:    1,
+		},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseDirectives(tc.loc, "@", true)
+			require.Error(t, err)
+
+			var de DirectiveError
+			require.True(t, errors.As(err, &de))
+			assert.Equal(t, tc.loc, de.Location)
+			assert.Equal(t, tc.comment, de.Comment)
+			assert.Equal(t, tc.line, de.Line)
+			assert.Equal(t, tc.src, de.SourceLine)
+			assert.Equal(t, "@bad!", de.Text)
+
+			if tc.src > 0 {
+				assert.True(t, strings.HasPrefix(err.Error(), fmt.Sprintf("line %d: ", tc.src)), err.Error())
+			} else {
+				assert.False(t, strings.HasPrefix(err.Error(), "line "), err.Error())
+			}
+		})
+	}
+}
@@ -41,12 +41,29 @@ type Entity interface {
 	// protoc run or if it was loaded as an external dependency.
 	BuildTarget() bool
 
+	// Deprecated returns true if the entity is marked with the deprecated
+	// option. Deprecation is not inherited: a Field of a deprecated Message is
+	// only deprecated if it is also marked as such. OneOfs cannot be
+	// deprecated and always return false.
+	Deprecated() bool
+
 	// SourceCodeInfo returns the SourceCodeInfo associated with the entity.
 	// Primarily, this struct contains the comments associated with the Entity.
 	SourceCodeInfo() SourceCodeInfo
 
+	// OptionSourceCodeInfo returns the SourceCodeInfo of an option set on the
+	// entity, identified by the path of field numbers within the entity's
+	// options message. For example, the path for the option
+	// `(validate.rules).string.min_len` is {1071, 14, 2} (see OptionPath). If
+	// the option was set as part of an aggregate value, the SourceCodeInfo of
+	// the most specific enclosing option is returned. With no path, the
+	// SourceCodeInfo of the entire options declaration is returned, if
+	// provided by protoc. Nil is returned if no matching location is found.
+	OptionSourceCodeInfo(path ...int32) SourceCodeInfo
+
 	childAtPath(path []int32) Entity
 	addSourceCodeInfo(info SourceCodeInfo)
+	addOptionSourceCodeInfo(path []int32, info SourceCodeInfo)
 }
 
 // A ParentEntity is any Entity type that can contain messages and/or enums.
@@ -1,6 +1,8 @@
 package pgs
 
 import (
+	"sync"
+
 	"google.golang.org/protobuf/runtime/protoimpl"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -24,7 +26,23 @@ type Enum interface {
 	// transitively used.
 	Dependents() []Message
 
+	// ReservedRanges returns the ranges of values reserved on this Enum.
+	ReservedRanges() []NumberRange
+
+	// ReservedNames returns the value names reserved on this Enum.
+	ReservedNames() []Name
+
+	// IsNumberReserved returns true if the value n is within one of the Enum's
+	// reserved ranges.
+	IsNumberReserved(n int32) bool
+
+	// IsNameReserved returns true if name is one of the Enum's reserved value
+	// names.
+	IsNameReserved(name Name) bool
+
 	addValue(v EnumValue)
+	addReservedRange(r NumberRange)
+	rangeAtPath(path []int32) NumberRange
 	addDependent(m Message)
 	setParent(p ParentEntity)
 }
@@ -34,9 +52,12 @@ type enum struct {
 	parent          ParentEntity
 	vals            []EnumValue
 	info            SourceCodeInfo
+	optInfo         optionLocations
 	fqn             string
 	dependents      []Message
+	dependentsOnce  sync.Once
 	dependentsCache map[string]Message
+	reserved        []NumberRange
 }
 
 func (e *enum) Name() Name                                  { return Name(e.desc.GetName()) }
@@ -45,26 +66,44 @@ func (e *enum) Syntax() Syntax                              { return e.parent.Sy
 func (e *enum) Package() Package                            { return e.parent.Package() }
 func (e *enum) File() File                                  { return e.parent.File() }
 func (e *enum) BuildTarget() bool                           { return e.parent.BuildTarget() }
+func (e *enum) Deprecated() bool                            { return e.desc.GetOptions().GetDeprecated() }
 func (e *enum) SourceCodeInfo() SourceCodeInfo              { return e.info }
 func (e *enum) Descriptor() *descriptor.EnumDescriptorProto { return e.desc }
 func (e *enum) Parent() ParentEntity                        { return e.parent }
 func (e *enum) Imports() []File                             { return nil }
 func (e *enum) Values() []EnumValue                         { return e.vals }
+func (e *enum) ReservedRanges() []NumberRange               { return e.reserved }
 
-func (e *enum) populateDependentsCache() {
-	if e.dependentsCache != nil {
-		return
+func (e *enum) ReservedNames() []Name {
+	names := make([]Name, len(e.desc.GetReservedName()))
+	for i, n := range e.desc.GetReservedName() {
+		names[i] = Name(n)
 	}
+	return names
+}
+
+func (e *enum) IsNumberReserved(n int32) bool {
+	for _, r := range e.reserved {
+		if r.Contains(n) {
+			return true
+		}
+	}
+	return false
+}
 
-	e.dependentsCache = map[string]Message{}
-	for _, dep := range e.dependents {
-		e.dependentsCache[dep.FullyQualifiedName()] = dep
-		dep.getDependents(e.dependentsCache)
+func (e *enum) IsNameReserved(name Name) bool {
+	for _, n := range e.desc.GetReservedName() {
+		if n == name.String() {
+			return true
+		}
 	}
+	return false
 }
 
 func (e *enum) Dependents() []Message {
-	e.populateDependentsCache()
+	e.dependentsOnce.Do(func() {
+		e.dependentsCache = collectMessages(e.dependents, Message.directDependents)
+	})
 	return messageSetToSlice("", e.dependentsCache)
 }
 
@@ -99,6 +138,10 @@ func (e *enum) addValue(v EnumValue) {
 	e.vals = append(e.vals, v)
 }
 
+func (e *enum) addReservedRange(r NumberRange) {
+	e.reserved = append(e.reserved, r)
+}
+
 func (e *enum) setParent(p ParentEntity) { e.parent = p }
 
 func (e *enum) childAtPath(path []int32) Entity {
@@ -114,6 +157,21 @@ func (e *enum) childAtPath(path []int32) Entity {
 	}
 }
 
+func (e *enum) rangeAtPath(path []int32) NumberRange {
+	if len(path) != 2 || path[0] != enumTypeReservedPath {
+		return nil
+	}
+	return e.reserved[path[1]]
+}
+
 func (e *enum) addSourceCodeInfo(info SourceCodeInfo) { e.info = info }
 
+func (e *enum) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return e.optInfo.lookup(path)
+}
+
+func (e *enum) addOptionSourceCodeInfo(path []int32, info SourceCodeInfo) {
+	e.optInfo = append(e.optInfo, optionLocation{path: path, info: info})
+}
+
 var _ Enum = (*enum)(nil)
@@ -210,6 +210,29 @@ func (e *mockEnum) accept(v Visitor) error {
 	return err
 }
 
+func TestEnum_Reserved(t *testing.T) {
+	t.Parallel()
+
+	e := dummyEnum()
+	e.desc.ReservedName = []string{"FOO"}
+	assert.Equal(t, []Name{"FOO"}, e.ReservedNames())
+	assert.True(t, e.IsNameReserved("FOO"))
+	assert.False(t, e.IsNameReserved("BAR"))
+
+	assert.Empty(t, e.ReservedRanges())
+	assert.False(t, e.IsNumberReserved(2))
+
+	r := newInclusiveRange(2, 5)
+	e.addReservedRange(&r)
+	assert.Len(t, e.ReservedRanges(), 1)
+	assert.True(t, e.IsNumberReserved(5))
+	assert.False(t, e.IsNumberReserved(6))
+
+	assert.Equal(t, &r, e.rangeAtPath([]int32{enumTypeReservedPath, 0}))
+	assert.Nil(t, e.rangeAtPath([]int32{enumTypeValuePath, 0}))
+	assert.Nil(t, e.rangeAtPath(nil))
+}
+
 func dummyEnum() *enum {
 	f := dummyFile()
 	e := &enum{desc: &descriptor.EnumDescriptorProto{Name: proto.String("enum")}}
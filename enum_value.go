@@ -26,7 +26,8 @@ type enumVal struct {
 	enum Enum
 	fqn  string
 
-	info SourceCodeInfo
+	info    SourceCodeInfo
+	optInfo optionLocations
 }
 
 func (ev *enumVal) Name() Name                                       { return Name(ev.desc.GetName()) }
@@ -35,6 +36,7 @@ func (ev *enumVal) Syntax() Syntax                                   { return ev
 func (ev *enumVal) Package() Package                                 { return ev.enum.Package() }
 func (ev *enumVal) File() File                                       { return ev.enum.File() }
 func (ev *enumVal) BuildTarget() bool                                { return ev.enum.BuildTarget() }
+func (ev *enumVal) Deprecated() bool                                 { return ev.desc.GetOptions().GetDeprecated() }
 func (ev *enumVal) SourceCodeInfo() SourceCodeInfo                   { return ev.info }
 func (ev *enumVal) Descriptor() *descriptor.EnumValueDescriptorProto { return ev.desc }
 func (ev *enumVal) Enum() Enum                                       { return ev.enum }
@@ -66,4 +68,12 @@ func (ev *enumVal) childAtPath(path []int32) Entity {
 
 func (ev *enumVal) addSourceCodeInfo(info SourceCodeInfo) { ev.info = info }
 
+func (ev *enumVal) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return ev.optInfo.lookup(path)
+}
+
+func (ev *enumVal) addOptionSourceCodeInfo(path []int32, info SourceCodeInfo) {
+	ev.optInfo = append(ev.optInfo, optionLocation{path: path, info: info})
+}
+
 var _ EnumValue = (*enumVal)(nil)
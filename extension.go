@@ -44,6 +44,7 @@ func (e *ext) OneOf() OneOf               { return nil }
 func (e *ext) setMessage(m Message)       {} // noop
 func (e *ext) setOneOf(o OneOf)           {} // noop
 func (e *ext) setExtendee(m Message)      { e.extendee = m }
+func (e *ext) IsDelimited() bool          { return isDelimited(e, e.parent) }
 
 func (e *ext) accept(v Visitor) (err error) {
 	if v == nil {
@@ -0,0 +1,226 @@
+package pgs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultExternalTimeout = 30 * time.Second
+
+// A MissingCommandPolicy controls how an ExternalFormatter behaves if its
+// command cannot be found.
+type MissingCommandPolicy int
+
+const (
+	// SkipMissingCommand leaves the output unchanged and logs a warning for
+	// each Artifact the formatter would have processed.
+	SkipMissingCommand MissingCommandPolicy = iota
+
+	// FailMissingCommand fails code generation.
+	FailMissingCommand
+)
+
+// ExternalCommand configures an ExternalFormatter.
+type ExternalCommand struct {
+	// Command is the name or path of the formatter executable, such as
+	// "clang-format" or "prettier". Names without a path separator are
+	// resolved using PATH.
+	Command string
+
+	// Args are passed to Command. The placeholders "{name}" and "{ext}" are
+	// replaced with the Artifact's file name and extension, which many
+	// formatters accept as a hint for the contents of stdin (eg,
+	// "--stdin-filepath={name}" or "--assume-filename={name}").
+	Args []string
+
+	// Patterns are globs matched against the file names of the Artifacts to
+	// format, using path.Match syntax (eg, "*.ts"). Patterns without a "/" are
+	// matched against the base name of the file. If empty, no Artifacts are
+	// matched.
+	Patterns []string
+
+	// Timeout limits how long Command may run for a single Artifact. If zero,
+	// a timeout of 30 seconds is used.
+	Timeout time.Duration
+
+	// Env contains additional "KEY=value" environment variables for Command.
+	Env []string
+
+	// Dir is the working directory of Command. Formatters typically discover
+	// their configuration (eg, .clang-format, .prettierrc, rustfmt.toml, or
+	// pyproject.toml) by searching upward from it. If empty, the working
+	// directory of the plugin process is used.
+	Dir string
+
+	// TempDir runs Command in a new, empty temporary directory instead of Dir,
+	// isolating it from any configuration files.
+	TempDir bool
+
+	// Missing sets the behavior if Command cannot be found. By default, the
+	// formatter is skipped with a warning.
+	Missing MissingCommandPolicy
+}
+
+// ExternalFormatter returns a PostProcessor that pipes the contents of
+// matching Artifacts through a local command, replacing them with its
+// standard output. It allows using formatters without a Go implementation,
+// such as clang-format, prettier, rustfmt, or black:
+//
+//	g.RegisterPostProcessor(pgs.ExternalFormatter(pgs.ExternalCommand{
+//	  Command:  "prettier",
+//	  Args:     []string{"--stdin-filepath", "{name}"},
+//	  Patterns: []string{"*.ts", "*.js"},
+//	}))
+//
+// The command is run in Dir, or an empty temporary directory if TempDir is
+// set, with a minimal environment: PATH, HOME, TMPDIR, Env, and PGS_FILE_NAME
+// and PGS_FILE_EXT describing the Artifact. A non-zero exit status fails code
+// generation with the command's standard error, as does empty output for
+// non-empty input, which usually indicates the command rewrote a file in
+// place (eg, "clang-format -i") instead of printing the result. Results are
+// cached by the name and contents of the input, so identical files are only
+// formatted once.
+//
+// The PostProcessor's name is the base name of Command. It implements
+// ArtifactPostProcessor, as the command's arguments depend on the Artifact.
+func ExternalFormatter(cmd ExternalCommand) PostProcessor {
+	if cmd.Timeout <= 0 {
+		cmd.Timeout = defaultExternalTimeout
+	}
+	return &externalFormatter{cmd: cmd, cache: map[[sha256.Size]byte][]byte{}}
+}
+
+type externalFormatter struct {
+	cmd ExternalCommand
+
+	lookup    sync.Once
+	path      string
+	lookupErr error
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte][]byte
+}
+
+func (f *externalFormatter) Name() string { return filepath.Base(f.cmd.Command) }
+
+func (f *externalFormatter) Match(a Artifact) bool {
+	switch a.(type) {
+	case GeneratorFile, GeneratorTemplateFile, CustomFile, CustomTemplateFile:
+	default:
+		return false
+	}
+
+	name := ArtifactName(a)
+	for _, p := range f.cmd.Patterns {
+		target := name
+		if !strings.Contains(p, "/") {
+			target = path.Base(filepath.ToSlash(name))
+		}
+
+		if ok, _ := path.Match(p, target); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Process formats in without a file name, which is passed to the command as
+// an empty string.
+func (f *externalFormatter) Process(in []byte) ([]byte, error) { return f.format("", in) }
+
+func (f *externalFormatter) ProcessArtifact(a Artifact, in []byte) ([]byte, error) {
+	return f.format(ArtifactName(a), in)
+}
+
+func (f *externalFormatter) format(name string, in []byte) ([]byte, error) {
+	f.lookup.Do(func() { f.path, f.lookupErr = exec.LookPath(f.cmd.Command) })
+	if f.lookupErr != nil {
+		if f.cmd.Missing == SkipMissingCommand {
+			return nil, fmt.Errorf("%w: %v", ErrPostProcessorSkipped, f.lookupErr)
+		}
+		return nil, f.lookupErr
+	}
+
+	key := sha256.Sum256(append([]byte(name+"\x00"), in...))
+
+	f.mu.Lock()
+	out, ok := f.cache[key]
+	f.mu.Unlock()
+	if ok {
+		return out, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.cmd.Timeout)
+	defer cancel()
+
+	out, err := f.run(ctx, name, in)
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("%s timed out after %v", f.cmd.Command, f.cmd.Timeout)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 && len(bytes.TrimSpace(in)) > 0 {
+		return nil, fmt.Errorf("%s produced no output; the formatted result must be written to stdout", f.cmd.Command)
+	}
+
+	f.mu.Lock()
+	f.cache[key] = out
+	f.mu.Unlock()
+
+	return out, nil
+}
+
+// run executes the command in the configured directory, writing in to its
+// standard input and returning its standard output.
+func (f *externalFormatter) run(ctx context.Context, name string, in []byte) ([]byte, error) {
+	dir, tmp := f.cmd.Dir, os.TempDir()
+	if f.cmd.TempDir {
+		var err error
+		if dir, err = os.MkdirTemp("", "pgs-format-"); err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		tmp = dir
+	}
+
+	ext := filepath.Ext(name)
+	args := make([]string, len(f.cmd.Args))
+	r := strings.NewReplacer("{name}", name, "{ext}", ext)
+	for i, a := range f.cmd.Args {
+		args[i] = r.Replace(a)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	c := exec.CommandContext(ctx, f.path, args...)
+	c.Dir = dir
+	c.Stdin = bytes.NewReader(in)
+	c.Stdout, c.Stderr = stdout, stderr
+	c.Env = append([]string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + os.Getenv("HOME"),
+		"TMPDIR=" + tmp,
+		"PGS_FILE_NAME=" + name,
+		"PGS_FILE_EXT=" + ext,
+	}, f.cmd.Env...)
+
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", f.cmd.Command, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", f.cmd.Command, err)
+	}
+
+	return stdout.Bytes(), nil
+}
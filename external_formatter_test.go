@@ -0,0 +1,164 @@
+package pgs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func shFormatter(t *testing.T, script string) *externalFormatter {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	return ExternalFormatter(ExternalCommand{
+		Command:  "sh",
+		Args:     []string{"-c", script, "fmt", "{name}", "{ext}"},
+		Patterns: []string{"*.txt"},
+		Env:      []string{"GREETING=hi"},
+	}).(*externalFormatter)
+}
+
+func TestExternalFormatter_Match(t *testing.T) {
+	t.Parallel()
+
+	f := ExternalFormatter(ExternalCommand{
+		Command:  "/usr/bin/prettier",
+		Patterns: []string{"*.ts", "web/*.js"},
+	})
+	assert.Equal(t, "prettier", PostProcessorName(f))
+
+	tests := []struct {
+		a Artifact
+		m bool
+	}{
+		{GeneratorFile{Name: "foo/bar.ts"}, true},
+		{CustomTemplateFile{Name: "bar.ts"}, true},
+		{GeneratorFile{Name: "web/bar.js"}, true},
+		{GeneratorFile{Name: "other/bar.js"}, false},
+		{GeneratorAppend{FileName: "bar.ts"}, false},
+		{GeneratorBytesFile{Name: "bar.ts"}, false},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.m, f.Match(tc.a), ArtifactName(tc.a))
+	}
+}
+
+func TestExternalFormatter_Process(t *testing.T) {
+	t.Parallel()
+
+	f := shFormatter(t, `tr a-z A-Z; echo "$1 $2 $PGS_FILE_NAME $PGS_FILE_EXT $GREETING $(ls -A | wc -l | tr -d ' ')"`)
+	f.cmd.TempDir = true
+
+	a := CustomFile{Name: "foo/bar.txt"}
+	require.True(t, f.Match(a))
+	require.True(t, f.Match(CustomFile{Name: "other.txt"}), "Match should not affect the Artifact processed")
+
+	out, err := f.ProcessArtifact(a, []byte("hello\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO\nfoo/bar.txt .txt foo/bar.txt .txt hi 0\n", string(out))
+
+	f.cmd.Args = []string{"-c", "echo changed"}
+	out, err = f.ProcessArtifact(a, []byte("hello\n"))
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "HELLO", "results should be cached by name and content")
+
+	out, err = f.ProcessArtifact(CustomFile{Name: "other.txt"}, []byte("hello\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed\n", string(out))
+}
+
+func TestExternalFormatter_Process_Dir(t *testing.T) {
+	t.Parallel()
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	f := shFormatter(t, "cat > /dev/null; pwd")
+	out, err := f.ProcessArtifact(CustomFile{Name: "foo.txt"}, []byte("x"))
+	require.NoError(t, err)
+	assert.Equal(t, wd+"\n", string(out), "should default to the process working directory")
+
+	dir := t.TempDir()
+	f = shFormatter(t, "cat > /dev/null; pwd")
+	f.cmd.Dir = dir
+	out, err = f.ProcessArtifact(CustomFile{Name: "foo.txt"}, []byte("x"))
+	require.NoError(t, err)
+	assert.Equal(t, dir+"\n", string(out))
+}
+
+func TestExternalFormatter_Process_Errors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("exit status", func(t *testing.T) {
+		t.Parallel()
+
+		f := shFormatter(t, "echo 'syntax error' >&2; exit 3")
+		_, err := f.ProcessArtifact(GeneratorFile{Name: "foo.txt"}, []byte("x"))
+		assert.EqualError(t, err, "sh: exit status 3: syntax error")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		f := shFormatter(t, "exec sleep 5")
+		f.cmd.Timeout = 50 * time.Millisecond
+		_, err := f.ProcessArtifact(GeneratorFile{Name: "foo.txt"}, []byte("x"))
+		assert.EqualError(t, err, "sh timed out after 50ms")
+	})
+
+	t.Run("empty output", func(t *testing.T) {
+		t.Parallel()
+
+		f := shFormatter(t, "cat > /dev/null")
+		_, err := f.ProcessArtifact(GeneratorFile{Name: "foo.txt"}, []byte("x"))
+		assert.EqualError(t, err, "sh produced no output; the formatted result must be written to stdout")
+
+		out, err := f.ProcessArtifact(GeneratorFile{Name: "foo.txt"}, []byte("\n"))
+		assert.NoError(t, err)
+		assert.Empty(t, out)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		t.Parallel()
+
+		f := ExternalFormatter(ExternalCommand{Command: "pgs-no-such-formatter", Patterns: []string{"*"}})
+		_, err := f.Process([]byte("x"))
+		assert.True(t, errors.Is(err, ErrPostProcessorSkipped))
+
+		f = ExternalFormatter(ExternalCommand{
+			Command:  "pgs-no-such-formatter",
+			Patterns: []string{"*"},
+			Missing:  FailMissingCommand,
+		})
+		_, err = f.Process([]byte("x"))
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, ErrPostProcessorSkipped))
+	})
+}
+
+func TestPersister_PostProcess_Skipped(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	p.AddPostProcessor(ExternalFormatter(ExternalCommand{
+		Command:  "pgs-no-such-formatter",
+		Patterns: []string{"*.ts"},
+	}))
+
+	resp := p.Persist(GeneratorFile{Name: "foo.ts", Contents: "let x"})
+
+	require.NoError(t, d.Err())
+	assert.Equal(t, "let x", resp.File[0].GetContent())
+
+	out, _ := ioutil.ReadAll(d.Output())
+	assert.Contains(t, string(out), "warning: post-processor pgs-no-such-formatter failed on foo.ts: post-processor skipped")
+}
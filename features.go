@@ -0,0 +1,153 @@
+package pgs
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Field numbers of the `features` field on each of the descriptor options
+// messages, and the features within the FeatureSet message. These are read
+// directly from the wire format so that they resolve regardless of whether
+// the descriptor package in use predates editions.
+const (
+	fileOptionsFeaturesPath    protowire.Number = 50 // FileOptions.features
+	messageOptionsFeaturesPath protowire.Number = 12 // MessageOptions.features
+	fieldOptionsFeaturesPath   protowire.Number = 21 // FieldOptions.features
+	oneofOptionsFeaturesPath   protowire.Number = 2  // OneofOptions.features
+
+	featureMessageEncodingPath protowire.Number = 5 // FeatureSet.message_encoding
+)
+
+// featureMessageEncodingDelimited is the DELIMITED value of the
+// FeatureSet.MessageEncoding enum.
+const featureMessageEncodingDelimited uint64 = 2
+
+// isDelimited resolves whether the message-typed field f uses the delimited
+// encoding. For editions files, the message_encoding feature is resolved from
+// the field, its oneof (if any), and then each enclosing scope out to the
+// file.
+func isDelimited(f Field, scope Entity) bool {
+	if f.IsGroup() {
+		return true
+	}
+
+	if f.Syntax() != Editions || ProtoType(f.Descriptor().GetType()) != MessageT {
+		return false
+	}
+
+	// map fields are always length-prefixed, regardless of features
+	if t := f.Type(); t != nil && t.IsMap() {
+		return false
+	}
+
+	if v, ok := feature(f.Descriptor().GetOptions(), fieldOptionsFeaturesPath, featureMessageEncodingPath); ok {
+		return v == featureMessageEncodingDelimited
+	}
+
+	if o := f.OneOf(); o != nil {
+		if v, ok := feature(o.Descriptor().GetOptions(), oneofOptionsFeaturesPath, featureMessageEncodingPath); ok {
+			return v == featureMessageEncodingDelimited
+		}
+	}
+
+	for scope != nil {
+		var opts proto.Message
+		var path protowire.Number
+
+		switch e := scope.(type) {
+		case Message:
+			opts, path, scope = e.Descriptor().GetOptions(), messageOptionsFeaturesPath, e.Parent()
+		case File:
+			opts, path, scope = e.Descriptor().GetOptions(), fileOptionsFeaturesPath, nil
+		default:
+			return false
+		}
+
+		if v, ok := feature(opts, path, featureMessageEncodingPath); ok {
+			return v == featureMessageEncodingDelimited
+		}
+	}
+
+	return false
+}
+
+// feature extracts the varint value of the FeatureSet field num from the
+// `features` field (identified by path) of the options message opts. The last
+// occurrence wins, matching proto merge semantics. If the feature is not set,
+// ok will be false.
+func feature(opts proto.Message, path, num protowire.Number) (v uint64, ok bool) {
+	if opts == nil || !opts.ProtoReflect().IsValid() {
+		return 0, false
+	}
+
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(opts)
+	if err != nil {
+		return 0, false
+	}
+
+	for _, fs := range fieldBytes(b, path) {
+		for _, val := range fieldVarints(fs, num) {
+			v, ok = val, true
+		}
+	}
+
+	return v, ok
+}
+
+// fieldBytes returns the values of every length-delimited occurrence of field
+// num in the wire-encoded message b.
+func fieldBytes(b []byte, num protowire.Number) (out [][]byte) {
+	for len(b) > 0 {
+		n, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return out
+		}
+		b = b[l:]
+
+		if n == num && typ == protowire.BytesType {
+			v, l := protowire.ConsumeBytes(b)
+			if l < 0 {
+				return out
+			}
+			out = append(out, v)
+			b = b[l:]
+			continue
+		}
+
+		if l = protowire.ConsumeFieldValue(n, typ, b); l < 0 {
+			return out
+		}
+		b = b[l:]
+	}
+
+	return out
+}
+
+// fieldVarints returns the values of every varint occurrence of field num in
+// the wire-encoded message b.
+func fieldVarints(b []byte, num protowire.Number) (out []uint64) {
+	for len(b) > 0 {
+		n, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return out
+		}
+		b = b[l:]
+
+		if n == num && typ == protowire.VarintType {
+			v, l := protowire.ConsumeVarint(b)
+			if l < 0 {
+				return out
+			}
+			out = append(out, v)
+			b = b[l:]
+			continue
+		}
+
+		if l = protowire.ConsumeFieldValue(n, typ, b); l < 0 {
+			return out
+		}
+		b = b[l:]
+	}
+
+	return out
+}
@@ -0,0 +1,141 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func withFeature(opts proto.Message, path, num protowire.Number, v uint64) {
+	var fs []byte
+	fs = protowire.AppendTag(fs, num, protowire.VarintType)
+	fs = protowire.AppendVarint(fs, v)
+
+	b := opts.ProtoReflect().GetUnknown()
+	b = protowire.AppendTag(b, path, protowire.BytesType)
+	b = protowire.AppendBytes(b, fs)
+	opts.ProtoReflect().SetUnknown(b)
+}
+
+func TestFeature(t *testing.T) {
+	t.Parallel()
+
+	assert.NotPanics(t, func() {
+		_, ok := feature((*descriptor.FieldOptions)(nil), fieldOptionsFeaturesPath, featureMessageEncodingPath)
+		assert.False(t, ok)
+	})
+
+	opts := &descriptor.FieldOptions{Deprecated: proto.Bool(true)}
+	_, ok := feature(opts, fieldOptionsFeaturesPath, featureMessageEncodingPath)
+	assert.False(t, ok)
+
+	withFeature(opts, fieldOptionsFeaturesPath, 1, 1)
+	_, ok = feature(opts, fieldOptionsFeaturesPath, featureMessageEncodingPath)
+	assert.False(t, ok, "unrelated feature")
+
+	withFeature(opts, fieldOptionsFeaturesPath, featureMessageEncodingPath, 1)
+	withFeature(opts, fieldOptionsFeaturesPath, featureMessageEncodingPath, featureMessageEncodingDelimited)
+	v, ok := feature(opts, fieldOptionsFeaturesPath, featureMessageEncodingPath)
+	assert.True(t, ok)
+	assert.Equal(t, featureMessageEncodingDelimited, v, "last value wins")
+}
+
+func TestIsDelimited(t *testing.T) {
+	t.Parallel()
+
+	setup := func(syntax Syntax) (*file, *msg, *field) {
+		f := dummyFile()
+		f.desc.Syntax = proto.String(string(syntax))
+		f.desc.Options = &descriptor.FileOptions{}
+
+		m := &msg{desc: &descriptor.DescriptorProto{Options: &descriptor.MessageOptions{}}}
+		f.addMessage(m)
+
+		fld := &field{desc: &descriptor.FieldDescriptorProto{
+			Type:    MessageT.ProtoPtr(),
+			Options: &descriptor.FieldOptions{},
+		}}
+		m.addField(fld)
+		fld.addType(&embedT{scalarT: &scalarT{}})
+
+		return f, m, fld
+	}
+
+	t.Run("group", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, fld := setup(Proto2)
+		fld.desc.Type = GroupT.ProtoPtr()
+		assert.True(t, fld.IsGroup())
+		assert.True(t, fld.IsDelimited())
+	})
+
+	t.Run("proto2 message", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, fld := setup(Proto2)
+		withFeature(fld.desc.Options, fieldOptionsFeaturesPath, featureMessageEncodingPath, featureMessageEncodingDelimited)
+		assert.False(t, fld.IsGroup())
+		assert.False(t, fld.IsDelimited(), "features are ignored outside of editions")
+	})
+
+	t.Run("editions default", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, fld := setup(Editions)
+		assert.False(t, fld.IsDelimited())
+	})
+
+	t.Run("editions field", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, fld := setup(Editions)
+		withFeature(fld.desc.Options, fieldOptionsFeaturesPath, featureMessageEncodingPath, featureMessageEncodingDelimited)
+		assert.True(t, fld.IsDelimited())
+	})
+
+	t.Run("editions message", func(t *testing.T) {
+		t.Parallel()
+
+		_, m, fld := setup(Editions)
+		withFeature(m.desc.Options, messageOptionsFeaturesPath, featureMessageEncodingPath, featureMessageEncodingDelimited)
+		assert.True(t, fld.IsDelimited())
+
+		withFeature(fld.desc.Options, fieldOptionsFeaturesPath, featureMessageEncodingPath, 1)
+		assert.False(t, fld.IsDelimited(), "field overrides message")
+	})
+
+	t.Run("editions file", func(t *testing.T) {
+		t.Parallel()
+
+		f, _, fld := setup(Editions)
+		withFeature(f.desc.Options, fileOptionsFeaturesPath, featureMessageEncodingPath, featureMessageEncodingDelimited)
+		assert.True(t, fld.IsDelimited())
+
+		fld.addType(&mapT{repT: &repT{scalarT: &scalarT{}}})
+		assert.False(t, fld.IsDelimited(), "maps are never delimited")
+	})
+
+	t.Run("editions scalar", func(t *testing.T) {
+		t.Parallel()
+
+		f, _, fld := setup(Editions)
+		withFeature(f.desc.Options, fileOptionsFeaturesPath, featureMessageEncodingPath, featureMessageEncodingDelimited)
+		fld.desc.Type = StringT.ProtoPtr()
+		assert.False(t, fld.IsDelimited())
+	})
+
+	t.Run("editions extension", func(t *testing.T) {
+		t.Parallel()
+
+		f, _, _ := setup(Editions)
+		withFeature(f.desc.Options, fileOptionsFeaturesPath, featureMessageEncodingPath, featureMessageEncodingDelimited)
+
+		e := &ext{parent: f}
+		e.desc = &descriptor.FieldDescriptorProto{Type: MessageT.ProtoPtr()}
+		assert.True(t, e.IsDelimited())
+	})
+}
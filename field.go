@@ -1,6 +1,11 @@
 package pgs
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
 	"google.golang.org/protobuf/runtime/protoimpl"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -43,6 +48,41 @@ type Field interface {
 	// will only be true if the syntax is proto2.
 	Required() bool
 
+	// IsGroup returns true if the field is a proto2 group. The synthetic
+	// Message generated for the group is available via the FieldType's Embed
+	// (or Element().Embed() for repeated groups), and identifies itself via
+	// Message.IsGroup.
+	IsGroup() bool
+
+	// IsDelimited returns true if the field's message value is encoded on the
+	// wire using the delimited (group) encoding instead of being length
+	// prefixed. This is true for proto2 groups as well as message fields in
+	// editions files that resolve the message_encoding feature to DELIMITED.
+	IsDelimited() bool
+
+	// HasDefaultValue returns true if the field explicitly declares a default
+	// value (eg, `[default = 123]`). Only proto2 and editions fields may
+	// declare a default; this will always be false for proto3 fields.
+	HasDefaultValue() bool
+
+	// DefaultValue returns the default value of the field, parsed from the
+	// descriptor's string encoding into one of the following types based on
+	// its ProtoType:
+	//
+	//     - int64: Int64T, SInt64, SFixed64, Int32T, SInt32, SFixed32
+	//     - uint64: UInt64T, Fixed64T, UInt32T, Fixed32T
+	//     - float64: DoubleT, FloatT (including ±Inf and NaN)
+	//     - bool: BoolT
+	//     - string: StringT
+	//     - []byte: BytesT (C-escape sequences are decoded)
+	//     - EnumValue: EnumT
+	//
+	// If HasDefaultValue is false, the implicit default is returned instead:
+	// the zero value of the type, or the first declared value for enums.
+	// Repeated, map and embedded message fields have no default value and
+	// return nil. An error is returned if the default cannot be parsed.
+	DefaultValue() (interface{}, error)
+
 	setMessage(m Message)
 	setOneOf(o OneOf)
 	addType(t FieldType)
@@ -55,7 +95,8 @@ type field struct {
 	oneof OneOf
 	typ   FieldType
 
-	info SourceCodeInfo
+	info    SourceCodeInfo
+	optInfo optionLocations
 }
 
 func (f *field) Name() Name                                   { return Name(f.desc.GetName()) }
@@ -65,6 +106,7 @@ func (f *field) Package() Package                             { return f.msg.Pac
 func (f *field) Imports() []File                              { return f.typ.Imports() }
 func (f *field) File() File                                   { return f.msg.File() }
 func (f *field) BuildTarget() bool                            { return f.msg.BuildTarget() }
+func (f *field) Deprecated() bool                             { return f.desc.GetOptions().GetDeprecated() }
 func (f *field) SourceCodeInfo() SourceCodeInfo               { return f.info }
 func (f *field) Descriptor() *descriptor.FieldDescriptorProto { return f.desc }
 func (f *field) Message() Message                             { return f.msg }
@@ -108,6 +150,66 @@ func (f *field) Required() bool {
 		f.desc.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REQUIRED
 }
 
+func (f *field) IsGroup() bool { return f.desc.GetType() == descriptor.FieldDescriptorProto_TYPE_GROUP }
+
+func (f *field) IsDelimited() bool { return isDelimited(f, f.msg) }
+
+func (f *field) HasDefaultValue() bool { return f.desc.DefaultValue != nil }
+
+func (f *field) DefaultValue() (interface{}, error) {
+	if f.typ == nil || f.typ.IsRepeated() || f.typ.IsMap() || f.typ.IsEmbed() {
+		return nil, nil
+	}
+
+	s, set := f.desc.GetDefaultValue(), f.HasDefaultValue()
+
+	switch pt := f.typ.ProtoType(); pt {
+	case Int64T, SInt64, SFixed64, Int32T, SInt32, SFixed32:
+		if !set {
+			return int64(0), nil
+		}
+		return strconv.ParseInt(s, 10, 64)
+	case UInt64T, Fixed64T, UInt32T, Fixed32T:
+		if !set {
+			return uint64(0), nil
+		}
+		return strconv.ParseUint(s, 10, 64)
+	case DoubleT, FloatT:
+		if !set {
+			return float64(0), nil
+		}
+		return parseDefaultFloat(s)
+	case BoolT:
+		if !set {
+			return false, nil
+		}
+		return strconv.ParseBool(s)
+	case StringT:
+		return s, nil
+	case BytesT:
+		if !set {
+			return []byte{}, nil
+		}
+		return unescapeDefaultBytes(s)
+	case EnumT:
+		vals := f.typ.Enum().Values()
+		if !set {
+			if len(vals) == 0 {
+				return nil, fmt.Errorf("enum %s has no values", f.typ.Enum().FullyQualifiedName())
+			}
+			return vals[0], nil
+		}
+		for _, ev := range vals {
+			if ev.Name().String() == s {
+				return ev, nil
+			}
+		}
+		return nil, fmt.Errorf("default value %q is not a member of enum %s", s, f.typ.Enum().FullyQualifiedName())
+	default:
+		return nil, fmt.Errorf("unsupported default value type: %v", pt)
+	}
+}
+
 func (f *field) addType(t FieldType) {
 	t.setField(f)
 	f.typ = t
@@ -135,4 +237,92 @@ func (f *field) childAtPath(path []int32) Entity {
 
 func (f *field) addSourceCodeInfo(info SourceCodeInfo) { f.info = info }
 
+func (f *field) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return f.optInfo.lookup(path)
+}
+
+func (f *field) addOptionSourceCodeInfo(path []int32, info SourceCodeInfo) {
+	f.optInfo = append(f.optInfo, optionLocation{path: path, info: info})
+}
+
+// parseDefaultFloat parses the default value encoding protoc uses for
+// floating point fields, which includes the special values inf, -inf and nan.
+func parseDefaultFloat(s string) (float64, error) {
+	switch s {
+	case "inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan":
+		return math.NaN(), nil
+	default:
+		return strconv.ParseFloat(s, 64)
+	}
+}
+
+// unescapeDefaultBytes decodes the C-style escaping protoc applies to the
+// default values of bytes fields.
+func unescapeDefaultBytes(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out = append(out, s[i])
+			continue
+		}
+
+		if i++; i >= len(s) {
+			return nil, errors.New("invalid trailing escape in bytes default value")
+		}
+
+		switch c := s[i]; c {
+		case 'a':
+			out = append(out, '\a')
+		case 'b':
+			out = append(out, '\b')
+		case 'f':
+			out = append(out, '\f')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'v':
+			out = append(out, '\v')
+		case '\\', '\'', '"', '?':
+			out = append(out, c)
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			n := 0
+			for j := 0; j < 3 && i < len(s) && s[i] >= '0' && s[i] <= '7'; i, j = i+1, j+1 {
+				n = n*8 + int(s[i]-'0')
+			}
+			if n > math.MaxUint8 {
+				return nil, fmt.Errorf("octal escape out of range in bytes default value: %q", s)
+			}
+			out = append(out, byte(n))
+			i--
+		case 'x', 'X':
+			j := i + 1
+			for j < len(s) && j < i+3 && isHexDigit(s[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("invalid hex escape in bytes default value: %q", s)
+			}
+			n, _ := strconv.ParseUint(s[i+1:j], 16, 8)
+			out = append(out, byte(n))
+			i = j - 1
+		default:
+			return nil, fmt.Errorf("invalid escape sequence \\%c in bytes default value", c)
+		}
+	}
+
+	return out, nil
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
 var _ Field = (*field)(nil)
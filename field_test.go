@@ -2,6 +2,7 @@ package pgs
 
 import (
 	"errors"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -210,6 +211,95 @@ func TestField_Required(t *testing.T) {
 	assert.False(t, f.Required(), "proto2 + optional")
 }
 
+func TestField_DefaultValue(t *testing.T) {
+	t.Parallel()
+
+	en := dummyEnum()
+	en.addValue(&enumVal{desc: &descriptor.EnumValueDescriptorProto{Name: proto.String("FIRST")}})
+	en.addValue(&enumVal{desc: &descriptor.EnumValueDescriptorProto{Name: proto.String("SECOND")}})
+
+	newField := func(pt ProtoType, def *string) *field {
+		f := &field{desc: &descriptor.FieldDescriptorProto{
+			Type:         pt.ProtoPtr(),
+			Label:        Optional.ProtoPtr(),
+			DefaultValue: def,
+		}}
+		dummyMsg().addField(f)
+		if pt == EnumT {
+			f.addType(&enumT{scalarT: &scalarT{}, enum: en})
+		} else {
+			f.addType(&scalarT{})
+		}
+		return f
+	}
+
+	tests := []struct {
+		name string
+		typ  ProtoType
+		def  *string
+		exp  interface{}
+		err  bool
+	}{
+		{"int32 unset", Int32T, nil, int64(0), false},
+		{"int32", Int32T, proto.String("-123"), int64(-123), false},
+		{"sint64", SInt64, proto.String("-9223372036854775808"), int64(math.MinInt64), false},
+		{"int bad", Int64T, proto.String("foo"), nil, true},
+		{"uint64 unset", UInt64T, nil, uint64(0), false},
+		{"fixed64", Fixed64T, proto.String("18446744073709551615"), uint64(math.MaxUint64), false},
+		{"double unset", DoubleT, nil, float64(0), false},
+		{"double", DoubleT, proto.String("1.5e+10"), 1.5e10, false},
+		{"float inf", FloatT, proto.String("inf"), math.Inf(1), false},
+		{"double -inf", DoubleT, proto.String("-inf"), math.Inf(-1), false},
+		{"bool unset", BoolT, nil, false, false},
+		{"bool", BoolT, proto.String("true"), true, false},
+		{"string unset", StringT, nil, "", false},
+		{"string", StringT, proto.String(`foo"bar`), `foo"bar`, false},
+		{"bytes unset", BytesT, nil, []byte{}, false},
+		{"bytes", BytesT, proto.String(`a\000\377\n\"\x41`), []byte("a\x00\xff\n\"A"), false},
+		{"bytes bad escape", BytesT, proto.String(`\q`), nil, true},
+		{"bytes trailing escape", BytesT, proto.String(`abc\`), nil, true},
+		{"enum unset", EnumT, nil, en.vals[0], false},
+		{"enum", EnumT, proto.String("SECOND"), en.vals[1], false},
+		{"enum unknown", EnumT, proto.String("THIRD"), nil, true},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := newField(tc.typ, tc.def)
+			assert.Equal(t, tc.def != nil, f.HasDefaultValue())
+
+			v, err := f.DefaultValue()
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.exp, v)
+		})
+	}
+
+	t.Run("nan", func(t *testing.T) {
+		t.Parallel()
+
+		v, err := newField(DoubleT, proto.String("nan")).DefaultValue()
+		assert.NoError(t, err)
+		assert.True(t, math.IsNaN(v.(float64)))
+	})
+
+	t.Run("no default", func(t *testing.T) {
+		t.Parallel()
+
+		f := newField(Int32T, nil)
+		f.addType(&repT{scalarT: &scalarT{}})
+		v, err := f.DefaultValue()
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+	})
+}
+
 func TestField_ChildAtPath(t *testing.T) {
 	t.Parallel()
 
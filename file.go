@@ -1,6 +1,8 @@
 package pgs
 
 import (
+	"sync"
+
 	"google.golang.org/protobuf/runtime/protoimpl"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -57,12 +59,14 @@ type file struct {
 	enums                   []Enum
 	defExts                 []Extension
 	dependents              []File
+	dependentsOnce          sync.Once
 	dependentsCache         []File
 	fileDependencies        []File
 	msgs                    []Message
 	srvs                    []Service
 	buildTarget             bool
 	syntaxInfo, packageInfo SourceCodeInfo
+	optInfo                 optionLocations
 }
 
 func (f *file) Name() Name                                  { return Name(f.desc.GetName()) }
@@ -71,6 +75,7 @@ func (f *file) Syntax() Syntax                              { return Syntax(f.de
 func (f *file) Package() Package                            { return f.pkg }
 func (f *file) File() File                                  { return f }
 func (f *file) BuildTarget() bool                           { return f.buildTarget }
+func (f *file) Deprecated() bool                            { return f.desc.GetOptions().GetDeprecated() }
 func (f *file) Descriptor() *descriptor.FileDescriptorProto { return f.desc }
 func (f *file) InputPath() FilePath                         { return FilePath(f.Name().String()) }
 func (f *file) MapEntries() (me []Message)                  { return nil }
@@ -164,7 +169,7 @@ func (f *file) UnusedImports() []File {
 }
 
 func (f *file) Dependents() []File {
-	if f.dependentsCache == nil {
+	f.dependentsOnce.Do(func() {
 		set := make(map[string]File)
 		for _, fl := range f.dependents {
 			set[fl.Name().String()] = fl
@@ -177,7 +182,7 @@ func (f *file) Dependents() []File {
 		for _, d := range set {
 			f.dependentsCache = append(f.dependentsCache, d)
 		}
-	}
+	})
 	return f.dependentsCache
 }
 
@@ -272,6 +277,8 @@ func (f *file) childAtPath(path []int32) Entity {
 		child = f.enums[path[1]]
 	case servicePath:
 		child = f.srvs[path[1]]
+	case extensionPath:
+		child = f.defExts[path[1]]
 	default:
 		return nil
 	}
@@ -283,6 +290,14 @@ func (f *file) addSourceCodeInfo(info SourceCodeInfo) {
 	f.syntaxInfo = info
 }
 
+func (f *file) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return f.optInfo.lookup(path)
+}
+
+func (f *file) addOptionSourceCodeInfo(path []int32, info SourceCodeInfo) {
+	f.optInfo = append(f.optInfo, optionLocation{path: path, info: info})
+}
+
 func (f *file) addPackageSourceCodeInfo(info SourceCodeInfo) {
 	f.packageInfo = info
 }
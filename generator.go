@@ -4,6 +4,8 @@ import (
 	"io"
 	"log"
 	"os"
+
+	"github.com/spf13/afero"
 )
 
 // Generator configures and executes a protoc plugin's lifecycle.
@@ -13,7 +15,8 @@ type Generator struct {
 	persister persister // handles writing artifacts to their output
 	workflow  workflow
 
-	mods []Module // registered pg* modules
+	mods       []Module    // registered pg* modules
+	transforms []Transform // registered descriptor transforms
 
 	in  io.Reader // protoc input reader
 	out io.Writer // protoc output writer
@@ -22,6 +25,10 @@ type Generator struct {
 
 	params        Parameters     // CLI parameters passed in from protoc
 	paramMutators []ParamMutator // registered param mutators
+
+	provenance *provenance // provenance header configuration, if enabled
+
+	fs afero.Fs // file system read by Modules implementing fsModule
 }
 
 // Init configures a new Generator. InitOptions may be provided as well to
@@ -31,6 +38,7 @@ func Init(opts ...InitOption) *Generator {
 		in:        os.Stdin,
 		out:       os.Stdout,
 		persister: newPersister(),
+		fs:        afero.NewOsFs(),
 		workflow:  &onceWorkflow{workflow: &standardWorkflow{}},
 	}
 
@@ -56,6 +64,20 @@ func (g *Generator) RegisterModule(m ...Module) *Generator {
 	return g
 }
 
+// RegisterTransform should be called before Render to attach Transforms to
+// the Generator. This method can be called multiple times. Transforms are
+// applied to the CodeGeneratorRequest in the order in which they are
+// registered, before the AST is hydrated.
+func (g *Generator) RegisterTransform(t ...Transform) *Generator {
+	for _, tr := range t {
+		g.Assert(tr != nil, "nil transform provided")
+		g.Debug("registering transform: ", tr.Name())
+	}
+
+	g.transforms = append(g.transforms, t...)
+	return g
+}
+
 // RegisterPostProcessor should be called before Render to attach
 // PostProcessors to the Generator. This method can be called multiple times.
 // PostProcessors are executed against their matches in the order in which they
@@ -46,6 +46,22 @@ func TestGenerator_RegisterModule(t *testing.T) {
 	assert.True(t, d.Failed())
 }
 
+func TestGenerator_RegisterTransform(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	g := &Generator{Debugger: d}
+
+	assert.Empty(t, g.transforms)
+	g.RegisterTransform(TransformFunc("foo", nil))
+
+	assert.False(t, d.Failed())
+	assert.Len(t, g.transforms, 1)
+
+	assert.Panics(t, func() { g.RegisterTransform(nil) })
+	assert.True(t, d.Failed())
+}
+
 func TestGenerator_RegisterPostProcessor(t *testing.T) {
 	t.Parallel()
 
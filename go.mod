@@ -1,19 +1,20 @@
 module github.com/lyft/protoc-gen-star/v2
 
-go 1.17
+go 1.18
 
 require (
+	github.com/sergi/go-diff v1.3.1
 	github.com/spf13/afero v1.3.3
 	github.com/stretchr/testify v1.6.1
 	golang.org/x/tools v0.1.12
-	google.golang.org/protobuf v1.23.0
+	google.golang.org/protobuf v1.28.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/mod v0.6.0-dev.0.20220419223038-86c51ed26bb4 // indirect
 	golang.org/x/sys v0.0.0-20220722155257-8c9f86f7a55f // indirect
 	golang.org/x/text v0.3.7 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
@@ -39,8 +39,15 @@ func MutateParams(pm ...ParamMutator) InitOption {
 
 // FileSystem overrides the default file system used to write Artifacts to
 // disk. By default, the OS's file system is used. This option currently only
-// impacts CustomFile and CustomTemplateFile artifacts generated by modules.
-func FileSystem(fs afero.Fs) InitOption { return func(g *Generator) { g.persister.SetFS(fs) } }
+// impacts custom file artifacts generated by modules, the archive written
+// when the "archive" parameter is set, and the files read by built-in
+// Modules, such as the rules file of PackageLayering.
+func FileSystem(fs afero.Fs) InitOption {
+	return func(g *Generator) {
+		g.fs = fs
+		g.persister.SetFS(fs)
+	}
+}
 
 // BiDirectional instructs the Generator to build the AST graph in both
 // directions (ie, accessing dependents of an entity, not just dependencies).
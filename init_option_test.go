@@ -48,6 +48,7 @@ func TestFileSystem(t *testing.T) {
 	FileSystem(fs)(g)
 
 	assert.Equal(t, fs, p.fs)
+	assert.Equal(t, fs, g.fs)
 }
 
 func TestProtocInput(t *testing.T) {
@@ -0,0 +1,211 @@
+package pgs
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+var insertionPointPattern = regexp.MustCompile(`@@protoc_insertion_point\(([^)]*)\)`)
+
+// InsertionPointMarker returns the marker protoc searches for to locate the
+// named insertion point. The marker must be placed on its own line, typically
+// within a comment; injected content is inserted immediately above that line.
+func InsertionPointMarker(name string) string {
+	return "@@protoc_insertion_point(" + name + ")"
+}
+
+// InsertionPointComment returns a line declaring the named insertion point,
+// with the marker wrapped in a comment using the syntax of the file's
+// extension (eg, "// @@protoc_insertion_point(imports)\n" for Go). If the
+// file's comment syntax is unknown, the bare marker is returned.
+func InsertionPointComment(fileName, name string) string {
+	marker := InsertionPointMarker(name)
+
+	style, ok := commentStyleFor(fileName)
+	switch {
+	case !ok:
+		return marker + "\n"
+	case style.Open != "":
+		return style.Open + " " + marker + " " + strings.TrimSpace(style.Close) + "\n"
+	default:
+		return style.Prefix + " " + marker + "\n"
+	}
+}
+
+// InsertionPoints returns the names of the insertion points declared in
+// content, in the order they first appear.
+func InsertionPoints(content string) []string {
+	var (
+		names []string
+		seen  = map[string]struct{}{}
+	)
+
+	for _, m := range insertionPointPattern.FindAllStringSubmatch(content, -1) {
+		if _, ok := seen[m[1]]; !ok {
+			seen[m[1]] = struct{}{}
+			names = append(names, m[1])
+		}
+	}
+
+	return names
+}
+
+// validateInsertionPoints checks that each injection in resp targets an
+// insertion point declared by a preceding file in resp, including points
+// declared by appended or previously injected content. Injections into files
+// not in resp, such as those from another plugin, cannot be checked and are
+// ignored.
+func validateInsertionPoints(resp *plugin_go.CodeGeneratorResponse) error {
+	var (
+		points = map[string]map[string]struct{}{}
+		last   string
+	)
+
+	declare := func(name, content string) {
+		if points[name] == nil {
+			points[name] = map[string]struct{}{}
+		}
+		for _, p := range InsertionPoints(content) {
+			points[name][p] = struct{}{}
+		}
+	}
+
+	for _, f := range resp.GetFile() {
+		name := f.GetName()
+
+		switch {
+		case name == "":
+			if last != "" {
+				declare(last, f.GetContent())
+			}
+		case f.InsertionPoint == nil:
+			last = name
+			points[name] = nil
+			declare(name, f.GetContent())
+		default:
+			last = ""
+
+			known, ok := points[name]
+			if !ok {
+				continue
+			}
+
+			point := f.GetInsertionPoint()
+			if _, ok = known[point]; !ok {
+				return missingInsertionPoint(name, point, known)
+			}
+
+			last = name
+			declare(name, f.GetContent())
+		}
+	}
+
+	return nil
+}
+
+func missingInsertionPoint(name, point string, known map[string]struct{}) error {
+	if len(known) == 0 {
+		return fmt.Errorf("insertion point %q not found in %s, which declares no insertion points", point, name)
+	}
+
+	names := make([]string, 0, len(known))
+	for n := range known {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return fmt.Errorf("insertion point %q not found in %s; known insertion points: %s",
+		point, name, strings.Join(names, ", "))
+}
+
+// applyInsertion inserts text immediately before the line containing the named
+// insertion point marker, indenting each line of text to match the marker.
+func applyInsertion(content []byte, point, text string) ([]byte, error) {
+	marker := []byte(InsertionPointMarker(point))
+
+	i := bytes.Index(content, marker)
+	if i < 0 {
+		return nil, fmt.Errorf("insertion point %q not found", point)
+	}
+
+	start := bytes.LastIndexByte(content[:i], '\n') + 1
+	indent := content[start:start]
+	for j := start; j < i && (content[j] == ' ' || content[j] == '\t'); j++ {
+		indent = content[start : j+1]
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write(content[:start])
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		if line != "\n" {
+			buf.Write(indent)
+		}
+		buf.WriteString(line)
+	}
+	buf.Write(content[start:])
+
+	return buf.Bytes(), nil
+}
+
+// assembledFile is a file in a CodeGeneratorResponse with the appends and
+// insertions that follow it in the same response applied.
+type assembledFile struct {
+	file     *plugin_go.CodeGeneratorResponse_File
+	content  []byte
+	modified bool
+}
+
+// assembleResponse resolves the appends and insertion points in resp as protoc
+// would against the files in the same response. Appends without a preceding
+// file and insertions into files not in resp, such as those from another
+// plugin, are returned as unresolved.
+func assembleResponse(resp *plugin_go.CodeGeneratorResponse) (files []*assembledFile, unresolved []*plugin_go.CodeGeneratorResponse_File, err error) {
+	var (
+		index = map[string]*assembledFile{}
+		last  *assembledFile
+	)
+
+	for _, f := range resp.GetFile() {
+		name := f.GetName()
+
+		if name == "" {
+			if last == nil {
+				unresolved = append(unresolved, f)
+				continue
+			}
+			last.content = append(last.content, f.GetContent()...)
+			last.modified = true
+			continue
+		}
+
+		if f.InsertionPoint != nil {
+			target, ok := index[name]
+			if !ok {
+				unresolved = append(unresolved, f)
+				last = nil
+				continue
+			}
+
+			content, err := applyInsertion(target.content, f.GetInsertionPoint(), f.GetContent())
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", name, err)
+			}
+			target.content, target.modified, last = content, true, target
+			continue
+		}
+
+		last = &assembledFile{file: f, content: []byte(f.GetContent())}
+		index[name] = last
+		files = append(files, last)
+	}
+
+	return files, unresolved, nil
+}
@@ -0,0 +1,125 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestInsertionPointComment(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		file, expected string
+	}{
+		{"foo.go", "// @@protoc_insertion_point(imports)\n"},
+		{"foo.py", "# @@protoc_insertion_point(imports)\n"},
+		{"foo.css", "/* @@protoc_insertion_point(imports) */\n"},
+		{"foo.html", "<!-- @@protoc_insertion_point(imports) -->\n"},
+		{"foo.json", "@@protoc_insertion_point(imports)\n"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, InsertionPointComment(tc.file, "imports"), tc.file)
+	}
+}
+
+func TestInsertionPoints(t *testing.T) {
+	t.Parallel()
+
+	content := "// @@protoc_insertion_point(imports)\n" +
+		"package foo\n" +
+		"// @@protoc_insertion_point(class_scope:foo.Bar)\n" +
+		"// @@protoc_insertion_point(imports)\n"
+
+	assert.Equal(t, []string{"imports", "class_scope:foo.Bar"}, InsertionPoints(content))
+	assert.Empty(t, InsertionPoints("package foo\n"))
+}
+
+func TestValidateInsertionPoints(t *testing.T) {
+	t.Parallel()
+
+	file := func(name, point, content string) *plugin_go.CodeGeneratorResponse_File {
+		f := &plugin_go.CodeGeneratorResponse_File{Content: proto.String(content)}
+		if name != "" {
+			f.Name = proto.String(name)
+		}
+		if point != "" {
+			f.InsertionPoint = proto.String(point)
+		}
+		return f
+	}
+
+	tests := []struct {
+		name  string
+		files []*plugin_go.CodeGeneratorResponse_File
+		err   string
+	}{
+		{
+			name: "valid",
+			files: []*plugin_go.CodeGeneratorResponse_File{
+				file("foo.go", "", "// @@protoc_insertion_point(a)\n"),
+				file("", "", "// @@protoc_insertion_point(appended)\n"),
+				file("foo.go", "a", "// @@protoc_insertion_point(nested)\n"),
+				file("foo.go", "appended", ""),
+				file("foo.go", "nested", ""),
+			},
+		},
+		{
+			name:  "other plugin",
+			files: []*plugin_go.CodeGeneratorResponse_File{file("foo.go", "a", "")},
+		},
+		{
+			name: "missing",
+			files: []*plugin_go.CodeGeneratorResponse_File{
+				file("foo.go", "", "// @@protoc_insertion_point(b)\n// @@protoc_insertion_point(a)\n"),
+				file("foo.go", "c", ""),
+			},
+			err: `insertion point "c" not found in foo.go; known insertion points: a, b`,
+		},
+		{
+			name: "none declared",
+			files: []*plugin_go.CodeGeneratorResponse_File{
+				file("foo.go", "", "package foo\n"),
+				file("foo.go", "c", ""),
+			},
+			err: `insertion point "c" not found in foo.go, which declares no insertion points`,
+		},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateInsertionPoints(&plugin_go.CodeGeneratorResponse{File: tc.files})
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestPersister_Persist_InvalidInjection(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+
+	p.Persist(
+		GeneratorFile{Name: "foo.go", Contents: "package foo\n" + InsertionPointComment("foo.go", "imports")},
+		GeneratorInjection{FileName: "foo.go", InsertionPoint: "imports", Contents: "import \"fmt\"\n"},
+	)
+	require.NoError(t, d.Err())
+
+	p.Persist(
+		GeneratorFile{Name: "foo.go", Contents: "package foo\n" + InsertionPointComment("foo.go", "imports")},
+		GeneratorInjection{FileName: "foo.go", InsertionPoint: "import", Contents: "import \"fmt\"\n"},
+	)
+	assert.EqualError(t, d.Err(), `insertion point "import" not found in foo.go; known insertion points: imports`)
+}
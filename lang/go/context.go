@@ -49,6 +49,23 @@ type Context interface {
 	// packages will be prefixed with the package name.
 	Type(field pgs.Field) TypeName
 
+	// DefaultValue returns the default value of a Field rendered as a Go source
+	// literal, matching the Default_* constants and variables emitted by
+	// protoc-gen-go. Enum values from imported packages are prefixed with the
+	// package name, and floating point infinities and NaN are rendered using
+	// the "math" package. Repeated, map and embedded message fields render as
+	// "nil". An error is returned if the default value cannot be parsed.
+	DefaultValue(field pgs.Field) (string, error)
+
+	// Deprecation returns the deprecation comment protoc-gen-go emits for a
+	// deprecated Entity, without a trailing newline. An empty string is
+	// returned if the Entity is not deprecated.
+	//
+	//     - File: "// Deprecated: The entire proto file {name} is marked as deprecated."
+	//     - Others: "// Deprecated: Do not use."
+	//
+	Deprecation(entity pgs.Entity) string
+
 	// PackageName returns the name of the Node's package as it would appear in
 	// Go source generated by the official protoc-gen-go plugin.
 	PackageName(node pgs.Node) pgs.Name
@@ -0,0 +1,62 @@
+package pgsgo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	pgs "github.com/lyft/protoc-gen-star/v2"
+)
+
+func (c context) DefaultValue(f pgs.Field) (string, error) {
+	v, err := f.DefaultValue()
+	if err != nil {
+		return "", err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return "nil", nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return floatLiteral(f.Type().ProtoType(), v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case string:
+		return strconv.Quote(v), nil
+	case []byte:
+		return fmt.Sprintf("[]byte(%s)", strconv.Quote(string(v))), nil
+	case pgs.EnumValue:
+		return c.importableTypeName(f, v).String(), nil
+	default:
+		return "", fmt.Errorf("unexpected default value type %T for field %s", v, f.FullyQualifiedName())
+	}
+}
+
+func floatLiteral(t pgs.ProtoType, v float64) string {
+	var s string
+
+	switch {
+	case math.IsInf(v, 1):
+		s = "math.Inf(1)"
+	case math.IsInf(v, -1):
+		s = "math.Inf(-1)"
+	case math.IsNaN(v):
+		s = "math.NaN()"
+	default:
+		bits := 64
+		if t == pgs.FloatT {
+			bits = 32
+		}
+		return strconv.FormatFloat(v, 'g', -1, bits)
+	}
+
+	if t == pgs.FloatT {
+		return fmt.Sprintf("float32(%s)", s)
+	}
+
+	return s
+}
@@ -0,0 +1,114 @@
+package pgsgo
+
+import (
+	"testing"
+
+	pgs "github.com/lyft/protoc-gen-star/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestDefaultValue(t *testing.T) {
+	t.Parallel()
+
+	fld := func(name string, typ pgs.ProtoType, def string) *descriptor.FieldDescriptorProto {
+		fd := &descriptor.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(1),
+			Label:  pgs.Optional.ProtoPtr(),
+			Type:   typ.ProtoPtr(),
+		}
+		if def != "" {
+			fd.DefaultValue = proto.String(def)
+		}
+		return fd
+	}
+
+	enumFld := fld("enum", pgs.EnumT, "BAR")
+	enumFld.TypeName = proto.String(".defaults.Enum")
+
+	impFld := fld("imported", pgs.EnumT, "")
+	impFld.TypeName = proto.String(".other.Other")
+
+	repFld := fld("repeated", pgs.Int32T, "")
+	repFld.Label = pgs.Repeated.ProtoPtr()
+
+	fdset := &descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{
+		{
+			Name:    proto.String("other.proto"),
+			Package: proto.String("other"),
+			Options: &descriptor.FileOptions{GoPackage: proto.String("example.com/other")},
+			EnumType: []*descriptor.EnumDescriptorProto{{
+				Name:  proto.String("Other"),
+				Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String("OTHER_ZERO"), Number: proto.Int32(0)}},
+			}},
+		},
+		{
+			Name:       proto.String("defaults.proto"),
+			Package:    proto.String("defaults"),
+			Dependency: []string{"other.proto"},
+			Options:    &descriptor.FileOptions{GoPackage: proto.String("example.com/defaults")},
+			EnumType: []*descriptor.EnumDescriptorProto{{
+				Name: proto.String("Enum"),
+				Value: []*descriptor.EnumValueDescriptorProto{
+					{Name: proto.String("FOO"), Number: proto.Int32(0)},
+					{Name: proto.String("BAR"), Number: proto.Int32(1)},
+				},
+			}},
+			MessageType: []*descriptor.DescriptorProto{{
+				Name: proto.String("Msg"),
+				Field: []*descriptor.FieldDescriptorProto{
+					fld("int", pgs.Int32T, "-42"),
+					fld("uint", pgs.UInt64T, ""),
+					fld("double", pgs.DoubleT, "1.5"),
+					fld("float_inf", pgs.FloatT, "inf"),
+					fld("double_nan", pgs.DoubleT, "nan"),
+					fld("bool", pgs.BoolT, "true"),
+					fld("string", pgs.StringT, `say "hi"`),
+					fld("bytes", pgs.BytesT, `\000\377`),
+					enumFld,
+					impFld,
+					repFld,
+				},
+			}},
+		},
+	}}
+
+	d := pgs.InitMockDebugger()
+	ast := pgs.ProcessFileDescriptorSet(d, fdset)
+	require.False(t, d.Failed())
+
+	ctx := InitContext(pgs.Parameters{})
+
+	tests := []struct {
+		field, expected string
+	}{
+		{"int", "-42"},
+		{"uint", "0"},
+		{"double", "1.5"},
+		{"float_inf", "float32(math.Inf(1))"},
+		{"double_nan", "math.NaN()"},
+		{"bool", "true"},
+		{"string", `"say \"hi\""`},
+		{"bytes", `[]byte("\x00\xff")`},
+		{"enum", "Enum_BAR"},
+		{"imported", "other.Other_OTHER_ZERO"},
+		{"repeated", "nil"},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.field, func(t *testing.T) {
+			t.Parallel()
+
+			e, ok := ast.Lookup(".defaults.Msg." + tc.field)
+			require.True(t, ok)
+
+			lit, err := ctx.DefaultValue(e.(pgs.Field))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, lit)
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package pgsgo
+
+import (
+	"fmt"
+
+	pgs "github.com/lyft/protoc-gen-star/v2"
+)
+
+func (c context) Deprecation(e pgs.Entity) string {
+	if !e.Deprecated() {
+		return ""
+	}
+
+	if f, ok := e.(pgs.File); ok {
+		return fmt.Sprintf("// Deprecated: The entire proto file %s is marked as deprecated.", f.Name())
+	}
+
+	return "// Deprecated: Do not use."
+}
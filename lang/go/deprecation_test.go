@@ -0,0 +1,53 @@
+package pgsgo
+
+import (
+	"testing"
+
+	pgs "github.com/lyft/protoc-gen-star/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestDeprecation(t *testing.T) {
+	t.Parallel()
+
+	fdset := &descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{{
+		Name:    proto.String("dep.proto"),
+		Package: proto.String("dep"),
+		Options: &descriptor.FileOptions{
+			GoPackage:  proto.String("example.com/dep"),
+			Deprecated: proto.Bool(true),
+		},
+		MessageType: []*descriptor.DescriptorProto{
+			{Name: proto.String("Old"), Options: &descriptor.MessageOptions{Deprecated: proto.Bool(true)}},
+			{Name: proto.String("New")},
+		},
+	}}}
+
+	d := pgs.InitMockDebugger()
+	ast := pgs.ProcessFileDescriptorSet(d, fdset)
+	require.False(t, d.Failed())
+
+	ctx := InitContext(pgs.Parameters{})
+
+	tests := []struct {
+		name, expected string
+	}{
+		{"dep.proto", "// Deprecated: The entire proto file dep.proto is marked as deprecated."},
+		{".dep.Old", "// Deprecated: Do not use."},
+		{".dep.New", ""},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e, ok := ast.Lookup(tc.name)
+			require.True(t, ok)
+			assert.Equal(t, tc.expected, ctx.Deprecation(e))
+		})
+	}
+}
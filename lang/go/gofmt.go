@@ -12,6 +12,8 @@ type goFmt struct{}
 // GoFmt returns a PostProcessor that runs gofmt on any files ending in ".go"
 func GoFmt() pgs.PostProcessor { return goFmt{} }
 
+func (p goFmt) Name() string { return "gofmt" }
+
 func (p goFmt) Match(a pgs.Artifact) bool {
 	var n string
 
@@ -42,6 +42,11 @@ func TestGoFmt_Match(t *testing.T) {
 	}
 }
 
+func TestGoFmt_Name(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "gofmt", pgs.PostProcessorName(GoFmt()))
+}
+
 func TestGoFmt_Process(t *testing.T) {
 	t.Parallel()
 
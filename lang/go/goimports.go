@@ -13,6 +13,8 @@ type goImports struct{}
 // GoImports returns a PostProcessor that run goimports on any files ending . ".go"
 func GoImports() pgs.PostProcessor { return goImports{} }
 
+func (g goImports) Name() string { return "goimports" }
+
 func (g goImports) Match(a pgs.Artifact) bool {
 	var n string
 
@@ -42,6 +42,11 @@ func TestGoImports_Match(t *testing.T) {
 	}
 }
 
+func TestGoImports_Name(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "goimports", pgs.PostProcessorName(GoImports()))
+}
+
 func TestGoImports_Process(t *testing.T) {
 	t.Parallel()
 
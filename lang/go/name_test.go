@@ -6,6 +6,8 @@ import (
 	pgs "github.com/lyft/protoc-gen-star/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
 
 func TestPGGUpperCamelCase(t *testing.T) {
@@ -234,3 +236,38 @@ func TestContext_ServerStream(t *testing.T) {
 		})
 	}
 }
+
+func TestName_Group(t *testing.T) {
+	t.Parallel()
+
+	fd := &descriptor.FileDescriptorProto{
+		Name:    proto.String("groups.proto"),
+		Package: proto.String("groups"),
+		Options: &descriptor.FileOptions{GoPackage: proto.String("example.com/groups")},
+		MessageType: []*descriptor.DescriptorProto{{
+			Name:       proto.String("Msg"),
+			NestedType: []*descriptor.DescriptorProto{{Name: proto.String("MyGroup")}},
+			Field: []*descriptor.FieldDescriptorProto{{
+				Name:     proto.String("mygroup"),
+				Number:   proto.Int32(1),
+				Label:    pgs.Optional.ProtoPtr(),
+				Type:     pgs.GroupT.ProtoPtr(),
+				TypeName: proto.String(".groups.Msg.MyGroup"),
+			}},
+		}},
+	}
+
+	d := pgs.InitMockDebugger()
+	ast := pgs.ProcessFileDescriptorSet(d, &descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{fd}})
+	require.False(t, d.Failed())
+
+	ctx := InitContext(pgs.Parameters{})
+
+	ent, ok := ast.Lookup(".groups.Msg.mygroup")
+	require.True(t, ok)
+	fld := ent.(pgs.Field)
+
+	assert.Equal(t, pgs.Name("Mygroup"), ctx.Name(fld))
+	assert.Equal(t, pgs.Name("Msg_MyGroup"), ctx.Name(fld.Type().Embed()))
+	assert.Equal(t, TypeName("*Msg_MyGroup"), ctx.Type(fld))
+}
@@ -0,0 +1,278 @@
+package pgs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	packageDenyKey   = "package_deny"
+	packageRulesKey  = "package_rules"
+	packageCyclesKey = "package_cycles"
+
+	packageRuleSep = "->"
+)
+
+// A PackageRule forbids proto packages matching From from importing packages
+// matching To. Both are patterns as defined by path.Match, applied to the
+// package name (eg, "*.public" or "acme.internal.*"). Since package names
+// contain no slashes, a "*" matches across dots.
+type PackageRule struct {
+	From, To string
+}
+
+// ParsePackageRule parses a PackageRule of the form "from -> to". An error is
+// returned if the rule is malformed or either pattern is invalid.
+func ParsePackageRule(s string) (PackageRule, error) {
+	parts := strings.Split(s, packageRuleSep)
+	if len(parts) != 2 {
+		return PackageRule{}, fmt.Errorf("invalid package rule %q: expected the form `from %s to`", s, packageRuleSep)
+	}
+
+	r := PackageRule{
+		From: strings.TrimSpace(parts[0]),
+		To:   strings.TrimSpace(parts[1]),
+	}
+
+	for _, pattern := range []string{r.From, r.To} {
+		if pattern == "" {
+			return PackageRule{}, fmt.Errorf("invalid package rule %q: empty pattern", s)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return PackageRule{}, fmt.Errorf("invalid package rule %q: %v", s, err)
+		}
+	}
+
+	return r, nil
+}
+
+// Violated returns true if a Package named from importing a Package named to
+// breaks this rule.
+func (r PackageRule) Violated(from, to Name) bool {
+	f, _ := path.Match(r.From, from.String())
+	t, _ := path.Match(r.To, to.String())
+	return f && t
+}
+
+// String satisfies the fmt.Stringer interface, returning the rule in the form
+// accepted by ParsePackageRule.
+func (r PackageRule) String() string {
+	return fmt.Sprintf("%s %s %s", r.From, packageRuleSep, r.To)
+}
+
+type layeringModule struct {
+	*ModuleBase
+
+	fs    afero.Fs
+	ast   AST
+	rules []PackageRule
+}
+
+// PackageLayering returns a Module that fails generation if a target Package
+// depends on another Package, directly or transitively, in violation of any
+// of the provided rules. Each violation is reported as a GeneratorError: a
+// direct import names the offending files, while a transitive dependency
+// names the chain of Packages leading to the forbidden one. Additional rules
+// may be provided via the following parameters:
+//
+//	package_deny=*.public->*.internal;acme.api->acme.storage
+//	package_rules=path/to/rules.txt
+//
+// The rules file contains one rule per line in the same "from -> to" form.
+// Blank lines and lines starting with "#" are ignored. It is read from the
+// Generator's FileSystem. If the package_cycles parameter is set to "deny",
+// import cycles between Packages containing build targets are reported as
+// well.
+func PackageLayering(rules ...PackageRule) Module {
+	return &layeringModule{
+		ModuleBase: &ModuleBase{},
+		fs:         afero.NewOsFs(),
+		rules:      rules,
+	}
+}
+
+func (m *layeringModule) Name() string { return "layering" }
+
+func (m *layeringModule) setFS(fs afero.Fs) { m.fs = fs }
+
+func (m *layeringModule) setAST(ast AST) { m.ast = ast }
+
+func (m *layeringModule) Execute(targets map[string]File, pkgs map[string]Package) []Artifact {
+	rules := append(append([]PackageRule{}, m.rules...), m.paramRules()...)
+
+	g := m.packageGraph(pkgs)
+	targetPkgs := m.targetPackages(targets)
+
+	for _, p := range g.Packages() {
+		files, ok := targetPkgs[pkgName(p)]
+		if !ok {
+			continue
+		}
+
+		for _, dep := range g.TransitiveDependencies(p) {
+			if pkgName(dep) == pkgName(p) {
+				continue
+			}
+
+			for _, r := range rules {
+				if r.Violated(p.ProtoName(), dep.ProtoName()) {
+					m.reportViolation(g, files, p, dep, r)
+				}
+			}
+		}
+	}
+
+	if m.Parameters().Str(packageCyclesKey) == "deny" {
+		m.checkCycles(g, targetPkgs)
+	}
+
+	return m.Artifacts()
+}
+
+// packageGraph reuses the AST's cached PackageGraph if the Module was run by
+// a Generator, building one from pkgs otherwise.
+func (m *layeringModule) packageGraph(pkgs map[string]Package) PackageGraph {
+	if m.ast != nil {
+		return PackageGraphOf(m.ast)
+	}
+	return NewPackageGraph(pkgs)
+}
+
+// targetPackages groups the target Files by the name of their Package, with
+// each group sorted by File name.
+func (m *layeringModule) targetPackages(targets map[string]File) map[string][]File {
+	out := make(map[string][]File, len(targets))
+	for _, f := range targets {
+		n := pkgName(f.Package())
+		out[n] = append(out[n], f)
+	}
+
+	for _, fs := range out {
+		sort.Slice(fs, func(i, j int) bool { return fs[i].Name() < fs[j].Name() })
+	}
+
+	return out
+}
+
+// reportViolation adds an error for Package p depending on dep in violation
+// of rule r. Direct imports from the target files are reported per File;
+// otherwise, the shortest chain of Packages from p to dep is reported.
+func (m *layeringModule) reportViolation(g PackageGraph, files []File, p, dep Package, r PackageRule) {
+	from, to := p.ProtoName(), dep.ProtoName()
+
+	direct := false
+	for _, f := range files {
+		for _, imp := range f.Imports() {
+			if pkgName(imp.Package()) == pkgName(dep) {
+				direct = true
+				m.AddError(fmt.Sprintf("%s (package %s) imports %s (package %s), violating rule `%s`",
+					f.Name(), from, imp.Name(), to, r))
+			}
+		}
+	}
+
+	if direct {
+		return
+	}
+
+	chain := importChain(g, p, dep)
+	names := make([]string, len(chain))
+	for i, c := range chain {
+		names[i] = pkgName(c)
+	}
+
+	m.AddError(fmt.Sprintf("package %s depends on package %s through %s, violating rule `%s`",
+		from, to, strings.Join(names, " "+packageRuleSep+" "), r))
+}
+
+// importChain returns the shortest chain of imports from Package a to
+// Package b, inclusive of both, or nil if a does not depend on b.
+func importChain(g PackageGraph, a, b Package) []Package {
+	prev := map[string]Package{pkgName(a): nil}
+	queue := []Package{a}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		for _, d := range g.Dependencies(p) {
+			if _, seen := prev[pkgName(d)]; seen {
+				continue
+			}
+			prev[pkgName(d)] = p
+
+			if pkgName(d) != pkgName(b) {
+				queue = append(queue, d)
+				continue
+			}
+
+			path := []Package{d}
+			for n := p; n != nil; n = prev[pkgName(n)] {
+				path = append([]Package{n}, path...)
+			}
+			return path
+		}
+	}
+
+	return nil
+}
+
+func (m *layeringModule) checkCycles(g PackageGraph, targetPkgs map[string][]File) {
+	for _, cycle := range g.Cycles() {
+		names := make([]string, len(cycle))
+		inTargets := false
+		for i, p := range cycle {
+			names[i] = p.ProtoName().String()
+			if _, ok := targetPkgs[pkgName(p)]; ok {
+				inTargets = true
+			}
+		}
+
+		if inTargets {
+			m.AddError(fmt.Sprintf("import cycle between packages: %s", strings.Join(names, ", ")))
+		}
+	}
+}
+
+func (m *layeringModule) paramRules() (rules []PackageRule) {
+	params := m.Parameters()
+
+	for _, s := range strings.Split(params.Str(packageDenyKey), ";") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		r, err := ParsePackageRule(s)
+		m.CheckErr(err, "unable to parse ", packageDenyKey, " parameter")
+		rules = append(rules, r)
+	}
+
+	name := params.Str(packageRulesKey)
+	if name == "" {
+		return rules
+	}
+
+	data, err := afero.ReadFile(m.fs, name)
+	m.CheckErr(err, "unable to read package rules file: ", name)
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; s.Scan(); line++ {
+		txt := strings.TrimSpace(s.Text())
+		if txt == "" || strings.HasPrefix(txt, "#") {
+			continue
+		}
+
+		r, err := ParsePackageRule(txt)
+		m.CheckErr(err, fmt.Sprintf("%s:%d", name, line))
+		rules = append(rules, r)
+	}
+
+	return rules
+}
+
+var _ Module = (*layeringModule)(nil)
@@ -0,0 +1,109 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePackageRule(t *testing.T) {
+	t.Parallel()
+
+	r, err := ParsePackageRule(" *.public ->*.internal ")
+	require.NoError(t, err)
+	assert.Equal(t, PackageRule{From: "*.public", To: "*.internal"}, r)
+	assert.Equal(t, "*.public -> *.internal", r.String())
+
+	for _, s := range []string{"foo", "a->b->c", "->b", "[->b"} {
+		_, err = ParsePackageRule(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestPackageRule_Violated(t *testing.T) {
+	t.Parallel()
+
+	r := PackageRule{From: "*.public", To: "*.internal"}
+	assert.True(t, r.Violated("acme.public", "acme.internal"))
+	assert.True(t, r.Violated("acme.foo.public", "other.internal"))
+	assert.False(t, r.Violated("acme.internal", "acme.public"))
+	assert.False(t, r.Violated("acme.public", "acme.internal.v1"))
+}
+
+func TestPackageLayering(t *testing.T) {
+	t.Parallel()
+
+	pkgs := dummyPkgGraph(map[string][]string{
+		"acme.public":   {"acme.internal", "acme.types"},
+		"acme.internal": {"acme.types"},
+		"acme.types":    {"acme.util"},
+		"acme.util":     {"acme.types"},
+		"acme.web":      {"acme.public"},
+	})
+
+	targets := map[string]File{}
+	for _, n := range []string{"acme.public", "acme.types", "acme.web"} {
+		f := pkgs[n].Files()[0]
+		targets[f.Name().String()] = f
+	}
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "rules.txt", []byte("# comment\n\nacme.types -> acme.util\n"), 0644))
+
+	params := Parameters{}
+	params.SetStr(packageDenyKey, "*.public->*.internal;acme.web -> *.util")
+	params.SetStr(packageRulesKey, "rules.txt")
+	params.SetStr(packageCyclesKey, "deny")
+
+	d := InitMockDebugger()
+	m := PackageLayering(PackageRule{From: "acme.*", To: "acme.types"}).(*layeringModule)
+	m.fs = fs
+	m.InitContext(Context(d, params, "."))
+
+	arts := m.Execute(targets, pkgs)
+	require.NoError(t, d.Err())
+
+	var msgs []string
+	for _, a := range arts {
+		msgs = append(msgs, a.(GeneratorError).Message)
+	}
+
+	assert.Equal(t, []string{
+		"acme.public.proto (package acme.public) imports acme.internal.proto (package acme.internal), violating rule `*.public -> *.internal`",
+		"acme.public.proto (package acme.public) imports acme.types.proto (package acme.types), violating rule `acme.* -> acme.types`",
+		"acme.types.proto (package acme.types) imports acme.util.proto (package acme.util), violating rule `acme.types -> acme.util`",
+		"package acme.web depends on package acme.types through acme.web -> acme.public -> acme.types, violating rule `acme.* -> acme.types`",
+		"package acme.web depends on package acme.util through acme.web -> acme.public -> acme.types -> acme.util, violating rule `acme.web -> *.util`",
+		"import cycle between packages: acme.types, acme.util",
+	}, msgs)
+}
+
+func TestPackageLayering_BadParams(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]Parameters{
+		"deny":         {packageDenyKey: "foo"},
+		"missing file": {packageRulesKey: "missing.txt"},
+		"bad file":     {packageRulesKey: "rules.txt"},
+	}
+
+	for name, params := range tests {
+		p := params
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, "rules.txt", []byte("foo -> \n"), 0644))
+
+			d := InitMockDebugger()
+			m := PackageLayering().(*layeringModule)
+			m.fs = fs
+			m.InitContext(Context(d, p, "."))
+
+			m.Execute(map[string]File{}, map[string]Package{})
+			assert.Error(t, d.Err())
+		})
+	}
+}
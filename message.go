@@ -1,6 +1,8 @@
 package pgs
 
 import (
+	"sync"
+
 	"google.golang.org/protobuf/runtime/protoimpl"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -52,11 +54,41 @@ type Message interface {
 	// transitively uses.
 	Dependencies() []Message
 
+	// ReservedRanges returns the ranges of field numbers reserved on this
+	// Message.
+	ReservedRanges() []NumberRange
+
+	// ReservedNames returns the field names reserved on this Message.
+	ReservedNames() []Name
+
+	// ExtensionRanges returns the ranges of field numbers declared available
+	// for extensions of this Message.
+	ExtensionRanges() []ExtensionRange
+
+	// IsNumberReserved returns true if the field number n is within one of the
+	// Message's reserved ranges.
+	IsNumberReserved(n int32) bool
+
+	// IsNameReserved returns true if name is one of the Message's reserved
+	// field names.
+	IsNameReserved(name Name) bool
+
+	// NextAvailableFieldNumber returns the smallest field number that is not
+	// used by a field, reserved, within an extension range, or within the
+	// range reserved for the protocol buffers implementation (19000-19999).
+	// Zero is returned if no field numbers are available.
+	NextAvailableFieldNumber() int32
+
 	// IsMapEntry identifies this message as a MapEntry. If true, this message is
 	// not generated as code, and is used exclusively when marshaling a map field
 	// to the wire format.
 	IsMapEntry() bool
 
+	// IsGroup identifies this message as the synthetic Message generated for a
+	// proto2 group field. The message shares its name with the group, while
+	// the field itself uses the lower-cased name.
+	IsGroup() bool
+
 	// IsWellKnown identifies whether or not this Message is a WKT from the
 	// `google.protobuf` package. Most official plugins special case these types
 	// and they usually need to be handled differently.
@@ -67,13 +99,17 @@ type Message interface {
 	WellKnownType() WellKnownType
 
 	setParent(p ParentEntity)
+	setGroup()
 	addField(f Field)
 	addExtension(e Extension)
 	addOneOf(o OneOf)
 	addDependent(message Message)
-	getDependents(set map[string]Message)
+	directDependents() []Message
 	addDependency(message Message)
-	getDependencies(set map[string]Message)
+	directDependencies() []Message
+	addReservedRange(r NumberRange)
+	addExtensionRange(r ExtensionRange)
+	rangeAtPath(path []int32) NumberRange
 }
 
 type msg struct {
@@ -89,11 +125,17 @@ type msg struct {
 	oneofs              []OneOf
 	maps                []Message
 	dependents          []Message
+	dependentsOnce      sync.Once
 	dependentsCache     map[string]Message
 	dependencies        []Message
+	dependenciesOnce    sync.Once
 	dependenciesCache   map[string]Message
+	reserved            []NumberRange
+	extRanges           []ExtensionRange
+	group               bool
 
-	info SourceCodeInfo
+	info    SourceCodeInfo
+	optInfo optionLocations
 }
 
 func (m *msg) Name() Name                              { return Name(m.desc.GetName()) }
@@ -102,15 +144,65 @@ func (m *msg) Syntax() Syntax                          { return m.parent.Syntax(
 func (m *msg) Package() Package                        { return m.parent.Package() }
 func (m *msg) File() File                              { return m.parent.File() }
 func (m *msg) BuildTarget() bool                       { return m.parent.BuildTarget() }
+func (m *msg) Deprecated() bool                        { return m.desc.GetOptions().GetDeprecated() }
 func (m *msg) SourceCodeInfo() SourceCodeInfo          { return m.info }
 func (m *msg) Descriptor() *descriptor.DescriptorProto { return m.desc }
 func (m *msg) Parent() ParentEntity                    { return m.parent }
 func (m *msg) IsMapEntry() bool                        { return m.desc.GetOptions().GetMapEntry() }
+func (m *msg) IsGroup() bool                           { return m.group }
 func (m *msg) Enums() []Enum                           { return m.enums }
 func (m *msg) Messages() []Message                     { return m.msgs }
 func (m *msg) Fields() []Field                         { return m.fields }
 func (m *msg) OneOfs() []OneOf                         { return m.oneofs }
 func (m *msg) MapEntries() []Message                   { return m.maps }
+func (m *msg) ReservedRanges() []NumberRange           { return m.reserved }
+func (m *msg) ExtensionRanges() []ExtensionRange       { return m.extRanges }
+
+func (m *msg) ReservedNames() []Name {
+	names := make([]Name, len(m.desc.GetReservedName()))
+	for i, n := range m.desc.GetReservedName() {
+		names[i] = Name(n)
+	}
+	return names
+}
+
+func (m *msg) IsNumberReserved(n int32) bool {
+	for _, r := range m.reserved {
+		if r.Contains(n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *msg) IsNameReserved(name Name) bool {
+	for _, n := range m.desc.GetReservedName() {
+		if n == name.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *msg) NextAvailableFieldNumber() int32 {
+	taken := make([]numRange, 0, len(m.fields)+len(m.reserved)+len(m.extRanges)+1)
+	taken = append(taken, numRange{start: FirstReservedFieldNumber, last: LastReservedFieldNumber})
+
+	for _, f := range m.fields {
+		n := f.Descriptor().GetNumber()
+		taken = append(taken, numRange{start: n, last: n})
+	}
+
+	for _, r := range m.reserved {
+		taken = append(taken, newExclusiveRange(r.Start(), r.End()))
+	}
+
+	for _, r := range m.extRanges {
+		taken = append(taken, newExclusiveRange(r.Start(), r.End()))
+	}
+
+	return nextAvailable(1, MaxFieldNumber, taken)
+}
 
 func (m *msg) WellKnownType() WellKnownType {
 	if m.Package().ProtoName() == WellKnownTypePackage {
@@ -190,54 +282,46 @@ func (m *msg) Imports() (i []File) {
 	return
 }
 
-func (m *msg) getDependents(set map[string]Message) {
-	m.populateDependentsCache()
-
-	for fqn, d := range m.dependentsCache {
-		set[fqn] = d
-	}
-}
-
-func (m *msg) populateDependentsCache() {
-	if m.dependentsCache != nil {
-		return
-	}
-
-	m.dependentsCache = map[string]Message{}
-	for _, dep := range m.dependents {
-		m.dependentsCache[dep.FullyQualifiedName()] = dep
-		dep.getDependents(m.dependentsCache)
-	}
-}
+func (m *msg) directDependents() []Message { return m.dependents }
 
 func (m *msg) Dependents() []Message {
-	m.populateDependentsCache()
+	m.dependentsOnce.Do(func() {
+		m.dependentsCache = collectMessages(m.dependents, Message.directDependents)
+	})
 	return messageSetToSlice(m.FullyQualifiedName(), m.dependentsCache)
 }
 
-func (m *msg) getDependencies(set map[string]Message) {
-	m.populateDependenciesCache()
+func (m *msg) directDependencies() []Message { return m.dependencies }
 
-	for fqn, d := range m.dependenciesCache {
-		set[fqn] = d
-	}
+func (m *msg) Dependencies() []Message {
+	m.dependenciesOnce.Do(func() {
+		m.dependenciesCache = collectMessages(m.dependencies, Message.directDependencies)
+	})
+	return messageSetToSlice(m.FullyQualifiedName(), m.dependenciesCache)
 }
 
-func (m *msg) populateDependenciesCache() {
-	if m.dependenciesCache != nil {
-		return
-	}
+// collectMessages returns the Messages reachable from roots by following next,
+// keyed by their fully qualified names. Each cache is computed independently,
+// rather than from the caches of other Messages, so they can be safely
+// populated concurrently even when the Messages form a cycle.
+func collectMessages(roots []Message, next func(Message) []Message) map[string]Message {
+	set := map[string]Message{}
+
+	queue := append([]Message(nil), roots...)
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+
+		fqn := m.FullyQualifiedName()
+		if _, ok := set[fqn]; ok {
+			continue
+		}
 
-	m.dependenciesCache = map[string]Message{}
-	for _, dep := range m.dependencies {
-		m.dependenciesCache[dep.FullyQualifiedName()] = dep
-		dep.getDependencies(m.dependenciesCache)
+		set[fqn] = m
+		queue = append(queue, next(m)...)
 	}
-}
 
-func (m *msg) Dependencies() []Message {
-	m.populateDependenciesCache()
-	return messageSetToSlice(m.FullyQualifiedName(), m.dependenciesCache)
+	return set
 }
 
 func (m *msg) Extension(desc *protoimpl.ExtensionInfo, ext interface{}) (bool, error) {
@@ -304,6 +388,8 @@ func (m *msg) addDefExtension(ext Extension) {
 
 func (m *msg) setParent(p ParentEntity) { m.parent = p }
 
+func (m *msg) setGroup() { m.group = true }
+
 func (m *msg) addEnum(e Enum) {
 	e.setParent(m)
 	m.enums = append(m.enums, e)
@@ -329,6 +415,14 @@ func (m *msg) addMapEntry(me Message) {
 	m.maps = append(m.maps, me)
 }
 
+func (m *msg) addReservedRange(r NumberRange) {
+	m.reserved = append(m.reserved, r)
+}
+
+func (m *msg) addExtensionRange(r ExtensionRange) {
+	m.extRanges = append(m.extRanges, r)
+}
+
 func (m *msg) addDependent(message Message) {
 	m.dependents = append(m.dependents, message)
 }
@@ -357,6 +451,8 @@ func (m *msg) childAtPath(path []int32) Entity {
 		child = m.enums[path[1]]
 	case messageTypeOneofDeclPath:
 		child = m.oneofs[path[1]]
+	case messageTypeExtensionPath:
+		child = m.defExts[path[1]]
 	default:
 		return nil
 	}
@@ -364,8 +460,31 @@ func (m *msg) childAtPath(path []int32) Entity {
 	return child.childAtPath(path[2:])
 }
 
+func (m *msg) rangeAtPath(path []int32) NumberRange {
+	if len(path) != 2 {
+		return nil
+	}
+
+	switch path[0] {
+	case messageTypeReservedPath:
+		return m.reserved[path[1]]
+	case messageTypeExtRangePath:
+		return m.extRanges[path[1]]
+	default:
+		return nil
+	}
+}
+
 func (m *msg) addSourceCodeInfo(info SourceCodeInfo) { m.info = info }
 
+func (m *msg) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return m.optInfo.lookup(path)
+}
+
+func (m *msg) addOptionSourceCodeInfo(path []int32, info SourceCodeInfo) {
+	m.optInfo = append(m.optInfo, optionLocation{path: path, info: info})
+}
+
 func messageSetToSlice(name string, set map[string]Message) []Message {
 	dependents := make([]Message, 0, len(set))
 
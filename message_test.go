@@ -438,6 +438,83 @@ func TestMsg_Dependencies(t *testing.T) {
 	assert.Contains(t, m3.Dependencies(), m2)
 }
 
+func TestMsg_Reserved(t *testing.T) {
+	t.Parallel()
+
+	m := dummyMsg()
+	m.desc.ReservedName = []string{"foo", "bar"}
+	assert.Equal(t, []Name{"foo", "bar"}, m.ReservedNames())
+	assert.True(t, m.IsNameReserved("foo"))
+	assert.False(t, m.IsNameReserved("baz"))
+
+	assert.Empty(t, m.ReservedRanges())
+	assert.False(t, m.IsNumberReserved(3))
+
+	r := newExclusiveRange(2, 5)
+	m.addReservedRange(&r)
+	assert.Len(t, m.ReservedRanges(), 1)
+	assert.True(t, m.IsNumberReserved(3))
+	assert.False(t, m.IsNumberReserved(5))
+}
+
+func TestMsg_ExtensionRanges(t *testing.T) {
+	t.Parallel()
+
+	m := dummyMsg()
+	assert.Empty(t, m.ExtensionRanges())
+
+	r := &extRange{numRange: newExclusiveRange(100, 200), msg: m}
+	m.addExtensionRange(r)
+	assert.Equal(t, []ExtensionRange{r}, m.ExtensionRanges())
+	assert.False(t, m.IsNumberReserved(150), "extension ranges are not reserved")
+}
+
+func TestMsg_NextAvailableFieldNumber(t *testing.T) {
+	t.Parallel()
+
+	m := dummyMsg()
+	assert.Equal(t, int32(1), m.NextAvailableFieldNumber())
+
+	for _, n := range []int32{1, 2, 4} {
+		m.addField(&field{desc: &descriptor.FieldDescriptorProto{Number: proto.Int32(n)}})
+	}
+	assert.Equal(t, int32(3), m.NextAvailableFieldNumber())
+
+	r := newExclusiveRange(3, 10)
+	m.addReservedRange(&r)
+	assert.Equal(t, int32(10), m.NextAvailableFieldNumber())
+
+	m.addExtensionRange(&extRange{numRange: newExclusiveRange(10, FirstReservedFieldNumber)})
+	assert.Equal(t, LastReservedFieldNumber+1, m.NextAvailableFieldNumber())
+
+	m.addExtensionRange(&extRange{numRange: newExclusiveRange(LastReservedFieldNumber+1, MaxFieldNumber+1)})
+	assert.Zero(t, m.NextAvailableFieldNumber())
+}
+
+func TestMsg_RangeAtPath(t *testing.T) {
+	t.Parallel()
+
+	m := dummyMsg()
+	r := newExclusiveRange(1, 2)
+	m.addReservedRange(&r)
+	er := &extRange{numRange: newExclusiveRange(100, 200)}
+	m.addExtensionRange(er)
+
+	assert.Equal(t, &r, m.rangeAtPath([]int32{messageTypeReservedPath, 0}))
+	assert.Equal(t, er, m.rangeAtPath([]int32{messageTypeExtRangePath, 0}))
+	assert.Nil(t, m.rangeAtPath([]int32{messageTypeReservedPath}))
+	assert.Nil(t, m.rangeAtPath([]int32{messageTypeFieldPath, 0}))
+}
+
+func TestMsg_IsGroup(t *testing.T) {
+	t.Parallel()
+
+	m := dummyMsg()
+	assert.False(t, m.IsGroup())
+	m.setGroup()
+	assert.True(t, m.IsGroup())
+}
+
 func TestMsg_ChildAtPath(t *testing.T) {
 	t.Parallel()
 
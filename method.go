@@ -37,7 +37,8 @@ type method struct {
 
 	in, out Message
 
-	info SourceCodeInfo
+	info    SourceCodeInfo
+	optInfo optionLocations
 }
 
 func (m *method) Name() Name                                    { return Name(m.desc.GetName()) }
@@ -46,6 +47,7 @@ func (m *method) Syntax() Syntax                                { return m.servi
 func (m *method) Package() Package                              { return m.service.Package() }
 func (m *method) File() File                                    { return m.service.File() }
 func (m *method) BuildTarget() bool                             { return m.service.BuildTarget() }
+func (m *method) Deprecated() bool                              { return m.desc.GetOptions().GetDeprecated() }
 func (m *method) SourceCodeInfo() SourceCodeInfo                { return m.info }
 func (m *method) Descriptor() *descriptor.MethodDescriptorProto { return m.desc }
 func (m *method) Service() Service                              { return m.service }
@@ -93,4 +95,12 @@ func (m *method) childAtPath(path []int32) Entity {
 
 func (m *method) addSourceCodeInfo(info SourceCodeInfo) { m.info = info }
 
+func (m *method) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return m.optInfo.lookup(path)
+}
+
+func (m *method) addOptionSourceCodeInfo(path []int32, info SourceCodeInfo) {
+	m.optInfo = append(m.optInfo, optionLocation{path: path, info: info})
+}
+
 var _ Method = (*method)(nil)
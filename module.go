@@ -1,6 +1,10 @@
 package pgs
 
-import "os"
+import (
+	"os"
+
+	"github.com/spf13/afero"
+)
 
 // Module describes the interface for a domain-specific code generation module
 // that can be registered with the PG* generator.
@@ -19,6 +23,18 @@ type Module interface {
 	Execute(targets map[string]File, packages map[string]Package) []Artifact
 }
 
+// fsModule is implemented by built-in Modules that read files, such as
+// configuration, from the Generator's FileSystem.
+type fsModule interface {
+	setFS(fs afero.Fs)
+}
+
+// astModule is implemented by built-in Modules that use the AST beyond the
+// Files and Packages passed to Execute, such as its cached PackageGraph.
+type astModule interface {
+	setAST(ast AST)
+}
+
 // ModuleBase provides utility methods and a base implementation for a
 // protoc-gen-star Module. ModuleBase should be used as an anonymously embedded
 // field of an actual Module implementation. The only methods that need to be
@@ -126,6 +142,26 @@ func (m *ModuleBase) OverwriteGeneratorFile(name, content string) {
 	})
 }
 
+// AddGeneratorBytesFile behaves the same as AddGeneratorFile, however the
+// contents are binary. Only BinaryPostProcessors are applied to the file.
+func (m *ModuleBase) AddGeneratorBytesFile(name string, content []byte) {
+	m.AddArtifact(GeneratorBytesFile{
+		Name:     name,
+		Contents: content,
+	})
+}
+
+// OverwriteGeneratorBytesFile behaves the same as AddGeneratorBytesFile,
+// however if a previously executed Module has created a file with the same
+// name, it will be overwritten with this one.
+func (m *ModuleBase) OverwriteGeneratorBytesFile(name string, content []byte) {
+	m.AddArtifact(GeneratorBytesFile{
+		Name:      name,
+		Contents:  content,
+		Overwrite: true,
+	})
+}
+
 // AddGeneratorTemplateFile behaves the same as AddGeneratorFile, however the
 // contents are rendered from the provided tpl and data.
 func (m *ModuleBase) AddGeneratorTemplateFile(name string, tpl Template, data interface{}) {
@@ -228,6 +264,27 @@ func (m *ModuleBase) OverwriteCustomFile(name, content string, perms os.FileMode
 	})
 }
 
+// AddCustomBytesFile behaves the same as AddCustomFile, however the contents
+// are binary. Only BinaryPostProcessors are applied to the file.
+func (m *ModuleBase) AddCustomBytesFile(name string, content []byte, perms os.FileMode) {
+	m.AddArtifact(CustomBytesFile{
+		Name:     name,
+		Contents: content,
+		Perms:    perms,
+	})
+}
+
+// OverwriteCustomBytesFile behaves the same as AddCustomBytesFile, however if
+// the file already exists, it will be overwritten with this one.
+func (m *ModuleBase) OverwriteCustomBytesFile(name string, content []byte, perms os.FileMode) {
+	m.AddArtifact(CustomBytesFile{
+		Name:      name,
+		Contents:  content,
+		Perms:     perms,
+		Overwrite: true,
+	})
+}
+
 // AddCustomTemplateFile behaves the same as AddCustomFile, however the
 // contents are rendered from the provided tpl and data.
 func (m *ModuleBase) AddCustomTemplateFile(name string, tpl Template, data interface{}, perms os.FileMode) {
@@ -102,6 +102,25 @@ func TestModuleBase_AddGeneratorFile(t *testing.T) {
 	}, arts[0])
 }
 
+func TestModuleBase_AddGeneratorBytesFile(t *testing.T) {
+	t.Parallel()
+
+	m := new(ModuleBase)
+	m.AddGeneratorBytesFile("foo", []byte{0xff})
+	m.OverwriteGeneratorBytesFile("bar", []byte{0xfe})
+	arts := m.Artifacts()
+	assert.Len(t, arts, 2)
+	assert.Equal(t, GeneratorBytesFile{
+		Name:     "foo",
+		Contents: []byte{0xff},
+	}, arts[0])
+	assert.Equal(t, GeneratorBytesFile{
+		Name:      "bar",
+		Contents:  []byte{0xfe},
+		Overwrite: true,
+	}, arts[1])
+}
+
 func TestModuleBase_OverwriteGeneratorFile(t *testing.T) {
 	t.Parallel()
 
@@ -238,6 +257,27 @@ func TestModuleBase_OverwriteCustomFile(t *testing.T) {
 	}, arts[0])
 }
 
+func TestModuleBase_AddCustomBytesFile(t *testing.T) {
+	t.Parallel()
+
+	m := new(ModuleBase)
+	m.AddCustomBytesFile("foo", []byte{0xff}, 0765)
+	m.OverwriteCustomBytesFile("bar", []byte{0xfe}, 0600)
+	arts := m.Artifacts()
+	assert.Len(t, arts, 2)
+	assert.Equal(t, CustomBytesFile{
+		Name:     "foo",
+		Contents: []byte{0xff},
+		Perms:    0765,
+	}, arts[0])
+	assert.Equal(t, CustomBytesFile{
+		Name:      "bar",
+		Contents:  []byte{0xfe},
+		Perms:     0600,
+		Overwrite: true,
+	}, arts[1])
+}
+
 func TestModuleBase_AddCustomTemplateFile(t *testing.T) {
 	t.Parallel()
 
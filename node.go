@@ -23,8 +23,14 @@ type Visitor interface {
 	VisitMethod(Method) (v Visitor, err error)
 }
 
-// Walk applies a depth-first visitor pattern with v against Node n.
-func Walk(v Visitor, n Node) error { return n.accept(v) }
+// Walk applies a depth-first visitor pattern with v against Node n. The
+// provided WalkOptions control which Nodes are visited beyond the defaults.
+func Walk(v Visitor, n Node, opts ...WalkOption) error {
+	if len(opts) == 0 {
+		return n.accept(v)
+	}
+	return newWalkOptions(opts).walk(v, n)
+}
 
 type nilVisitor struct{}
 
@@ -33,7 +33,8 @@ type oneof struct {
 	flds []Field
 	fqn  string
 
-	info SourceCodeInfo
+	info    SourceCodeInfo
+	optInfo optionLocations
 }
 
 func (o *oneof) accept(v Visitor) (err error) {
@@ -51,6 +52,7 @@ func (o *oneof) Syntax() Syntax                               { return o.msg.Syn
 func (o *oneof) Package() Package                             { return o.msg.Package() }
 func (o *oneof) File() File                                   { return o.msg.File() }
 func (o *oneof) BuildTarget() bool                            { return o.msg.BuildTarget() }
+func (o *oneof) Deprecated() bool                             { return false } // OneofOptions has no deprecated field
 func (o *oneof) SourceCodeInfo() SourceCodeInfo               { return o.info }
 func (o *oneof) Descriptor() *descriptor.OneofDescriptorProto { return o.desc }
 func (o *oneof) Message() Message                             { return o.msg }
@@ -100,4 +102,12 @@ func (o *oneof) childAtPath(path []int32) Entity {
 
 func (o *oneof) addSourceCodeInfo(info SourceCodeInfo) { o.info = info }
 
+func (o *oneof) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return o.optInfo.lookup(path)
+}
+
+func (o *oneof) addOptionSourceCodeInfo(path []int32, info SourceCodeInfo) {
+	o.optInfo = append(o.optInfo, optionLocation{path: path, info: info})
+}
+
 var _ OneOf = (*oneof)(nil)
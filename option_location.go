@@ -0,0 +1,127 @@
+package pgs
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	fileOptionsPath      int32 = 8 // FileDescriptorProto.Options
+	messageOptionsPath   int32 = 7 // DescriptorProto.Options
+	fieldOptionsPath     int32 = 8 // FieldDescriptorProto.Options
+	oneofOptionsPath     int32 = 2 // OneofDescriptorProto.Options
+	enumOptionsPath      int32 = 3 // EnumDescriptorProto.Options
+	enumValueOptionsPath int32 = 3 // EnumValueDescriptorProto.Options
+	serviceOptionsPath   int32 = 3 // ServiceDescriptorProto.Options
+	methodOptionsPath    int32 = 4 // MethodDescriptorProto.Options
+)
+
+// OptionPath resolves the path of field numbers to an option within an
+// entity's options message, suitable for Entity.OptionSourceCodeInfo. The
+// option is identified by its extension type (such as a generated
+// *protoimpl.ExtensionInfo) and the names of any nested fields. For example,
+// the option `(validate.rules).string.min_len` has the path returned by
+// OptionPath(validate.E_Rules, "string", "min_len").
+func OptionPath(ext protoreflect.ExtensionType, fields ...string) ([]int32, error) {
+	fd := protoreflect.FieldDescriptor(ext.TypeDescriptor())
+	path := []int32{int32(fd.Number())}
+
+	for _, name := range fields {
+		md := fd.Message()
+		if md == nil {
+			return nil, fmt.Errorf("cannot resolve %q on non-message option field %s", name, fd.FullName())
+		}
+
+		if fd = md.Fields().ByName(protoreflect.Name(name)); fd == nil {
+			return nil, fmt.Errorf("field %q not found on %s", name, md.FullName())
+		}
+
+		path = append(path, int32(fd.Number()))
+	}
+
+	return path, nil
+}
+
+type optionLocation struct {
+	path []int32
+	info SourceCodeInfo
+}
+
+type optionLocations []optionLocation
+
+// lookup returns the SourceCodeInfo with the longest path that is a prefix of
+// path. If path is empty, only a location with an empty path matches.
+func (locs optionLocations) lookup(path []int32) (info SourceCodeInfo) {
+	best := -1
+
+	for _, loc := range locs {
+		if len(loc.path) <= best || len(loc.path) > len(path) {
+			continue
+		}
+
+		// the options declaration as a whole does not describe any one option
+		if len(loc.path) == 0 && len(path) > 0 {
+			continue
+		}
+
+		if isPathPrefix(loc.path, path) {
+			best, info = len(loc.path), loc.info
+		}
+	}
+
+	return info
+}
+
+func isPathPrefix(prefix, path []int32) bool {
+	for i, n := range prefix {
+		if path[i] != n {
+			return false
+		}
+	}
+	return true
+}
+
+// optionsPath returns the field number of the options on the descriptor
+// underlying e.
+func optionsPath(e Entity) int32 {
+	switch e.(type) {
+	case File:
+		return fileOptionsPath
+	case Message:
+		return messageOptionsPath
+	case Field: // includes Extension
+		return fieldOptionsPath
+	case OneOf:
+		return oneofOptionsPath
+	case Enum:
+		return enumOptionsPath
+	case EnumValue:
+		return enumValueOptionsPath
+	case Service:
+		return serviceOptionsPath
+	case Method:
+		return methodOptionsPath
+	default:
+		return -1
+	}
+}
+
+// optionAtPath resolves the Entity whose options are described by path,
+// returning the Entity and the remaining path within its options message. A
+// nil Entity is returned if path does not refer to an option.
+func optionAtPath(f File, path []int32) (Entity, []int32) {
+	// declaration paths are pairs of field number and index
+	for i := 0; i < len(path); i += 2 {
+		e := f.childAtPath(path[:i])
+		if e == nil {
+			return nil, nil
+		}
+
+		if path[i] == optionsPath(e) {
+			return e, path[i+1:]
+		}
+	}
+
+	return nil, nil
+}
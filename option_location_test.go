@@ -0,0 +1,170 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestOptionLocations_Lookup(t *testing.T) {
+	t.Parallel()
+
+	info := func(c string) SourceCodeInfo {
+		return sci{desc: &descriptor.SourceCodeInfo_Location{LeadingComments: proto.String(c)}}
+	}
+
+	locs := optionLocations{
+		{path: []int32{}, info: info("block")},
+		{path: []int32{1071}, info: info("rules")},
+		{path: []int32{1071, 14, 2}, info: info("min_len")},
+		{path: []int32{3}, info: info("deprecated")},
+	}
+
+	assert.Equal(t, "block", locs.lookup(nil).LeadingComments())
+	assert.Equal(t, "min_len", locs.lookup([]int32{1071, 14, 2}).LeadingComments())
+	assert.Equal(t, "rules", locs.lookup([]int32{1071, 14, 3}).LeadingComments())
+	assert.Equal(t, "rules", locs.lookup([]int32{1071}).LeadingComments())
+	assert.Equal(t, "deprecated", locs.lookup([]int32{3}).LeadingComments())
+	assert.Nil(t, locs.lookup([]int32{1072}))
+	assert.Nil(t, optionLocations{}.lookup(nil))
+}
+
+func TestOptionPath(t *testing.T) {
+	t.Parallel()
+
+	fdp := &descriptor.FileDescriptorProto{
+		Name:       proto.String("rules.proto"),
+		Package:    proto.String("rules"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("FieldRules"),
+				Field: []*descriptor.FieldDescriptorProto{{
+					Name:     proto.String("string"),
+					JsonName: proto.String("string"),
+					Number:   proto.Int32(14),
+					Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+					TypeName: proto.String(".rules.StringRules"),
+				}},
+			},
+			{
+				Name: proto.String("StringRules"),
+				Field: []*descriptor.FieldDescriptorProto{{
+					Name:     proto.String("min_len"),
+					JsonName: proto.String("minLen"),
+					Number:   proto.Int32(2),
+					Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptor.FieldDescriptorProto_TYPE_UINT64.Enum(),
+				}},
+			},
+		},
+		Extension: []*descriptor.FieldDescriptorProto{{
+			Name:     proto.String("rules"),
+			JsonName: proto.String("rules"),
+			Number:   proto.Int32(1071),
+			Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(".rules.FieldRules"),
+			Extendee: proto.String(".google.protobuf.FieldOptions"),
+		}},
+	}
+
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	ext := dynamicpb.NewExtensionType(fd.Extensions().Get(0))
+
+	path, err := OptionPath(ext)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{1071}, path)
+
+	path, err = OptionPath(ext, "string", "min_len")
+	require.NoError(t, err)
+	assert.Equal(t, []int32{1071, 14, 2}, path)
+
+	_, err = OptionPath(ext, "bytes")
+	assert.Error(t, err)
+
+	_, err = OptionPath(ext, "string", "min_len", "foo")
+	assert.Error(t, err)
+}
+
+func TestGraph_OptionSourceCodeInfo(t *testing.T) {
+	t.Parallel()
+
+	loc := func(comment string, path ...int32) *descriptor.SourceCodeInfo_Location {
+		return &descriptor.SourceCodeInfo_Location{Path: path, Span: []int32{1, 2, 3}, LeadingComments: proto.String(comment)}
+	}
+
+	fd := &descriptor.FileDescriptorProto{
+		Name:    proto.String("opts.proto"),
+		Package: proto.String("opts"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("Msg"),
+			Field: []*descriptor.FieldDescriptorProto{{
+				Name:   proto.String("fld"),
+				Number: proto.Int32(1),
+				Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:   descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}},
+		}},
+		EnumType: []*descriptor.EnumDescriptorProto{{
+			Name:  proto.String("Enum"),
+			Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String("ZERO"), Number: proto.Int32(0)}},
+		}},
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name: proto.String("Svc"),
+			Method: []*descriptor.MethodDescriptorProto{{
+				Name:       proto.String("Do"),
+				InputType:  proto.String(".opts.Msg"),
+				OutputType: proto.String(".opts.Msg"),
+			}},
+		}},
+		SourceCodeInfo: &descriptor.SourceCodeInfo{Location: []*descriptor.SourceCodeInfo_Location{
+			loc("file option", fileOptionsPath, 11),
+			loc("message option", messageTypePath, 0, messageOptionsPath, 3),
+			loc("field options", messageTypePath, 0, messageTypeFieldPath, 0, fieldOptionsPath),
+			loc("field rules", messageTypePath, 0, messageTypeFieldPath, 0, fieldOptionsPath, 1071),
+			loc("field min_len", messageTypePath, 0, messageTypeFieldPath, 0, fieldOptionsPath, 1071, 14, 2),
+			loc("enum value option", enumTypePath, 0, enumTypeValuePath, 0, enumValueOptionsPath, 1),
+			loc("method option", servicePath, 0, serviceTypeMethodPath, 0, methodOptionsPath, 33),
+		}},
+	}
+
+	d := InitMockDebugger()
+	g := ProcessFileDescriptorSet(d, &descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{fd}})
+	require.False(t, d.Failed())
+
+	lookup := func(name string) Entity {
+		e, ok := g.Lookup(name)
+		require.True(t, ok, name)
+		return e
+	}
+
+	f := lookup("opts.proto")
+	assert.Equal(t, "file option", f.OptionSourceCodeInfo(11).LeadingComments())
+	assert.Nil(t, f.OptionSourceCodeInfo(1))
+
+	m := lookup(".opts.Msg")
+	assert.Equal(t, "message option", m.OptionSourceCodeInfo(3).LeadingComments())
+	assert.Nil(t, m.SourceCodeInfo(), "option locations are not attached to the entity itself")
+
+	fld := lookup(".opts.Msg.fld")
+	assert.Equal(t, "field options", fld.OptionSourceCodeInfo().LeadingComments())
+	assert.Equal(t, "field min_len", fld.OptionSourceCodeInfo(1071, 14, 2).LeadingComments())
+	assert.Equal(t, "field rules", fld.OptionSourceCodeInfo(1071, 14, 3).LeadingComments())
+	assert.Equal(t, Position{File: "opts.proto", StartLine: 2, StartCol: 3, EndLine: 2, EndCol: 3},
+		fld.OptionSourceCodeInfo(1071).Position())
+
+	assert.Equal(t, "enum value option", lookup(".opts.Enum.ZERO").OptionSourceCodeInfo(1).LeadingComments())
+	assert.Nil(t, lookup(".opts.Enum").OptionSourceCodeInfo(1))
+	assert.Equal(t, "method option", lookup(".opts.Svc.Do").OptionSourceCodeInfo(33).LeadingComments())
+	assert.Nil(t, lookup(".opts.Svc").OptionSourceCodeInfo(33))
+}
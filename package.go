@@ -1,6 +1,27 @@
 package pgs
 
-import descriptor "google.golang.org/protobuf/types/descriptorpb"
+import (
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// PackageScopedFileOptions are the file options that are expected to be
+// consistent across all Files in a Package, as they determine the namespace
+// of the generated code. These are checked by Package.FileOptionConflicts.
+var PackageScopedFileOptions = []string{
+	"csharp_namespace",
+	"go_package",
+	"java_package",
+	"objc_class_prefix",
+	"php_class_prefix",
+	"php_metadata_namespace",
+	"php_namespace",
+	"ruby_package",
+	"swift_prefix",
+}
 
 // Package is a container that encapsulates all the files under a single
 // package namespace.
@@ -13,11 +34,52 @@ type Package interface {
 	// All the files loaded for this Package
 	Files() []File
 
+	// Comments returns the comments attached to the `package` statement of each
+	// File in this Package, joined by newlines. Duplicate comments are only
+	// included once.
+	Comments() string
+
+	// AllMessages returns all top-level and nested messages from all Files in
+	// this Package. Map entry messages are not included.
+	AllMessages() []Message
+
+	// AllEnums returns all top-level and nested enums from all Files in this
+	// Package.
+	AllEnums() []Enum
+
+	// Services returns the services from all Files in this Package.
+	Services() []Service
+
+	// DefinedExtensions returns all Extensions defined in this Package, both
+	// at the top-level of its Files and nested within Messages.
+	DefinedExtensions() []Extension
+
+	// Imports returns the other Packages directly imported by any File in this
+	// Package, in the order they are first encountered.
+	Imports() []Package
+
+	// FileOptionConflicts returns the options listed in
+	// PackageScopedFileOptions that are set to differing values by the Files
+	// of this Package. Files that do not set an option are not considered to
+	// conflict with those that do. The returned conflicts are sorted by option
+	// name.
+	FileOptionConflicts() []FileOptionConflict
+
 	addFile(f File)
 
 	setComments(c string)
 }
 
+// FileOptionConflict describes a file option that is set to differing values
+// across the Files of a Package.
+type FileOptionConflict struct {
+	// Option is the name of the conflicting option (eg, "go_package").
+	Option string
+
+	// Values maps each distinct value of the option to the Files that set it.
+	Values map[string][]File
+}
+
 type pkg struct {
 	fd    *descriptor.FileDescriptorProto
 	files []File
@@ -25,11 +87,104 @@ type pkg struct {
 	comments string
 }
 
-func (p *pkg) ProtoName() Name  { return Name(p.fd.GetPackage()) }
-func (p *pkg) Comments() string { return p.comments }
+func (p *pkg) ProtoName() Name { return Name(p.fd.GetPackage()) }
 
 func (p *pkg) Files() []File { return p.files }
 
+func (p *pkg) Comments() string {
+	if p.comments != "" {
+		return p.comments
+	}
+
+	seen := make(map[string]struct{}, len(p.files))
+	var out []string
+	for _, f := range p.files {
+		info := f.PackageSourceCodeInfo()
+		if info == nil {
+			continue
+		}
+
+		c := strings.TrimSpace(info.LeadingComments())
+		if _, dupe := seen[c]; c == "" || dupe {
+			continue
+		}
+		seen[c] = struct{}{}
+		out = append(out, c)
+	}
+
+	return strings.Join(out, "\n")
+}
+
+func (p *pkg) AllMessages() (msgs []Message) {
+	for _, f := range p.files {
+		msgs = append(msgs, f.AllMessages()...)
+	}
+	return msgs
+}
+
+func (p *pkg) AllEnums() (enums []Enum) {
+	for _, f := range p.files {
+		enums = append(enums, f.AllEnums()...)
+	}
+	return enums
+}
+
+func (p *pkg) Services() (srvs []Service) {
+	for _, f := range p.files {
+		srvs = append(srvs, f.Services()...)
+	}
+	return srvs
+}
+
+func (p *pkg) DefinedExtensions() (exts []Extension) {
+	for _, f := range p.files {
+		exts = append(exts, f.DefinedExtensions()...)
+		for _, m := range f.AllMessages() {
+			exts = append(exts, m.DefinedExtensions()...)
+		}
+	}
+	return exts
+}
+
+func (p *pkg) Imports() (pkgs []Package) {
+	seen := map[string]struct{}{p.ProtoName().String(): {}}
+
+	for _, f := range p.files {
+		for _, imp := range f.Imports() {
+			ip := imp.Package()
+			if _, ok := seen[ip.ProtoName().String()]; ok {
+				continue
+			}
+			seen[ip.ProtoName().String()] = struct{}{}
+			pkgs = append(pkgs, ip)
+		}
+	}
+
+	return pkgs
+}
+
+func (p *pkg) FileOptionConflicts() (out []FileOptionConflict) {
+	opts := make([]string, len(PackageScopedFileOptions))
+	copy(opts, PackageScopedFileOptions)
+	sort.Strings(opts)
+
+	for _, opt := range opts {
+		vals := make(map[string][]File)
+
+		for _, f := range p.files {
+			if v, ok := fileOptionValue(f.Descriptor().GetOptions(), opt); ok {
+				vals[v] = append(vals[v], f)
+			}
+		}
+
+		if len(vals) > 1 {
+			out = append(out, FileOptionConflict{Option: opt, Values: vals})
+		}
+	}
+
+	return out
+}
+
 func (p *pkg) accept(v Visitor) (err error) {
 	if v == nil {
 		return nil
@@ -56,3 +211,21 @@ func (p *pkg) addFile(f File) {
 func (p *pkg) setComments(comments string) {
 	p.comments = comments
 }
+
+// fileOptionValue returns the string representation of the FileOptions field
+// with the provided name, if it is set.
+func fileOptionValue(opts *descriptor.FileOptions, name string) (string, bool) {
+	if opts == nil {
+		return "", false
+	}
+
+	m := opts.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil || !m.Has(fd) {
+		return "", false
+	}
+
+	return m.Get(fd).String(), true
+}
+
+var _ Package = (*pkg)(nil)
@@ -0,0 +1,182 @@
+package pgs
+
+import "sort"
+
+// PackageGraph describes the dependencies between proto Packages, derived
+// from the imports of the Files they contain.
+type PackageGraph interface {
+	// Packages returns all Packages in the graph, sorted by name.
+	Packages() []Package
+
+	// Dependencies returns the Packages directly imported by Package p.
+	Dependencies(p Package) []Package
+
+	// TransitiveDependencies returns all Packages directly or transitively
+	// imported by Package p, sorted by name. Package p is only included if it
+	// is part of an import cycle.
+	TransitiveDependencies(p Package) []Package
+
+	// Dependents returns the Packages that directly import Package p, sorted
+	// by name.
+	Dependents(p Package) []Package
+
+	// DependsOn returns true if Package a directly or transitively imports
+	// Package b.
+	DependsOn(a, b Package) bool
+
+	// Cycles returns each set of Packages that import one another, directly or
+	// transitively. Each cycle is sorted by name, as are the cycles
+	// themselves (by their first member).
+	Cycles() [][]Package
+}
+
+type pkgGraph struct {
+	pkgs       []Package
+	deps       map[string][]Package
+	dependents map[string][]Package
+}
+
+// NewPackageGraph builds a PackageGraph from the provided Packages, such as
+// those passed into Module.Execute. Imported Packages that are not present
+// in pkgs are still included in the graph.
+func NewPackageGraph(pkgs map[string]Package) PackageGraph {
+	g := &pkgGraph{
+		deps:       make(map[string][]Package, len(pkgs)),
+		dependents: make(map[string][]Package, len(pkgs)),
+	}
+
+	for _, p := range pkgs {
+		g.add(p)
+	}
+
+	sort.Slice(g.pkgs, func(i, j int) bool { return pkgName(g.pkgs[i]) < pkgName(g.pkgs[j]) })
+	for _, ds := range g.dependents {
+		sortPackages(ds)
+	}
+
+	return g
+}
+
+func (g *pkgGraph) add(p Package) {
+	if _, seen := g.deps[pkgName(p)]; seen {
+		return
+	}
+
+	deps := p.Imports()
+	g.pkgs = append(g.pkgs, p)
+	g.deps[pkgName(p)] = deps
+
+	for _, d := range deps {
+		g.dependents[pkgName(d)] = append(g.dependents[pkgName(d)], p)
+		g.add(d)
+	}
+}
+
+func (g *pkgGraph) Packages() []Package { return g.pkgs }
+
+func (g *pkgGraph) Dependencies(p Package) []Package { return g.deps[pkgName(p)] }
+
+func (g *pkgGraph) Dependents(p Package) []Package { return g.dependents[pkgName(p)] }
+
+func (g *pkgGraph) TransitiveDependencies(p Package) []Package {
+	seen := make(map[string]Package)
+
+	var visit func(p Package)
+	visit = func(p Package) {
+		for _, d := range g.deps[pkgName(p)] {
+			if _, ok := seen[pkgName(d)]; ok {
+				continue
+			}
+			seen[pkgName(d)] = d
+			visit(d)
+		}
+	}
+	visit(p)
+
+	out := make([]Package, 0, len(seen))
+	for _, d := range seen {
+		out = append(out, d)
+	}
+	sortPackages(out)
+
+	return out
+}
+
+func (g *pkgGraph) DependsOn(a, b Package) bool {
+	for _, d := range g.TransitiveDependencies(a) {
+		if pkgName(d) == pkgName(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// Cycles uses Tarjan's algorithm to find the strongly connected components of
+// the graph with more than one member.
+func (g *pkgGraph) Cycles() (cycles [][]Package) {
+	var (
+		index   int
+		stack   []Package
+		indices = make(map[string]int, len(g.pkgs))
+		lowlink = make(map[string]int, len(g.pkgs))
+		onStack = make(map[string]bool, len(g.pkgs))
+	)
+
+	var connect func(p Package)
+	connect = func(p Package) {
+		n := pkgName(p)
+		indices[n], lowlink[n] = index, index
+		index++
+		stack = append(stack, p)
+		onStack[n] = true
+
+		for _, d := range g.deps[n] {
+			dn := pkgName(d)
+			if _, visited := indices[dn]; !visited {
+				connect(d)
+				if lowlink[dn] < lowlink[n] {
+					lowlink[n] = lowlink[dn]
+				}
+			} else if onStack[dn] && indices[dn] < lowlink[n] {
+				lowlink[n] = indices[dn]
+			}
+		}
+
+		if lowlink[n] != indices[n] {
+			return
+		}
+
+		var scc []Package
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[pkgName(top)] = false
+			scc = append(scc, top)
+			if pkgName(top) == n {
+				break
+			}
+		}
+
+		if len(scc) > 1 {
+			sortPackages(scc)
+			cycles = append(cycles, scc)
+		}
+	}
+
+	for _, p := range g.pkgs {
+		if _, visited := indices[pkgName(p)]; !visited {
+			connect(p)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool { return pkgName(cycles[i][0]) < pkgName(cycles[j][0]) })
+	return cycles
+}
+
+func pkgName(p Package) string { return p.ProtoName().String() }
+
+func sortPackages(pkgs []Package) {
+	sort.Slice(pkgs, func(i, j int) bool { return pkgName(pkgs[i]) < pkgName(pkgs[j]) })
+}
+
+var _ PackageGraph = (*pkgGraph)(nil)
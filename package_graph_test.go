@@ -0,0 +1,96 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// dummyPkgGraph creates a Package for each name, with a single File importing
+// the Files of the Packages listed in deps.
+func dummyPkgGraph(deps map[string][]string) map[string]Package {
+	pkgs := make(map[string]Package, len(deps))
+	files := make(map[string]*file, len(deps))
+
+	for n := range deps {
+		p := &pkg{fd: &descriptor.FileDescriptorProto{Package: proto.String(n)}}
+		f := &file{desc: &descriptor.FileDescriptorProto{
+			Name:    proto.String(n + ".proto"),
+			Package: proto.String(n),
+		}}
+		p.addFile(f)
+		pkgs[n], files[n] = p, f
+	}
+
+	for n, ds := range deps {
+		for _, d := range ds {
+			files[n].addFileDependency(files[d])
+		}
+	}
+
+	return pkgs
+}
+
+func pkgNames(pkgs []Package) []string {
+	out := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		out[i] = pkgName(p)
+	}
+	return out
+}
+
+func TestPackageGraph(t *testing.T) {
+	t.Parallel()
+
+	pkgs := dummyPkgGraph(map[string][]string{
+		"a": {"b", "c"},
+		"b": {"c"},
+		"c": {},
+		"d": {"e"},
+		"e": {"f"},
+		"f": {"d"},
+	})
+
+	g := NewPackageGraph(pkgs)
+
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, pkgNames(g.Packages()))
+	assert.Equal(t, []string{"b", "c"}, pkgNames(g.Dependencies(pkgs["a"])))
+	assert.Empty(t, g.Dependencies(pkgs["c"]))
+	assert.Equal(t, []string{"a", "b"}, pkgNames(g.Dependents(pkgs["c"])))
+
+	assert.Equal(t, []string{"b", "c"}, pkgNames(g.TransitiveDependencies(pkgs["a"])))
+	assert.Equal(t, []string{"d", "e", "f"}, pkgNames(g.TransitiveDependencies(pkgs["d"])))
+
+	assert.True(t, g.DependsOn(pkgs["a"], pkgs["c"]))
+	assert.False(t, g.DependsOn(pkgs["c"], pkgs["a"]))
+	assert.True(t, g.DependsOn(pkgs["f"], pkgs["e"]))
+
+	cycles := g.Cycles()
+	assert.Len(t, cycles, 1)
+	assert.Equal(t, []string{"d", "e", "f"}, pkgNames(cycles[0]))
+}
+
+func TestPackageGraph_ImportedPackages(t *testing.T) {
+	t.Parallel()
+
+	pkgs := dummyPkgGraph(map[string][]string{"a": {"b"}, "b": {}})
+	delete(pkgs, "b")
+
+	g := NewPackageGraph(pkgs)
+	assert.Equal(t, []string{"a", "b"}, pkgNames(g.Packages()))
+	assert.Empty(t, g.Cycles())
+}
+
+func TestGraph_PackageGraph(t *testing.T) {
+	t.Parallel()
+
+	g := &graph{packages: dummyPkgGraph(map[string][]string{"a": {}})}
+	pg := PackageGraphOf(g)
+	assert.Len(t, pg.Packages(), 1)
+	assert.Equal(t, pg, PackageGraphOf(g))
+
+	other := PackageGraphOf(struct{ AST }{g})
+	assert.Equal(t, pkgNames(pg.Packages()), pkgNames(other.Packages()))
+}
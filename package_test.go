@@ -9,6 +9,7 @@ import (
 	"errors"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPkg_ProtoName(t *testing.T) {
@@ -81,6 +82,108 @@ func TestPackage_Comments(t *testing.T) {
 	assert.Equal(t, "foobar", pkg.Comments())
 }
 
+func TestPackage_Comments_Files(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPkg()
+	assert.Empty(t, p.Comments())
+
+	for _, c := range []string{" first ", "", "second", "first"} {
+		f := &file{desc: &descriptor.FileDescriptorProto{}}
+		f.addPackageSourceCodeInfo(sci{desc: &descriptor.SourceCodeInfo_Location{LeadingComments: proto.String(c)}})
+		p.addFile(f)
+	}
+	p.addFile(&file{desc: &descriptor.FileDescriptorProto{}})
+
+	assert.Equal(t, "first\nsecond", p.Comments())
+}
+
+func TestPackage_Aggregates(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPkg()
+
+	f1 := &file{desc: &descriptor.FileDescriptorProto{}}
+	p.addFile(f1)
+	m := &msg{desc: &descriptor.DescriptorProto{}}
+	f1.addMessage(m)
+	nm := &msg{desc: &descriptor.DescriptorProto{}}
+	m.addMessage(nm)
+	m.addMapEntry(&msg{desc: &descriptor.DescriptorProto{}})
+	e := &enum{desc: &descriptor.EnumDescriptorProto{}}
+	f1.addEnum(e)
+	fext := &ext{}
+	f1.addDefExtension(fext)
+	mext := &ext{}
+	nm.addDefExtension(mext)
+
+	f2 := &file{desc: &descriptor.FileDescriptorProto{}}
+	p.addFile(f2)
+	ne := &enum{desc: &descriptor.EnumDescriptorProto{}}
+	nm.addEnum(ne)
+	s := &service{desc: &descriptor.ServiceDescriptorProto{}}
+	f2.addService(s)
+
+	assert.Equal(t, []Message{m, nm}, p.AllMessages())
+	assert.Equal(t, []Enum{e, ne}, p.AllEnums())
+	assert.Equal(t, []Service{s}, p.Services())
+	assert.Equal(t, []Extension{fext, mext}, p.DefinedExtensions())
+}
+
+func TestPackage_Imports(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPkg()
+	other := &pkg{fd: &descriptor.FileDescriptorProto{Package: proto.String("other")}}
+	another := &pkg{fd: &descriptor.FileDescriptorProto{Package: proto.String("another")}}
+
+	local, o1, o2, a := &file{}, &file{}, &file{}, &file{}
+	p.addFile(local)
+	other.addFile(o1)
+	other.addFile(o2)
+	another.addFile(a)
+
+	f := &file{}
+	p.addFile(f)
+	f.addFileDependency(local)
+	f.addFileDependency(o1)
+	f.addFileDependency(a)
+	f.addFileDependency(o2)
+
+	assert.Equal(t, []Package{other, another}, p.Imports())
+}
+
+func TestPackage_FileOptionConflicts(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPkg()
+	newFile := func(opts *descriptor.FileOptions) *file {
+		f := &file{desc: &descriptor.FileDescriptorProto{Options: opts}}
+		p.addFile(f)
+		return f
+	}
+
+	newFile(nil)
+	f1 := newFile(&descriptor.FileOptions{GoPackage: proto.String("foo"), JavaPackage: proto.String("com.foo")})
+	f2 := newFile(&descriptor.FileOptions{GoPackage: proto.String("foo")})
+	assert.Empty(t, p.FileOptionConflicts())
+
+	f3 := newFile(&descriptor.FileOptions{
+		GoPackage:          proto.String("bar"),
+		JavaPackage:        proto.String("com.bar"),
+		JavaOuterClassname: proto.String("Bar"),
+	})
+
+	conflicts := p.FileOptionConflicts()
+	require.Len(t, conflicts, 2)
+
+	assert.Equal(t, "go_package", conflicts[0].Option)
+	assert.Equal(t, map[string][]File{"foo": {f1, f2}, "bar": {f3}}, conflicts[0].Values)
+
+	assert.Equal(t, "java_package", conflicts[1].Option)
+	assert.Equal(t, map[string][]File{"com.foo": {f1}, "com.bar": {f3}}, conflicts[1].Values)
+}
+
 func dummyPkg() *pkg {
 	return &pkg{
 		fd: &descriptor.FileDescriptorProto{Package: proto.String("pkg_name")},
@@ -8,7 +8,10 @@ import (
 	"time"
 )
 
-const outputPathKey = "output_path"
+const (
+	outputPathKey = "output_path"
+	archiveKey    = "archive"
+)
 
 // Parameters provides a convenience for accessing and modifying the parameters
 // passed into the protoc-gen-star plugin.
@@ -51,6 +54,17 @@ func (p Parameters) OutputPath() string { return p.StrDefault(outputPathKey, "."
 // for overriding the behavior of the ImportPath at runtime.
 func (p Parameters) SetOutputPath(path string) { p.SetStr(outputPathKey, path) }
 
+// Archive returns the protoc-gen-star special parameter "archive". If set, all
+// generated files, including CustomFiles, are collected into a single archive
+// at this path instead of being emitted via protoc or written to the file
+// system. The format is determined by the extension: ".zip", ".tar", or
+// ".tar.gz" (".tgz"). Relative paths are resolved against the directory in
+// which protoc is executed.
+func (p Parameters) Archive() string { return p.Str(archiveKey) }
+
+// SetArchive sets the protoc-gen-star Archive parameter.
+func (p Parameters) SetArchive(path string) { p.SetStr(archiveKey, path) }
+
 // String satisfies the string.Stringer interface. This method returns p in the
 // format it is provided to the protoc execution. Output of this function is
 // always stable; parameters are sorted before the string is emitted.
@@ -16,6 +16,15 @@ func TestParameters_OutputPath(t *testing.T) {
 	assert.Equal(t, "foo", p.OutputPath())
 }
 
+func TestParameters_Archive(t *testing.T) {
+	t.Parallel()
+
+	p := Parameters{}
+	assert.Empty(t, p.Archive())
+	p.SetArchive("sdk.tar.gz")
+	assert.Equal(t, "sdk.tar.gz", p.Archive())
+}
+
 func TestParseParameters(t *testing.T) {
 	t.Parallel()
 
@@ -1,6 +1,10 @@
 package pgs
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,6 +18,8 @@ type persister interface {
 	SetDebugger(d Debugger)
 	SetFS(fs afero.Fs)
 	SetSupportedFeatures(f *uint64)
+	SetProvenance(pv *provenance)
+	SetArchive(path string)
 	AddPostProcessor(proc ...PostProcessor)
 	Persist(a ...Artifact) *plugin_go.CodeGeneratorResponse
 }
@@ -24,6 +30,8 @@ type stdPersister struct {
 	fs                afero.Fs
 	procs             []PostProcessor
 	supportedFeatures *uint64
+	provenance        *provenance
+	archive           *archive
 }
 
 func newPersister() *stdPersister { return &stdPersister{fs: afero.NewOsFs()} }
@@ -31,6 +39,8 @@ func newPersister() *stdPersister { return &stdPersister{fs: afero.NewOsFs()} }
 func (p *stdPersister) SetDebugger(d Debugger)                 { p.Debugger = d }
 func (p *stdPersister) SetFS(fs afero.Fs)                      { p.fs = fs }
 func (p *stdPersister) SetSupportedFeatures(f *uint64)         { p.supportedFeatures = f }
+func (p *stdPersister) SetProvenance(pv *provenance)           { p.provenance = pv }
+func (p *stdPersister) SetArchive(path string)                 { p.archive = newArchive(path) }
 func (p *stdPersister) AddPostProcessor(proc ...PostProcessor) { p.procs = append(p.procs, proc...) }
 
 func (p *stdPersister) Persist(arts ...Artifact) *plugin_go.CodeGeneratorResponse {
@@ -42,12 +52,17 @@ func (p *stdPersister) Persist(arts ...Artifact) *plugin_go.CodeGeneratorRespons
 		case GeneratorFile:
 			f, err := a.ProtoFile()
 			p.CheckErr(err, "unable to convert ", a.Name, " to proto")
-			f.Content = proto.String(p.postProcess(a, f.GetContent()))
+			p.finishFile(a, f)
 			p.insertFile(resp, f, a.Overwrite)
 		case GeneratorTemplateFile:
 			f, err := a.ProtoFile()
 			p.CheckErr(err, "unable to convert ", a.Name, " to proto")
-			f.Content = proto.String(p.postProcess(a, f.GetContent()))
+			p.finishFile(a, f)
+			p.insertFile(resp, f, a.Overwrite)
+		case GeneratorBytesFile:
+			f, err := a.ProtoFile()
+			p.CheckErr(err, "unable to convert ", a.Name, " to proto")
+			f.Content = proto.String(string(p.postProcessBinary(a, a.Contents)))
 			p.insertFile(resp, f, a.Overwrite)
 		case GeneratorAppend:
 			f, err := a.ProtoFile()
@@ -74,14 +89,27 @@ func (p *stdPersister) Persist(arts ...Artifact) *plugin_go.CodeGeneratorRespons
 		case CustomFile:
 			p.writeFile(
 				a.Name,
-				[]byte(p.postProcess(a, a.Contents)),
+				[]byte(p.provenance.apply(a.Name, p.postProcess(a, a.Contents))),
+				a.Overwrite,
+				a.Perms,
+			)
+		case CustomBytesFile:
+			p.writeFile(
+				a.Name,
+				p.postProcessBinary(a, a.Contents),
 				a.Overwrite,
 				a.Perms,
 			)
 		case CustomTemplateFile:
+			if a.Stream {
+				p.streamFile(a.Name, a.Overwrite, a.Perms, func(w io.Writer) error {
+					return a.Template.Execute(w, a.Data)
+				})
+				continue
+			}
 			content, err := a.render()
 			p.CheckErr(err, "unable to render CustomTemplateFile: ", a.Name)
-			content = p.postProcess(a, content)
+			content = p.provenance.apply(a.Name, p.postProcess(a, content))
 			p.writeFile(
 				a.Name,
 				[]byte(content),
@@ -99,6 +127,15 @@ func (p *stdPersister) Persist(arts ...Artifact) *plugin_go.CodeGeneratorRespons
 		}
 	}
 
+	p.CheckErr(validateInsertionPoints(resp), "invalid injection")
+	p.provenance.rehash(resp)
+
+	if p.archive != nil {
+		p.CheckErr(p.archive.addResponse(resp), "unable to archive generated files")
+		p.CheckErr(p.archive.write(p.fs), "unable to write archive:", p.archive.path)
+		p.archive.entries = nil
+	}
+
 	return resp
 }
 
@@ -157,6 +194,28 @@ func (p *stdPersister) insertAppend(resp *plugin_go.CodeGeneratorResponse,
 }
 
 func (p *stdPersister) writeFile(name string, content []byte, overwrite bool, perms os.FileMode) {
+	p.streamFile(name, overwrite, perms, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+}
+
+// streamFile creates the file name, unless it exists and overwrite is false,
+// and passes it to write to populate. If an archive is being produced, the
+// file is added to it instead.
+func (p *stdPersister) streamFile(name string, overwrite bool, perms os.FileMode, write func(w io.Writer) error) {
+	if p.archive != nil {
+		buf := &bytes.Buffer{}
+		p.CheckErr(write(buf), "unable to write file:", name)
+
+		added, err := p.archive.add(name, buf.Bytes(), perms, overwrite)
+		p.CheckErr(err, "unable to archive file:", name)
+		if !added {
+			p.Debug("file", name, "exists, skipping")
+		}
+		return
+	}
+
 	dir := filepath.Dir(name)
 	p.CheckErr(
 		p.fs.MkdirAll(dir, 0755),
@@ -173,20 +232,78 @@ func (p *stdPersister) writeFile(name string, content []byte, overwrite bool, pe
 		p.Debug("file", name, "exists, overwriting")
 	}
 
-	p.CheckErr(
-		afero.WriteFile(p.fs, name, content, perms),
-		"unable to write file:", name)
+	f, err := p.fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perms)
+	if err != nil {
+		p.CheckErr(err, "unable to write file:", name)
+		return
+	}
+
+	buf := bufio.NewWriter(f)
+	err = write(buf)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	p.CheckErr(err, "unable to write file:", name)
+}
+
+// finishFile applies the PostProcessors and provenance header to the content
+// of f, moving any GeneratedCodeInfo annotations to match.
+func (p *stdPersister) finishFile(a Artifact, f *plugin_go.CodeGeneratorResponse_File) {
+	before := f.GetContent()
+	after := p.postProcess(a, before)
+	content := p.provenance.apply(f.GetName(), after)
+
+	relocateAnnotations(f, before, after, len(content)-len(after))
+	f.Content = proto.String(content)
 }
 
 func (p *stdPersister) postProcess(a Artifact, in string) string {
-	var err error
-	b := []byte(in)
+	return string(p.process(a, []byte(in), false))
+}
+
+// postProcessBinary applies only the BinaryPostProcessors to the contents of a
+// binary Artifact.
+func (p *stdPersister) postProcessBinary(a Artifact, in []byte) []byte {
+	return p.process(a, in, true)
+}
+
+func (p *stdPersister) process(a Artifact, b []byte, binary bool) []byte {
+	skip, _ := a.(interface{ skipsPostProcessor(name string) bool })
+
 	for _, pp := range p.procs {
-		if pp.Match(a) {
-			b, err = pp.Process(b)
-			p.CheckErr(err, "failed post-processing")
+		if binary {
+			if bpp, ok := pp.(BinaryPostProcessor); !ok || !bpp.AcceptsBinary() {
+				continue
+			}
+		}
+
+		name := PostProcessorName(pp)
+		if skip != nil && skip.skipsPostProcessor(name) {
+			continue
+		}
+
+		if !pp.Match(a) {
+			continue
 		}
+
+		out, err := processArtifact(pp, a, b)
+		if err == nil {
+			b = out
+			continue
+		}
+
+		err = &PostProcessError{Artifact: ArtifactName(a), Processor: name, Input: b, Err: err}
+		if _, ok := pp.(bestEffort); ok || errors.Is(err, ErrPostProcessorSkipped) {
+			p.Logf("warning: %v\nkeeping unprocessed output", err)
+			continue
+		}
+
+		p.CheckErr(err, "failed post-processing")
 	}
 
-	return string(b)
+	return b
 }
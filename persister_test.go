@@ -325,6 +325,82 @@ func TestPersister_Persist_CustomTemplateFile(t *testing.T) {
 	assert.Equal(t, "buzz", string(b))
 }
 
+func TestPersister_Persist_BytesFiles(t *testing.T) {
+	t.Parallel()
+
+	bin := []byte{0x89, 'P', 'N', 'G', 0xff, 0x00}
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	fs := afero.NewMemMapFs()
+	p.SetFS(fs)
+	p.SetProvenance(dummyProvenance())
+	p.AddPostProcessor(
+		mockPP{match: true, out: []byte("text")},
+		mockBinaryPP{mockPP: mockPP{match: true, out: []byte("declined")}},
+		mockBinaryPP{mockPP: mockPP{match: false, out: []byte("unmatched")}, binary: true},
+	)
+
+	resp := p.Persist(
+		GeneratorBytesFile{Name: "foo.go", Contents: bin},
+		CustomBytesFile{Name: "/bar.go", Contents: bin, Perms: 0644},
+	)
+
+	assert.False(t, d.Failed())
+	assert.Len(t, resp.File, 1)
+	assert.Equal(t, bin, []byte(resp.File[0].GetContent()))
+
+	b, err := afero.ReadFile(fs, "/bar.go")
+	assert.NoError(t, err)
+	assert.Equal(t, bin, b)
+
+	p.AddPostProcessor(mockBinaryPP{mockPP: mockPP{match: true, out: []byte("binary")}, binary: true})
+
+	resp = p.Persist(
+		GeneratorBytesFile{Name: "foo.go", Contents: bin},
+		CustomBytesFile{Name: "/bar.go", Contents: bin, Overwrite: true},
+	)
+
+	assert.Equal(t, "binary", resp.File[0].GetContent())
+
+	b, err = afero.ReadFile(fs, "/bar.go")
+	assert.NoError(t, err)
+	assert.Equal(t, "binary", string(b))
+}
+
+func TestPersister_Persist_CustomTemplateFile_Stream(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	fs := afero.NewMemMapFs()
+	p.SetFS(fs)
+	p.SetProvenance(dummyProvenance())
+	p.AddPostProcessor(mockPP{match: true, out: []byte("processed")})
+
+	f := CustomTemplateFile{
+		Name:  "foo/bar/baz.go",
+		Perms: 0655,
+		TemplateArtifact: TemplateArtifact{
+			Template: genTpl,
+			Data:     "fizz",
+		},
+		Stream: true,
+	}
+
+	p.Persist(f)
+	assert.False(t, d.Failed())
+
+	b, err := afero.ReadFile(fs, "foo/bar/baz.go")
+	assert.NoError(t, err)
+	assert.Equal(t, "fizz", string(b))
+
+	f.TemplateArtifact = TemplateArtifact{Template: badArtifactTpl, Data: "buzz"}
+	f.Overwrite = true
+	p.Persist(f)
+	assert.Error(t, d.Err())
+}
+
 func TestPersister_AddPostProcessor(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,113 @@
+package pgs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// protocTabWidth is the column width protoc assumes for tab characters when
+// computing source spans.
+const protocTabWidth = 8
+
+// Position describes the location of an entity in its source proto file.
+// Lines and columns are 1-based. As with protoc, a tab advances the column to
+// the next multiple of 8.
+type Position struct {
+	// File is the path of the source file, relative to its import path.
+	File string
+
+	// StartLine and StartCol are the position of the first character.
+	StartLine, StartCol int
+
+	// EndLine and EndCol are the position of the last character.
+	EndLine, EndCol int
+}
+
+// newPosition decodes the 3- or 4-element span of a SourceCodeInfo_Location
+// into a Position. The zero Position (with File set) is returned if span is
+// malformed.
+func newPosition(file string, span []int32) Position {
+	p := Position{File: file}
+
+	switch len(span) {
+	case 3:
+		p.StartLine, p.StartCol = int(span[0])+1, int(span[1])+1
+		p.EndLine, p.EndCol = p.StartLine, int(span[2])
+	case 4:
+		p.StartLine, p.StartCol = int(span[0])+1, int(span[1])+1
+		p.EndLine, p.EndCol = int(span[2])+1, int(span[3])
+	}
+
+	return p
+}
+
+// IsValid returns true if the Position refers to a location in a file.
+func (p Position) IsValid() bool { return p.StartLine > 0 }
+
+// String returns the Position in the form "file:line:col", or just the file if
+// the Position is not valid.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return p.File
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.StartLine, p.StartCol)
+}
+
+// Snippet returns the source text spanned by the Position, read from File on
+// fs. Typically, fs should be rooted at the import path the proto file was
+// compiled from (see afero.NewBasePathFs).
+func (p Position) Snippet(fs afero.Fs) (string, error) {
+	if !p.IsValid() {
+		return "", errors.New("cannot extract snippet for an invalid position")
+	}
+
+	data, err := afero.ReadFile(fs, p.File)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if p.EndLine > len(lines) || p.StartLine > p.EndLine {
+		return "", fmt.Errorf("position %s is out of range of the source file", p)
+	}
+
+	start := protocColumnOffset(lines[p.StartLine-1], p.StartCol-1)
+	end := protocColumnOffset(lines[p.EndLine-1], p.EndCol)
+
+	if p.StartLine == p.EndLine {
+		if end < start {
+			return "", nil
+		}
+		return lines[p.StartLine-1][start:end], nil
+	}
+
+	buf := &strings.Builder{}
+	buf.WriteString(lines[p.StartLine-1][start:])
+	for _, l := range lines[p.StartLine : p.EndLine-1] {
+		buf.WriteString(l)
+	}
+	buf.WriteString(lines[p.EndLine-1][:end])
+
+	return buf.String(), nil
+}
+
+// protocColumnOffset converts the 0-based column col, as computed by protoc,
+// to a byte offset within line.
+func protocColumnOffset(line string, col int) int {
+	c := 0
+	for i := 0; i < len(line); i++ {
+		if c >= col {
+			return i
+		}
+
+		if line[i] == '\t' {
+			c += protocTabWidth - c%protocTabWidth
+		} else {
+			c++
+		}
+	}
+	return len(line)
+}
@@ -0,0 +1,93 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestNewPosition(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		Position{File: "f.proto", StartLine: 3, StartCol: 5, EndLine: 3, EndCol: 10},
+		newPosition("f.proto", []int32{2, 4, 10}))
+
+	assert.Equal(t,
+		Position{File: "f.proto", StartLine: 3, StartCol: 1, EndLine: 7, EndCol: 1},
+		newPosition("f.proto", []int32{2, 0, 6, 1}))
+
+	p := newPosition("f.proto", []int32{1})
+	assert.False(t, p.IsValid())
+	assert.Equal(t, "f.proto", p.String())
+}
+
+func TestPosition_String(t *testing.T) {
+	t.Parallel()
+
+	p := Position{File: "foo/bar.proto", StartLine: 12, StartCol: 3}
+	assert.True(t, p.IsValid())
+	assert.Equal(t, "foo/bar.proto:12:3", p.String())
+}
+
+func TestSourceCodeInfo_Position(t *testing.T) {
+	t.Parallel()
+
+	info := sci{
+		desc: &descriptor.SourceCodeInfo_Location{Span: []int32{0, 0, 5}},
+		file: "f.proto",
+	}
+
+	assert.Equal(t, Position{File: "f.proto", StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 5}, info.Position())
+}
+
+func TestPosition_Snippet(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	src := "syntax = \"proto3\";\n\nmessage Foo {\n\tint32 bar = 1 [deprecated = true];\n}\n"
+	require.NoError(t, afero.WriteFile(fs, "foo/foo.proto", []byte(src), 0644))
+
+	tests := []struct {
+		name string
+		span []int32
+		ex   string
+	}{
+		{"single line", []int32{0, 0, 18}, `syntax = "proto3";`},
+		{"multi line", []int32{2, 0, 4, 1}, "message Foo {\n\tint32 bar = 1 [deprecated = true];\n}"},
+		{"tab expanded", []int32{3, 8, 21}, "int32 bar = 1"},
+		{"option", []int32{3, 23, 40}, "deprecated = true"},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := newPosition("foo/foo.proto", tc.span).Snippet(fs)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ex, s)
+		})
+	}
+
+	_, err := Position{File: "foo/foo.proto"}.Snippet(fs)
+	assert.Error(t, err, "invalid position")
+
+	_, err = newPosition("foo/foo.proto", []int32{10, 0, 1}).Snippet(fs)
+	assert.Error(t, err, "out of range")
+
+	_, err = newPosition("missing.proto", []int32{0, 0, 1}).Snippet(fs)
+	assert.Error(t, err, "missing file")
+
+	info := sci{desc: &descriptor.SourceCodeInfo_Location{
+		Span:            []int32{2, 8, 11},
+		LeadingComments: proto.String("Foo"),
+	}, file: "foo/foo.proto"}
+	s, err := info.Position().Snippet(fs)
+	require.NoError(t, err)
+	assert.Equal(t, "Foo", s)
+}
@@ -1,5 +1,19 @@
 package pgs
 
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrPostProcessorSkipped is returned (possibly wrapped) by a PostProcessor
+// that could not be applied, but whose failure should not halt code
+// generation. The Artifact's output is left unchanged and a warning is logged.
+var ErrPostProcessorSkipped = errors.New("post-processor skipped")
+
 // A PostProcessor modifies the output of an Artifact before final rendering.
 type PostProcessor interface {
 	// Match returns true if the PostProcess should be applied to the Artifact.
@@ -10,3 +24,183 @@ type PostProcessor interface {
 	// an error if something goes wrong.
 	Process(in []byte) ([]byte, error)
 }
+
+// A BinaryPostProcessor is a PostProcessor that also handles the binary
+// contents of GeneratorBytesFile and CustomBytesFile Artifacts. Plain
+// PostProcessors are never applied to these Artifacts, as most expect text.
+type BinaryPostProcessor interface {
+	PostProcessor
+
+	// AcceptsBinary returns true if the PostProcessor should be offered binary
+	// Artifacts. Match is still called to select which ones to process.
+	AcceptsBinary() bool
+}
+
+// An ArtifactPostProcessor is a PostProcessor whose output depends on the
+// Artifact being processed, such as its file name. ProcessArtifact is called
+// in place of Process, so the PostProcessor need not retain the Artifact
+// passed to Match, and may safely be shared between concurrent generators.
+type ArtifactPostProcessor interface {
+	PostProcessor
+
+	// ProcessArtifact receives the Artifact and its rendered output, and returns
+	// the processed bytes or an error if something goes wrong.
+	ProcessArtifact(a Artifact, in []byte) ([]byte, error)
+}
+
+// processArtifact applies pp to the output in of Artifact a, using
+// ProcessArtifact if pp implements ArtifactPostProcessor.
+func processArtifact(pp PostProcessor, a Artifact, in []byte) ([]byte, error) {
+	if app, ok := pp.(ArtifactPostProcessor); ok {
+		return app.ProcessArtifact(a, in)
+	}
+	return pp.Process(in)
+}
+
+// A NamedPostProcessor is a PostProcessor with a name. The name identifies the
+// PostProcessor in errors, and is matched against the SkipPostProcessors of
+// an Artifact. PostProcessors without a name are identified by their type.
+type NamedPostProcessor interface {
+	PostProcessor
+
+	// Name returns the name of the PostProcessor, such as "gofmt".
+	Name() string
+}
+
+// PostProcessorName returns the name of the PostProcessor pp, which is either
+// the value of its Name method or its type.
+func PostProcessorName(pp PostProcessor) string {
+	if n, ok := pp.(NamedPostProcessor); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", pp)
+}
+
+// PostProcessing contains the shared options controlling which PostProcessors
+// are applied to an Artifact.
+type PostProcessing struct {
+	// SkipPostProcessors are the names of PostProcessors that should not be
+	// applied to the Artifact, even if they match it.
+	SkipPostProcessors []string
+}
+
+func (pp PostProcessing) skipsPostProcessor(name string) bool {
+	for _, n := range pp.SkipPostProcessors {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// BestEffort wraps the PostProcessor pp so that a failure does not halt code
+// generation. Instead, a warning is logged and the Artifact's output is kept
+// as it was before pp was applied.
+func BestEffort(pp PostProcessor) PostProcessor { return bestEffort{pp} }
+
+type bestEffort struct {
+	PostProcessor
+}
+
+func (be bestEffort) Name() string { return PostProcessorName(be.PostProcessor) }
+
+func (be bestEffort) ProcessArtifact(a Artifact, in []byte) ([]byte, error) {
+	return processArtifact(be.PostProcessor, a, in)
+}
+
+func (be bestEffort) AcceptsBinary() bool {
+	bpp, ok := be.PostProcessor.(BinaryPostProcessor)
+	return ok && bpp.AcceptsBinary()
+}
+
+// PostProcessError describes the failure of a PostProcessor against an
+// Artifact.
+type PostProcessError struct {
+	// Artifact is the name of the file the Artifact targets.
+	Artifact string
+
+	// Processor is the name of the failing PostProcessor.
+	Processor string
+
+	// Input is the output of the Artifact as it was passed to the failing
+	// PostProcessor, including the changes of any prior PostProcessors.
+	Input []byte
+
+	// Err is the error returned by the PostProcessor.
+	Err error
+}
+
+// postProcessErrorPos matches the line and column prefix of errors returned by
+// go/format and similar tools (eg, "12:5: expected ';'").
+var postProcessErrorPos = regexp.MustCompile(`(?:^|[^\d])(\d+):\d+:`)
+
+const postProcessErrorContext = 3
+
+// Error satisfies the error interface. The message includes an excerpt of the
+// Input, centered on the line reported by Err if it contains a position.
+func (e *PostProcessError) Error() string {
+	msg := fmt.Sprintf("post-processor %s failed on %s: %v", e.Processor, e.Artifact, e.Err)
+
+	if !utf8.Valid(e.Input) || len(e.Input) == 0 {
+		return msg
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(e.Input), "\n"), "\n")
+
+	target, start, end := 0, 0, 2*postProcessErrorContext+1
+	if m := postProcessErrorPos.FindStringSubmatch(e.Err.Error()); m != nil {
+		fmt.Sscan(m[1], &target)
+		start, end = target-1-postProcessErrorContext, target+postProcessErrorContext
+	}
+
+	if start < 0 {
+		start = 0
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	buf := bytes.NewBufferString(msg)
+	for i := start; i < end; i++ {
+		marker := " "
+		if i+1 == target {
+			marker = ">"
+		}
+		fmt.Fprintf(buf, "\n%s %4d | %s", marker, i+1, lines[i])
+	}
+
+	return buf.String()
+}
+
+// Unwrap returns the error returned by the PostProcessor.
+func (e *PostProcessError) Unwrap() error { return e.Err }
+
+// ArtifactName returns the name of the file targeted by Artifact a, including
+// the insertion point for injections. An empty string is returned for
+// Artifacts that do not target a file.
+func ArtifactName(a Artifact) string {
+	switch a := a.(type) {
+	case GeneratorFile:
+		return a.Name
+	case GeneratorBytesFile:
+		return a.Name
+	case GeneratorTemplateFile:
+		return a.Name
+	case GeneratorAppend:
+		return a.FileName
+	case GeneratorTemplateAppend:
+		return a.FileName
+	case GeneratorInjection:
+		return a.FileName + "@" + a.InsertionPoint
+	case GeneratorTemplateInjection:
+		return a.FileName + "@" + a.InsertionPoint
+	case CustomFile:
+		return a.Name
+	case CustomBytesFile:
+		return a.Name
+	case CustomTemplateFile:
+		return a.Name
+	default:
+		return ""
+	}
+}
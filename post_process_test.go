@@ -1,5 +1,15 @@
 package pgs
 
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
 type mockPP struct {
 	match bool
 	out   []byte
@@ -8,3 +18,151 @@ type mockPP struct {
 
 func (pp mockPP) Match(a Artifact) bool             { return pp.match }
 func (pp mockPP) Process(in []byte) ([]byte, error) { return pp.out, pp.err }
+
+type mockBinaryPP struct {
+	mockPP
+	binary bool
+}
+
+func (pp mockBinaryPP) AcceptsBinary() bool { return pp.binary }
+
+type mockNamedPP struct {
+	mockPP
+	name string
+}
+
+func (pp mockNamedPP) Name() string { return pp.name }
+
+func TestPostProcessorName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "pgs.mockPP", PostProcessorName(mockPP{}))
+	assert.Equal(t, "foo", PostProcessorName(mockNamedPP{name: "foo"}))
+	assert.Equal(t, "foo", PostProcessorName(BestEffort(mockNamedPP{name: "foo"})))
+}
+
+func TestBestEffort_AcceptsBinary(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, BestEffort(mockPP{}).(BinaryPostProcessor).AcceptsBinary())
+	assert.True(t, BestEffort(mockBinaryPP{binary: true}).(BinaryPostProcessor).AcceptsBinary())
+}
+
+func TestPostProcessError(t *testing.T) {
+	t.Parallel()
+
+	cause := errors.New("6:3: expected declaration")
+	err := &PostProcessError{
+		Artifact:  "foo.go",
+		Processor: "gofmt",
+		Input:     []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"),
+		Err:       cause,
+	}
+
+	assert.Equal(t, "post-processor gofmt failed on foo.go: 6:3: expected declaration\n"+
+		"     3 | 3\n"+
+		"     4 | 4\n"+
+		"     5 | 5\n"+
+		">    6 | 6\n"+
+		"     7 | 7\n"+
+		"     8 | 8\n"+
+		"     9 | 9", err.Error())
+	assert.True(t, errors.Is(err, cause))
+
+	err.Err = errors.New("bad")
+	err.Input = []byte("a\nb\n")
+	assert.Equal(t, "post-processor gofmt failed on foo.go: bad\n     1 | a\n     2 | b", err.Error())
+
+	err.Input = []byte{0xff}
+	assert.Equal(t, "post-processor gofmt failed on foo.go: bad", err.Error())
+}
+
+func TestArtifactName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a   Artifact
+		exp string
+	}{
+		{GeneratorFile{Name: "a"}, "a"},
+		{GeneratorBytesFile{Name: "a"}, "a"},
+		{GeneratorTemplateFile{Name: "a"}, "a"},
+		{GeneratorAppend{FileName: "a"}, "a"},
+		{GeneratorTemplateAppend{FileName: "a"}, "a"},
+		{GeneratorInjection{FileName: "a", InsertionPoint: "b"}, "a@b"},
+		{GeneratorTemplateInjection{FileName: "a", InsertionPoint: "b"}, "a@b"},
+		{CustomFile{Name: "a"}, "a"},
+		{CustomBytesFile{Name: "a"}, "a"},
+		{CustomTemplateFile{Name: "a"}, "a"},
+		{GeneratorError{Message: "a"}, ""},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.exp, ArtifactName(tc.a), "%T", tc.a)
+	}
+}
+
+func TestPersister_PostProcess_Chain(t *testing.T) {
+	t.Parallel()
+
+	t.Run("opt out", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		p := dummyPersister(d)
+		p.AddPostProcessor(
+			mockNamedPP{mockPP{match: true, out: []byte("fmt")}, "fmt"},
+			mockNamedPP{mockPP{match: true, err: errors.New("lint")}, "lint"},
+		)
+
+		resp := p.Persist(GeneratorFile{
+			Name:           "foo.go",
+			Contents:       "foo",
+			PostProcessing: PostProcessing{SkipPostProcessors: []string{"lint"}},
+		})
+
+		require.NoError(t, d.Err())
+		assert.Equal(t, "fmt", resp.File[0].GetContent())
+	})
+
+	t.Run("best effort", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		p := dummyPersister(d)
+		fs := afero.NewMemMapFs()
+		p.SetFS(fs)
+		p.AddPostProcessor(
+			mockNamedPP{mockPP{match: true, out: []byte("fmt")}, "fmt"},
+			BestEffort(mockNamedPP{mockPP{match: true, err: errors.New("1:1: oops")}, "lint"}),
+		)
+
+		p.Persist(CustomFile{Name: "foo.go", Contents: "foo"})
+
+		require.NoError(t, d.Err())
+		b, err := afero.ReadFile(fs, "foo.go")
+		require.NoError(t, err)
+		assert.Equal(t, "fmt", string(b))
+		out, _ := ioutil.ReadAll(d.Output())
+		assert.Contains(t, string(out), "warning: post-processor lint failed on foo.go: 1:1: oops")
+	})
+
+	t.Run("error context", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		p := dummyPersister(d)
+		p.AddPostProcessor(
+			mockNamedPP{mockPP{match: true, out: []byte("fmt")}, "fmt"},
+			mockNamedPP{mockPP{match: true, err: errors.New("lint")}, "lint"},
+		)
+
+		p.Persist(GeneratorFile{Name: "foo.go", Contents: "foo"})
+
+		var ppErr *PostProcessError
+		require.True(t, errors.As(d.Err(), &ppErr))
+		assert.Equal(t, "foo.go", ppErr.Artifact)
+		assert.Equal(t, "lint", ppErr.Processor)
+		assert.Equal(t, "fmt", string(ppErr.Input))
+	})
+}
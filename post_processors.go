@@ -0,0 +1,289 @@
+package pgs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// extMatcher matches the whole-file text Artifacts whose names have one of the
+// listed extensions (or base names, for files such as "Makefile"). If no
+// extensions are listed, every whole-file text Artifact is matched.
+type extMatcher []string
+
+func (m extMatcher) Match(a Artifact) bool {
+	switch a.(type) {
+	case GeneratorFile, GeneratorTemplateFile, CustomFile, CustomTemplateFile:
+	default:
+		return false
+	}
+
+	if len(m) == 0 {
+		return true
+	}
+
+	name := ArtifactName(a)
+	ext, base := filepath.Ext(name), filepath.Base(name)
+	for _, e := range m {
+		if strings.EqualFold(e, ext) || e == base {
+			return true
+		}
+	}
+
+	return false
+}
+
+func extsOrDefault(exts []string, def ...string) extMatcher {
+	if len(exts) == 0 {
+		return def
+	}
+	return exts
+}
+
+type jsonFormat struct {
+	extMatcher
+	canonical bool
+}
+
+// JSONFormat returns a PostProcessor that pretty-prints JSON files with a two
+// space indent, preserving the order of object keys. By default, files ending
+// in ".json" are matched.
+func JSONFormat(exts ...string) PostProcessor {
+	return jsonFormat{extMatcher: extsOrDefault(exts, ".json")}
+}
+
+// JSONCanonical returns a PostProcessor that canonicalizes JSON files: object
+// keys are sorted, HTML characters are left unescaped, and the output is
+// indented as with JSONFormat. Numbers are preserved exactly as written. By
+// default, files ending in ".json" are matched.
+func JSONCanonical(exts ...string) PostProcessor {
+	return jsonFormat{extMatcher: extsOrDefault(exts, ".json"), canonical: true}
+}
+
+func (p jsonFormat) Name() string {
+	if p.canonical {
+		return "json-canonical"
+	}
+	return "jsonfmt"
+}
+
+func (p jsonFormat) Process(in []byte) ([]byte, error) {
+	if p.canonical {
+		dec := json.NewDecoder(bytes.NewReader(in))
+		dec.UseNumber()
+
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			return nil, errors.New("unexpected data after top-level JSON value")
+		}
+
+		buf := &bytes.Buffer{}
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := json.Indent(buf, bytes.TrimSpace(in), "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+type yamlFormat struct{ extMatcher }
+
+// YAMLFormat returns a PostProcessor that normalizes YAML files, re-encoding
+// each document with a two space indent. Comments and key order are
+// preserved. By default, files ending in ".yaml" or ".yml" are matched.
+func YAMLFormat(exts ...string) PostProcessor {
+	return yamlFormat{extsOrDefault(exts, ".yaml", ".yml")}
+}
+
+func (p yamlFormat) Name() string { return "yamlfmt" }
+
+func (p yamlFormat) Process(in []byte) ([]byte, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(in))
+
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if err := enc.Encode(&doc); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type whitespace struct{ extMatcher }
+
+// NormalizeWhitespace returns a PostProcessor that removes trailing whitespace
+// from every line, and ensures non-empty files end with exactly one newline.
+// If no extensions are provided, all text files are matched.
+func NormalizeWhitespace(exts ...string) PostProcessor { return whitespace{exts} }
+
+func (p whitespace) Name() string { return "whitespace" }
+
+func (p whitespace) Process(in []byte) ([]byte, error) {
+	lines := bytes.Split(in, []byte("\n"))
+	for i, l := range lines {
+		cr := bytes.HasSuffix(l, []byte("\r")) && i < len(lines)-1
+		l = bytes.TrimRight(l, " \t\r\f\v")
+		if cr {
+			l = append(l, '\r')
+		}
+		lines[i] = l
+	}
+
+	out := bytes.TrimRight(bytes.Join(lines, []byte("\n")), "\r\n")
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	if bytes.Contains(in, []byte("\r\n")) {
+		return append(out, '\r', '\n'), nil
+	}
+	return append(out, '\n'), nil
+}
+
+type licenseHeader struct {
+	extMatcher
+	text string
+}
+
+// LicenseHeader returns a PostProcessor that prepends text as a comment to the
+// top of files, using the comment syntax of each file's extension. Files that
+// already begin with the header are left unchanged, and a leading shebang
+// line is preserved. If no extensions are provided, all text files with a
+// known comment syntax are matched. The returned PostProcessor implements
+// ArtifactPostProcessor, as the comment syntax depends on the Artifact.
+func LicenseHeader(text string, exts ...string) PostProcessor {
+	return licenseHeader{extMatcher: exts, text: text}
+}
+
+func (p licenseHeader) Name() string { return "license" }
+
+func (p licenseHeader) Match(a Artifact) bool {
+	if !p.extMatcher.Match(a) {
+		return false
+	}
+
+	_, ok := commentStyleFor(ArtifactName(a))
+	return ok
+}
+
+func (p licenseHeader) Process(in []byte) ([]byte, error) {
+	return nil, errors.New("license header requires the Artifact to select a comment style")
+}
+
+func (p licenseHeader) ProcessArtifact(a Artifact, in []byte) ([]byte, error) {
+	style, ok := commentStyleFor(ArtifactName(a))
+	if !ok {
+		return in, nil
+	}
+
+	lines := normalizeCommentLines(p.text)
+	if len(lines) == 0 {
+		return in, nil
+	}
+
+	// The lines are rendered verbatim, rather than wrapped as with Render, to
+	// keep the license text exactly as written.
+	buf := &strings.Builder{}
+	if style.Open != "" {
+		buf.WriteString(style.Indent + style.Open + "\n")
+	}
+	for _, l := range lines {
+		style.writeLine(buf, l)
+	}
+	if style.Close != "" {
+		buf.WriteString(style.Indent + style.Close + "\n")
+	}
+	buf.WriteByte('\n')
+	header := buf.String()
+
+	var shebang []byte
+	if bytes.HasPrefix(in, []byte("#!")) {
+		if i := bytes.IndexByte(in, '\n'); i >= 0 {
+			shebang, in = in[:i+1], in[i+1:]
+		}
+	}
+
+	if bytes.HasPrefix(in, []byte(header)) {
+		return append(shebang, in...), nil
+	}
+
+	out := make([]byte, 0, len(shebang)+len(header)+len(in))
+	out = append(out, shebang...)
+	out = append(out, header...)
+	return append(out, in...), nil
+}
+
+// commentStyleFor returns the line comment style for the file name, based on
+// its extension, which is matched case-insensitively, or its base name.
+func commentStyleFor(name string) (CommentStyle, bool) {
+	if style, ok := provenanceStyles[strings.ToLower(filepath.Ext(name))]; ok {
+		return style, true
+	}
+	style, ok := provenanceStyles[filepath.Base(name)]
+	return style, ok
+}
+
+// A LineEnding is the sequence that terminates each line of a file.
+type LineEnding string
+
+const (
+	// LF is the Unix line ending.
+	LF LineEnding = "\n"
+
+	// CRLF is the Windows line ending.
+	CRLF LineEnding = "\r\n"
+)
+
+type lineEndings struct {
+	extMatcher
+	eol LineEnding
+}
+
+// ConvertLineEndings returns a PostProcessor that converts all line endings in
+// files to eol. If no extensions are provided, all text files are matched.
+func ConvertLineEndings(eol LineEnding, exts ...string) PostProcessor {
+	return lineEndings{extMatcher: exts, eol: eol}
+}
+
+func (p lineEndings) Name() string { return "line-endings" }
+
+func (p lineEndings) Process(in []byte) ([]byte, error) {
+	out := bytes.ReplaceAll(in, []byte("\r\n"), []byte("\n"))
+	if p.eol == LF {
+		return out, nil
+	}
+	return bytes.ReplaceAll(out, []byte("\n"), []byte(p.eol)), nil
+}
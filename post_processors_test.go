@@ -0,0 +1,172 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtMatcher_Match(t *testing.T) {
+	t.Parallel()
+
+	m := extMatcher{".json", "Makefile"}
+
+	tests := []struct {
+		n string
+		a Artifact
+		m bool
+	}{
+		{"GenFile", GeneratorFile{Name: "foo.json"}, true},
+		{"GenFileUpper", GeneratorFile{Name: "FOO.JSON"}, true},
+		{"GenTplFile", GeneratorTemplateFile{Name: "foo.json"}, true},
+		{"CustomFile", CustomFile{Name: "foo/Makefile"}, true},
+		{"CustomTplFile", CustomTemplateFile{Name: "foo.json"}, true},
+		{"OtherExt", GeneratorFile{Name: "foo.go"}, false},
+		{"Append", GeneratorAppend{FileName: "foo.json"}, false},
+		{"Bytes", GeneratorBytesFile{Name: "foo.json"}, false},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.n, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.m, m.Match(tc.a))
+		})
+	}
+
+	assert.True(t, extMatcher(nil).Match(CustomFile{Name: "foo"}))
+}
+
+func TestJSONFormat(t *testing.T) {
+	t.Parallel()
+
+	pp := JSONFormat()
+	assert.Equal(t, "jsonfmt", PostProcessorName(pp))
+	assert.True(t, pp.Match(GeneratorFile{Name: "foo.json"}))
+	assert.False(t, JSONFormat(".jsonc").Match(GeneratorFile{Name: "foo.json"}))
+
+	out, err := pp.Process([]byte(` {"b":1,"a":[1.50,"<x>"]} `))
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"b\": 1,\n  \"a\": [\n    1.50,\n    \"<x>\"\n  ]\n}\n", string(out))
+
+	_, err = pp.Process([]byte(`{"a":`))
+	assert.Error(t, err)
+}
+
+func TestJSONCanonical(t *testing.T) {
+	t.Parallel()
+
+	pp := JSONCanonical()
+	assert.Equal(t, "json-canonical", PostProcessorName(pp))
+
+	out, err := pp.Process([]byte(`{"b":{"d":1,"c":2},"a":[1.50,"<x>",12345678901234567890]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"a\": [\n    1.50,\n    \"<x>\",\n    12345678901234567890\n  ],\n"+
+		"  \"b\": {\n    \"c\": 2,\n    \"d\": 1\n  }\n}\n", string(out))
+
+	_, err = pp.Process([]byte(`{} {}`))
+	assert.Error(t, err)
+
+	_, err = pp.Process([]byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestYAMLFormat(t *testing.T) {
+	t.Parallel()
+
+	pp := YAMLFormat()
+	assert.Equal(t, "yamlfmt", PostProcessorName(pp))
+	assert.True(t, pp.Match(CustomFile{Name: "foo.yml"}))
+	assert.True(t, pp.Match(CustomFile{Name: "foo.yaml"}))
+
+	out, err := pp.Process([]byte("# header\nb:    1\na:\n    - x   # note\n---\nc: true\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "# header\nb: 1\na:\n  - x # note\n---\nc: true\n", string(out))
+
+	_, err = pp.Process([]byte("a: [1"))
+	assert.Error(t, err)
+}
+
+func TestNormalizeWhitespace(t *testing.T) {
+	t.Parallel()
+
+	pp := NormalizeWhitespace()
+	assert.Equal(t, "whitespace", PostProcessorName(pp))
+	assert.True(t, pp.Match(GeneratorFile{Name: "foo.anything"}))
+
+	tests := []struct {
+		in, out string
+	}{
+		{"a  \nb\t\n\n\n", "a\nb\n"},
+		{"a", "a\n"},
+		{"a \r\nb\r\n\r\n", "a\r\nb\r\n"},
+		{" \n\n", ""},
+	}
+
+	for _, tc := range tests {
+		out, err := pp.Process([]byte(tc.in))
+		require.NoError(t, err)
+		assert.Equal(t, tc.out, string(out), "%q", tc.in)
+	}
+}
+
+func TestLicenseHeader(t *testing.T) {
+	t.Parallel()
+
+	pp := LicenseHeader("Copyright Acme.\nAll rights reserved.").(ArtifactPostProcessor)
+	assert.Equal(t, "license", PostProcessorName(pp))
+	assert.False(t, pp.Match(GeneratorFile{Name: "foo.json"}))
+	assert.False(t, pp.Match(GeneratorFile{Name: "foo.r"}))
+	assert.False(t, LicenseHeader("x", ".py").Match(GeneratorFile{Name: "foo.go"}))
+
+	_, err := pp.Process([]byte("package foo\n"))
+	assert.Error(t, err)
+
+	tests := []struct {
+		a       Artifact
+		in, out string
+	}{
+		{GeneratorFile{Name: "foo.go"}, "package foo\n", "// Copyright Acme.\n// All rights reserved.\n\npackage foo\n"},
+		{GeneratorFile{Name: "foo.go"}, "// Copyright Acme.\n// All rights reserved.\n\npackage foo\n", "// Copyright Acme.\n// All rights reserved.\n\npackage foo\n"},
+		{CustomFile{Name: "run.sh"}, "#!/bin/sh\necho hi\n", "#!/bin/sh\n# Copyright Acme.\n# All rights reserved.\n\necho hi\n"},
+		{CustomFile{Name: "foo.css"}, "a {}\n", "/*\n * Copyright Acme.\n * All rights reserved.\n */\n\na {}\n"},
+		{GeneratorFile{Name: "FOO.GO"}, "package foo\n", "// Copyright Acme.\n// All rights reserved.\n\npackage foo\n"},
+		{CustomFile{Name: "foo.Proto"}, "syntax = \"proto3\";\n", "// Copyright Acme.\n// All rights reserved.\n\nsyntax = \"proto3\";\n"},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(ArtifactName(tc.a), func(t *testing.T) {
+			t.Parallel()
+
+			require.True(t, pp.Match(tc.a))
+			out, err := pp.ProcessArtifact(tc.a, []byte(tc.in))
+			require.NoError(t, err)
+			assert.Equal(t, tc.out, string(out))
+		})
+	}
+
+	empty := LicenseHeader("").(ArtifactPostProcessor)
+	require.True(t, empty.Match(CustomFile{Name: "run.sh"}))
+	out, err := empty.ProcessArtifact(CustomFile{Name: "run.sh"}, []byte("echo hi\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "echo hi\n", string(out))
+}
+
+func TestConvertLineEndings(t *testing.T) {
+	t.Parallel()
+
+	pp := ConvertLineEndings(CRLF, ".bat")
+	assert.Equal(t, "line-endings", PostProcessorName(pp))
+	assert.True(t, pp.Match(CustomFile{Name: "run.bat"}))
+	assert.False(t, pp.Match(CustomFile{Name: "run.sh"}))
+
+	out, err := pp.Process([]byte("a\nb\r\nc"))
+	require.NoError(t, err)
+	assert.Equal(t, "a\r\nb\r\nc", string(out))
+
+	out, err = ConvertLineEndings(LF).Process([]byte("a\r\nb\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\n", string(out))
+}
@@ -18,6 +18,11 @@ const (
 	// Most of the field types in the generated go structs are value types.
 	// See: https://github.com/protocolbuffers/protobuf/blob/v3.17.0/docs/field_presence.md#presence-in-proto3-apis
 	Proto3 Syntax = "proto3"
+
+	// Editions syntax replaces the proto2 and proto3 syntax distinctions with
+	// per-entity features, such as field presence and message encoding.
+	// See: https://protobuf.dev/editions/overview/
+	Editions Syntax = "editions"
 )
 
 // SupportsRequiredPrefix returns true if s supports "optional" and
@@ -75,8 +80,8 @@ func (pl ProtoLabel) String() string {
 // and utility methods. It is a 1-to-1 conversion.
 type ProtoType descriptor.FieldDescriptorProto_Type
 
-// 1-to-1 mapping of FieldDescriptorProto_Type enum to ProtoType. While all are
-// listed here, group types are not supported by this library.
+// 1-to-1 mapping of FieldDescriptorProto_Type enum to ProtoType. Group types
+// are treated as embedded messages; see Field.IsGroup and Message.IsGroup.
 const (
 	DoubleT  = ProtoType(descriptor.FieldDescriptorProto_TYPE_DOUBLE)
 	FloatT   = ProtoType(descriptor.FieldDescriptorProto_TYPE_FLOAT)
@@ -0,0 +1,220 @@
+package pgs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+const provenanceHashKey = "content-hash: sha256:"
+
+// ErrNoProvenance is returned by VerifyProvenance if the content does not
+// contain a provenance header.
+var ErrNoProvenance = errors.New("no provenance header found")
+
+// provenanceStyles maps lowercase file extensions (or base names for files
+// without one) to the comment style used for their provenance header. Files not listed
+// here, such as JSON, are left without a header.
+var provenanceStyles = map[string]CommentStyle{}
+
+func init() {
+	register := func(style CommentStyle, exts ...string) {
+		for _, ext := range exts {
+			provenanceStyles[ext] = style
+		}
+	}
+
+	register(CommentStyle{Prefix: "//"}, ".go", ".java", ".kt", ".scala", ".js", ".jsx", ".ts", ".tsx",
+		".dart", ".c", ".cc", ".cpp", ".h", ".hh", ".hpp", ".cs", ".swift", ".rs", ".proto", ".php")
+	register(CommentStyle{Prefix: "#"}, ".py", ".pyi", ".rb", ".sh", ".bash", ".yaml", ".yml", ".toml",
+		".bzl", ".pl", "BUILD", "Makefile")
+	register(CommentStyle{Prefix: "--"}, ".sql", ".lua", ".hs")
+	register(CommentStyle{Open: "/*", Prefix: " *", Close: " */"}, ".css", ".scss")
+	register(CommentStyle{Open: "<!--", Close: "-->"}, ".html", ".xml", ".md")
+}
+
+// ProvenanceHeaders prepends a header to every GeneratorFile,
+// GeneratorTemplateFile, CustomFile, and CustomTemplateFile Artifact recording
+// how it was produced: the plugin name and version, the protoc version, the
+// proto files to generate, and the parameters. The first line of the header
+// is the `Code generated ... DO NOT EDIT.` marker recognized by Go tooling,
+// and the last records a hash of the remaining content, which can be checked
+// with VerifyProvenance to detect hand edits.
+//
+// The header is rendered using the comment syntax for the file's extension,
+// after all PostProcessors are applied. Files with an unknown extension, or a
+// format that does not support comments (such as JSON), are not modified. The
+// hash covers any GeneratorAppend and GeneratorInjection Artifacts applied to
+// a file in the same run; injections by other plugins are not included.
+func ProvenanceHeaders(plugin, version string) InitOption {
+	return func(g *Generator) {
+		g.provenance = &provenance{plugin: plugin, version: version}
+		g.persister.SetProvenance(g.provenance)
+	}
+}
+
+type provenance struct {
+	plugin, version string
+
+	compiler string
+	sources  []string
+	params   string
+}
+
+// setRequest records the details of the protoc execution from req and the
+// (possibly mutated) Parameters.
+func (pv *provenance) setRequest(req *plugin_go.CodeGeneratorRequest, params Parameters) {
+	pv.compiler = "(unknown)"
+	if v := req.GetCompilerVersion(); v != nil {
+		pv.compiler = fmt.Sprintf("v%d.%d.%d", v.GetMajor(), v.GetMinor(), v.GetPatch())
+		if s := v.GetSuffix(); s != "" {
+			pv.compiler += "-" + s
+		}
+	}
+
+	pv.sources = append([]string(nil), req.GetFileToGenerate()...)
+	sort.Strings(pv.sources)
+
+	pv.params = params.String()
+}
+
+// apply returns content with the provenance header prepended, if the file
+// name has a known comment syntax. A leading shebang line is preserved.
+func (pv *provenance) apply(name, content string) string {
+	if pv == nil {
+		return content
+	}
+
+	style, ok := commentStyleFor(name)
+	if !ok {
+		return content
+	}
+
+	var shebang string
+	if strings.HasPrefix(content, "#!") {
+		if i := strings.IndexByte(content, '\n'); i >= 0 {
+			shebang, content = content[:i+1], content[i+1:]
+		}
+	}
+
+	version := ""
+	if pv.version != "" {
+		version = " " + pv.version
+	}
+
+	lines := []string{
+		fmt.Sprintf("Code generated by %s%s. DO NOT EDIT.", pv.plugin, version),
+		"protoc: " + pv.compiler,
+		"source: " + strings.Join(pv.sources, ", "),
+	}
+	if pv.params != "" {
+		lines = append(lines, "parameters: "+pv.params)
+	}
+	lines = append(lines, provenanceHashKey+provenanceHash(content))
+
+	buf := &strings.Builder{}
+	buf.WriteString(shebang)
+
+	if style.Open != "" {
+		buf.WriteString(style.Open)
+		buf.WriteByte('\n')
+	}
+
+	for _, l := range lines {
+		if style.Prefix != "" {
+			buf.WriteString(style.Prefix)
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(l)
+		buf.WriteByte('\n')
+	}
+
+	if style.Close != "" {
+		buf.WriteString(style.Close)
+		buf.WriteByte('\n')
+	}
+
+	buf.WriteByte('\n')
+	buf.WriteString(content)
+
+	return buf.String()
+}
+
+// rehash updates the content hash in the header of each file in resp that is
+// modified by a later append or insertion in the same response, so the hash
+// covers the file as protoc assembles it. The hash has a fixed length, so the
+// offsets of any annotations are unaffected.
+func (pv *provenance) rehash(resp *plugin_go.CodeGeneratorResponse) {
+	if pv == nil {
+		return
+	}
+
+	files, _, err := assembleResponse(resp)
+	if err != nil {
+		// invalid insertions are reported by validateInsertionPoints
+		return
+	}
+
+	for _, f := range files {
+		if !f.modified {
+			continue
+		}
+		if _, ok := commentStyleFor(f.file.GetName()); !ok {
+			continue
+		}
+
+		content := f.file.GetContent()
+		hash, body, ok := provenanceOffsets(content)
+		if !ok {
+			continue
+		}
+
+		f.file.Content = proto.String(content[:hash] + provenanceHash(string(f.content[body:])) + content[hash+sha256.Size*2:])
+	}
+}
+
+// VerifyProvenance returns true if the content following the provenance
+// header of a generated file matches the hash recorded in the header, and
+// false if it has been edited since it was generated. ErrNoProvenance is
+// returned if the content does not contain a header.
+func VerifyProvenance(content string) (bool, error) {
+	hash, body, ok := provenanceOffsets(content)
+	if !ok {
+		return false, ErrNoProvenance
+	}
+
+	end := hash + strings.IndexAny(content[hash:], " \n")
+	return content[hash:end] == provenanceHash(content[body:]), nil
+}
+
+// provenanceOffsets returns the offsets of the hash recorded in the
+// provenance header of content, and of the content following the header.
+func provenanceOffsets(content string) (hash, body int, ok bool) {
+	i := strings.Index(content, provenanceHashKey)
+	if i < 0 {
+		return 0, 0, false
+	}
+
+	hash = i + len(provenanceHashKey)
+	rest := content[hash:]
+	if strings.IndexAny(rest, " \n") < 0 {
+		return 0, 0, false
+	}
+
+	if body = strings.Index(rest, "\n\n"); body < 0 {
+		return 0, 0, false
+	}
+
+	return hash, hash + body + 2, true
+}
+
+func provenanceHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,172 @@
+package pgs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+func dummyProvenance() *provenance {
+	pv := &provenance{plugin: "protoc-gen-foo", version: "v1.2.3"}
+	pv.setRequest(&plugin_go.CodeGeneratorRequest{
+		FileToGenerate: []string{"b.proto", "a.proto"},
+		CompilerVersion: &plugin_go.Version{
+			Major:  proto.Int32(3),
+			Minor:  proto.Int32(21),
+			Patch:  proto.Int32(1),
+			Suffix: proto.String("rc1"),
+		},
+	}, Parameters{"foo": "bar", "fizz": ""})
+	return pv
+}
+
+func TestProvenanceHeaders(t *testing.T) {
+	t.Parallel()
+
+	g := &Generator{persister: newPersister()}
+	ProvenanceHeaders("protoc-gen-foo", "v1")(g)
+
+	require.NotNil(t, g.provenance)
+	assert.Equal(t, "protoc-gen-foo", g.provenance.plugin)
+	assert.Equal(t, "v1", g.provenance.version)
+	assert.Equal(t, g.provenance, g.persister.(*stdPersister).provenance)
+}
+
+func TestProvenance_SetRequest(t *testing.T) {
+	t.Parallel()
+
+	pv := dummyProvenance()
+	assert.Equal(t, "v3.21.1-rc1", pv.compiler)
+	assert.Equal(t, []string{"a.proto", "b.proto"}, pv.sources)
+	assert.Equal(t, "fizz,foo=bar", pv.params)
+
+	pv.setRequest(&plugin_go.CodeGeneratorRequest{}, Parameters{})
+	assert.Equal(t, "(unknown)", pv.compiler)
+	assert.Empty(t, pv.params)
+}
+
+func TestProvenance_Apply(t *testing.T) {
+	t.Parallel()
+
+	pv := dummyProvenance()
+	body := "package foo\n"
+
+	out := pv.apply("foo/bar.pb.go", body)
+	assert.Equal(t, "// Code generated by protoc-gen-foo v1.2.3. DO NOT EDIT.\n"+
+		"// protoc: v3.21.1-rc1\n"+
+		"// source: a.proto, b.proto\n"+
+		"// parameters: fizz,foo=bar\n"+
+		"// content-hash: sha256:"+provenanceHash(body)+"\n"+
+		"\n"+
+		body, out)
+
+	tests := []struct {
+		name, prefix string
+	}{
+		{"foo.py", "# Code generated"},
+		{"BUILD", "# Code generated"},
+		{"foo.sql", "-- Code generated"},
+		{"foo.css", "/*\n * Code generated"},
+		{"foo.html", "<!--\nCode generated"},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Contains(t, pv.apply(tc.name, body), tc.prefix)
+		})
+	}
+
+	t.Run("shebang", func(t *testing.T) {
+		t.Parallel()
+		out := pv.apply("run.sh", "#!/bin/sh\necho hi\n")
+		assert.Contains(t, out, "#!/bin/sh\n# Code generated")
+		ok, err := VerifyProvenance(out)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, body, pv.apply("foo.json", body))
+		assert.Equal(t, body, (*provenance)(nil).apply("foo.go", body))
+	})
+}
+
+func TestVerifyProvenance(t *testing.T) {
+	t.Parallel()
+
+	out := dummyProvenance().apply("foo.css", "a { color: red; }\n\nb {}\n")
+
+	ok, err := VerifyProvenance(out)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = VerifyProvenance(out + "// edited\n")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = VerifyProvenance("package foo\n")
+	assert.Equal(t, ErrNoProvenance, err)
+}
+
+func TestPersister_Persist_Provenance(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	fs := afero.NewMemMapFs()
+	p.SetFS(fs)
+	p.SetProvenance(dummyProvenance())
+
+	resp := p.Persist(
+		GeneratorFile{Name: "foo.go", Contents: "package foo\n"},
+		GeneratorAppend{FileName: "foo.go", Contents: "// appended\n"},
+		CustomFile{Name: "/bar.py", Contents: "pass\n"},
+	)
+
+	require.Len(t, resp.File, 2)
+	assert.Contains(t, resp.File[0].GetContent(), "DO NOT EDIT.")
+	assert.Equal(t, "// appended\n", resp.File[1].GetContent())
+
+	b, err := afero.ReadFile(fs, "/bar.py")
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(b, []byte("# Code generated by protoc-gen-foo")))
+}
+
+func TestPersister_Persist_ProvenanceAssembled(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	p.SetProvenance(dummyProvenance())
+
+	resp := p.Persist(
+		GeneratorFile{Name: "foo.go", Contents: "package foo\n\n// @@protoc_insertion_point(body)\n"},
+		GeneratorAppend{FileName: "foo.go", Contents: "// appended\n"},
+		GeneratorInjection{FileName: "foo.go", InsertionPoint: "body", Contents: "var x int\n"},
+		GeneratorFile{Name: "bar.go", Contents: "package bar\n"},
+	)
+	require.False(t, d.Failed())
+	require.Len(t, resp.File, 4)
+
+	files, _, err := assembleResponse(resp)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	for _, f := range files {
+		ok, err := VerifyProvenance(string(f.content))
+		assert.NoError(t, err)
+		assert.True(t, ok, f.file.GetName())
+	}
+
+	ok, err := VerifyProvenance(resp.File[0].GetContent())
+	assert.NoError(t, err)
+	assert.False(t, ok, "hash should cover the assembled file")
+}
@@ -0,0 +1,126 @@
+package pgs
+
+import (
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/runtime/protoimpl"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// MaxFieldNumber is the largest field number permitted on a Message.
+	MaxFieldNumber int32 = 1<<29 - 1
+
+	// FirstReservedFieldNumber is the first field number in the range reserved
+	// for the protocol buffers implementation.
+	FirstReservedFieldNumber int32 = 19000
+
+	// LastReservedFieldNumber is the last field number in the range reserved
+	// for the protocol buffers implementation.
+	LastReservedFieldNumber int32 = 19999
+)
+
+// NumberRange describes a contiguous range of field numbers on a Message or
+// values on an Enum, such as a reserved range or an extension range.
+type NumberRange interface {
+	// Start returns the first number in the range (inclusive).
+	Start() int32
+
+	// End returns the number immediately following the range (exclusive). For
+	// Enum ranges ending at max, End saturates at math.MaxInt32; use Contains
+	// to test whether a number is a member of the range.
+	End() int32
+
+	// Contains returns true if n is a member of the range.
+	Contains(n int32) bool
+
+	// SourceCodeInfo returns the SourceCodeInfo associated with the range.
+	// This value is nil if the source code info was not provided by protoc.
+	SourceCodeInfo() SourceCodeInfo
+
+	addSourceCodeInfo(info SourceCodeInfo)
+}
+
+// ExtensionRange describes a range of field numbers on a Message that are
+// available for third-party extensions.
+type ExtensionRange interface {
+	NumberRange
+
+	// Descriptor returns the underlying proto descriptor for this range.
+	Descriptor() *descriptor.DescriptorProto_ExtensionRange
+
+	// Message returns the Message declaring this range.
+	Message() Message
+
+	// Extension extracts an extension from the range's options, described by
+	// desc and populates the value ext. See Entity.Extension for details.
+	Extension(desc *protoimpl.ExtensionInfo, ext interface{}) (ok bool, err error)
+}
+
+type numRange struct {
+	start, last int32 // both inclusive
+	info        SourceCodeInfo
+}
+
+// newExclusiveRange creates a range from an exclusive end, such as those on
+// the DescriptorProto reserved and extension ranges.
+func newExclusiveRange(start, end int32) numRange { return numRange{start: start, last: end - 1} }
+
+// newInclusiveRange creates a range from an inclusive end, such as those on
+// the EnumDescriptorProto reserved ranges.
+func newInclusiveRange(start, end int32) numRange { return numRange{start: start, last: end} }
+
+func (r *numRange) Start() int32                          { return r.start }
+func (r *numRange) Contains(n int32) bool                 { return n >= r.start && n <= r.last }
+func (r *numRange) SourceCodeInfo() SourceCodeInfo        { return r.info }
+func (r *numRange) addSourceCodeInfo(info SourceCodeInfo) { r.info = info }
+
+func (r *numRange) End() int32 {
+	if r.last == math.MaxInt32 {
+		return r.last
+	}
+	return r.last + 1
+}
+
+type extRange struct {
+	numRange
+
+	desc *descriptor.DescriptorProto_ExtensionRange
+	msg  Message
+}
+
+func (r *extRange) Descriptor() *descriptor.DescriptorProto_ExtensionRange { return r.desc }
+func (r *extRange) Message() Message                                       { return r.msg }
+
+func (r *extRange) Extension(desc *protoimpl.ExtensionInfo, ext interface{}) (bool, error) {
+	return extension(r.desc.GetOptions(), desc, &ext)
+}
+
+// nextAvailable returns the smallest number between min and max (inclusive)
+// that is not contained by any of the provided ranges. Zero is returned if
+// no such number exists.
+func nextAvailable(min, max int32, taken []numRange) int32 {
+	sort.Slice(taken, func(i, j int) bool { return taken[i].start < taken[j].start })
+
+	candidate := int64(min)
+	for _, r := range taken {
+		if int64(r.start) > candidate {
+			break
+		}
+		if next := int64(r.last) + 1; next > candidate {
+			candidate = next
+		}
+	}
+
+	if candidate > int64(max) {
+		return 0
+	}
+
+	return int32(candidate)
+}
+
+var (
+	_ NumberRange    = (*numRange)(nil)
+	_ ExtensionRange = (*extRange)(nil)
+)
@@ -0,0 +1,79 @@
+package pgs
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestNumRange(t *testing.T) {
+	t.Parallel()
+
+	r := newExclusiveRange(5, 10)
+	assert.Equal(t, int32(5), r.Start())
+	assert.Equal(t, int32(10), r.End())
+	assert.True(t, r.Contains(5))
+	assert.True(t, r.Contains(9))
+	assert.False(t, r.Contains(10))
+	assert.False(t, r.Contains(4))
+
+	r = newInclusiveRange(5, 10)
+	assert.Equal(t, int32(11), r.End())
+	assert.True(t, r.Contains(10))
+
+	r = newInclusiveRange(100, math.MaxInt32)
+	assert.Equal(t, int32(math.MaxInt32), r.End())
+	assert.True(t, r.Contains(math.MaxInt32))
+
+	assert.Nil(t, r.SourceCodeInfo())
+	info := sci{}
+	r.addSourceCodeInfo(info)
+	assert.Equal(t, info, r.SourceCodeInfo())
+}
+
+func TestExtRange(t *testing.T) {
+	t.Parallel()
+
+	m := dummyMsg()
+	r := &extRange{
+		numRange: newExclusiveRange(100, 200),
+		desc:     &descriptor.DescriptorProto_ExtensionRange{Start: proto.Int32(100), End: proto.Int32(200)},
+		msg:      m,
+	}
+
+	assert.Equal(t, m, r.Message())
+	assert.Equal(t, r.desc, r.Descriptor())
+
+	ok, err := r.Extension(nil, nil)
+	assert.False(t, ok)
+	assert.NoError(t, err)
+}
+
+func TestNextAvailable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		min, max int32
+		taken    []numRange
+		expected int32
+	}{
+		{"empty", 1, 10, nil, 1},
+		{"gap", 1, 10, []numRange{{start: 1, last: 1}, {start: 3, last: 3}}, 2},
+		{"unsorted", 1, 10, []numRange{{start: 2, last: 4}, {start: 1, last: 1}}, 5},
+		{"overlapping", 1, 10, []numRange{{start: 1, last: 5}, {start: 3, last: 7}}, 8},
+		{"exhausted", 1, 10, []numRange{{start: 1, last: 10}}, 0},
+		{"max int", 1, math.MaxInt32, []numRange{{start: 1, last: math.MaxInt32}}, 0},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, nextAvailable(tc.min, tc.max, tc.taken))
+		})
+	}
+}
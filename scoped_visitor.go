@@ -0,0 +1,244 @@
+package pgs
+
+// A WalkAction instructs WalkScoped how to proceed after entering a Node.
+type WalkAction int
+
+const (
+	// WalkContinue descends into the children of the current Node.
+	WalkContinue WalkAction = iota
+
+	// WalkSkipChildren does not descend into the children of the current Node,
+	// continuing on to its next sibling. The Leave hook is still called for the
+	// current Node.
+	WalkSkipChildren
+
+	// WalkSkipSiblings does not descend into the children of the current Node
+	// and skips any of its remaining siblings. The Leave hooks for the current
+	// Node and its parent are still called.
+	WalkSkipSiblings
+
+	// WalkStop immediately halts the walk. No further hooks are called,
+	// including the Leave hook for the current Node and its ancestors.
+	WalkStop
+)
+
+// A Cursor describes the position of a Node within a WalkScoped traversal.
+// A Cursor is only valid for the duration of the hook it is passed to.
+type Cursor interface {
+	// Node returns the current Node.
+	Node() Node
+
+	// Parent returns the parent of the current Node, or nil if the current Node
+	// is the root of the walk.
+	Parent() Node
+
+	// Ancestors returns the Nodes enclosing the current Node, starting with the
+	// root of the walk and ending with its Parent. The current Node is not
+	// included.
+	Ancestors() []Node
+
+	// Depth returns the number of ancestors of the current Node. The root of
+	// the walk has a depth of zero.
+	Depth() int
+}
+
+// A ScopedVisitor exposes Enter and Leave hooks for each Node type, walked in
+// a depth-first manner by WalkScoped. Enter hooks are called before any of
+// the Node's children are visited and return a WalkAction controlling how the
+// walk proceeds. Leave hooks are called after all of the Node's children have
+// been visited. Any error returned will immediately halt execution.
+type ScopedVisitor interface {
+	EnterPackage(Cursor, Package) (WalkAction, error)
+	LeavePackage(Cursor, Package) error
+
+	EnterFile(Cursor, File) (WalkAction, error)
+	LeaveFile(Cursor, File) error
+
+	EnterMessage(Cursor, Message) (WalkAction, error)
+	LeaveMessage(Cursor, Message) error
+
+	EnterEnum(Cursor, Enum) (WalkAction, error)
+	LeaveEnum(Cursor, Enum) error
+
+	EnterEnumValue(Cursor, EnumValue) (WalkAction, error)
+	LeaveEnumValue(Cursor, EnumValue) error
+
+	EnterField(Cursor, Field) (WalkAction, error)
+	LeaveField(Cursor, Field) error
+
+	EnterExtension(Cursor, Extension) (WalkAction, error)
+	LeaveExtension(Cursor, Extension) error
+
+	EnterOneOf(Cursor, OneOf) (WalkAction, error)
+	LeaveOneOf(Cursor, OneOf) error
+
+	EnterService(Cursor, Service) (WalkAction, error)
+	LeaveService(Cursor, Service) error
+
+	EnterMethod(Cursor, Method) (WalkAction, error)
+	LeaveMethod(Cursor, Method) error
+}
+
+// WalkScoped applies a depth-first visitor pattern with v against Node n,
+// calling the Enter hook for each Node before its children and the Leave hook
+// after. Children are visited in the same order as Walk, and the same
+// WalkOptions are supported.
+func WalkScoped(v ScopedVisitor, n Node, opts ...WalkOption) error {
+	w := &scopedWalker{v: v, opts: newWalkOptions(opts)}
+	_, err := w.walk(n)
+	return err
+}
+
+type scopedWalker struct {
+	v     ScopedVisitor
+	opts  *walkOptions
+	stack []Node
+}
+
+func (w *scopedWalker) walk(n Node) (act WalkAction, err error) {
+	if !w.opts.include(n) {
+		return WalkContinue, nil
+	}
+
+	c := cursor{n: n, w: w}
+
+	if act, err = w.enter(c); err != nil || act == WalkStop {
+		return WalkStop, err
+	}
+
+	if act == WalkContinue {
+		w.stack = append(w.stack, n)
+
+		for _, ch := range w.opts.children(n) {
+			var chAct WalkAction
+			if chAct, err = w.walk(ch); err != nil || chAct == WalkStop {
+				return WalkStop, err
+			}
+
+			if chAct == WalkSkipSiblings {
+				break
+			}
+		}
+
+		w.stack = w.stack[:len(w.stack)-1]
+	}
+
+	if err = w.leave(c); err != nil {
+		return WalkStop, err
+	}
+
+	return act, nil
+}
+
+func (w *scopedWalker) enter(c cursor) (WalkAction, error) {
+	switch n := c.n.(type) {
+	case Package:
+		return w.v.EnterPackage(c, n)
+	case File:
+		return w.v.EnterFile(c, n)
+	case Message:
+		return w.v.EnterMessage(c, n)
+	case Enum:
+		return w.v.EnterEnum(c, n)
+	case EnumValue:
+		return w.v.EnterEnumValue(c, n)
+	case Extension:
+		return w.v.EnterExtension(c, n)
+	case Field:
+		return w.v.EnterField(c, n)
+	case OneOf:
+		return w.v.EnterOneOf(c, n)
+	case Service:
+		return w.v.EnterService(c, n)
+	case Method:
+		return w.v.EnterMethod(c, n)
+	default:
+		return WalkSkipChildren, nil
+	}
+}
+
+func (w *scopedWalker) leave(c cursor) error {
+	switch n := c.n.(type) {
+	case Package:
+		return w.v.LeavePackage(c, n)
+	case File:
+		return w.v.LeaveFile(c, n)
+	case Message:
+		return w.v.LeaveMessage(c, n)
+	case Enum:
+		return w.v.LeaveEnum(c, n)
+	case EnumValue:
+		return w.v.LeaveEnumValue(c, n)
+	case Extension:
+		return w.v.LeaveExtension(c, n)
+	case Field:
+		return w.v.LeaveField(c, n)
+	case OneOf:
+		return w.v.LeaveOneOf(c, n)
+	case Service:
+		return w.v.LeaveService(c, n)
+	case Method:
+		return w.v.LeaveMethod(c, n)
+	default:
+		return nil
+	}
+}
+
+type cursor struct {
+	n Node
+	w *scopedWalker
+}
+
+func (c cursor) Node() Node { return c.n }
+func (c cursor) Depth() int { return len(c.w.stack) }
+
+func (c cursor) Parent() Node {
+	if len(c.w.stack) == 0 {
+		return nil
+	}
+	return c.w.stack[len(c.w.stack)-1]
+}
+
+func (c cursor) Ancestors() []Node {
+	out := make([]Node, len(c.w.stack))
+	copy(out, c.w.stack)
+	return out
+}
+
+type nopScopedVisitor struct{}
+
+// NopScopedVisitor returns a ScopedVisitor whose Enter hooks always respond
+// with (WalkContinue, nil) and whose Leave hooks always return nil. This is
+// useful as an anonymous embedded struct to satisfy the ScopedVisitor
+// interface for implementations that only require some of the hooks.
+func NopScopedVisitor() ScopedVisitor { return nopScopedVisitor{} }
+
+func (nopScopedVisitor) EnterPackage(Cursor, Package) (WalkAction, error) { return WalkContinue, nil }
+func (nopScopedVisitor) LeavePackage(Cursor, Package) error               { return nil }
+func (nopScopedVisitor) EnterFile(Cursor, File) (WalkAction, error)       { return WalkContinue, nil }
+func (nopScopedVisitor) LeaveFile(Cursor, File) error                     { return nil }
+func (nopScopedVisitor) EnterMessage(Cursor, Message) (WalkAction, error) { return WalkContinue, nil }
+func (nopScopedVisitor) LeaveMessage(Cursor, Message) error               { return nil }
+func (nopScopedVisitor) EnterEnum(Cursor, Enum) (WalkAction, error)       { return WalkContinue, nil }
+func (nopScopedVisitor) LeaveEnum(Cursor, Enum) error                     { return nil }
+func (nopScopedVisitor) EnterEnumValue(Cursor, EnumValue) (WalkAction, error) {
+	return WalkContinue, nil
+}
+func (nopScopedVisitor) LeaveEnumValue(Cursor, EnumValue) error       { return nil }
+func (nopScopedVisitor) EnterField(Cursor, Field) (WalkAction, error) { return WalkContinue, nil }
+func (nopScopedVisitor) LeaveField(Cursor, Field) error               { return nil }
+func (nopScopedVisitor) EnterExtension(Cursor, Extension) (WalkAction, error) {
+	return WalkContinue, nil
+}
+func (nopScopedVisitor) LeaveExtension(Cursor, Extension) error           { return nil }
+func (nopScopedVisitor) EnterOneOf(Cursor, OneOf) (WalkAction, error)     { return WalkContinue, nil }
+func (nopScopedVisitor) LeaveOneOf(Cursor, OneOf) error                   { return nil }
+func (nopScopedVisitor) EnterService(Cursor, Service) (WalkAction, error) { return WalkContinue, nil }
+func (nopScopedVisitor) LeaveService(Cursor, Service) error               { return nil }
+func (nopScopedVisitor) EnterMethod(Cursor, Method) (WalkAction, error)   { return WalkContinue, nil }
+func (nopScopedVisitor) LeaveMethod(Cursor, Method) error                 { return nil }
+
+var (
+	_ ScopedVisitor = nopScopedVisitor{}
+	_ Cursor        = cursor{}
+)
@@ -0,0 +1,244 @@
+package pgs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+type recordingScopedVisitor struct {
+	ScopedVisitor
+
+	log     []string
+	actions map[string]WalkAction
+	err     error
+}
+
+func (v *recordingScopedVisitor) enter(c Cursor, n Name) (WalkAction, error) {
+	v.log = append(v.log, fmt.Sprintf("enter %s %d", n, c.Depth()))
+	return v.actions[n.String()], v.err
+}
+
+func (v *recordingScopedVisitor) leave(c Cursor, n Name) error {
+	v.log = append(v.log, fmt.Sprintf("leave %s %d", n, c.Depth()))
+	return nil
+}
+
+func (v *recordingScopedVisitor) EnterFile(c Cursor, f File) (WalkAction, error) {
+	return v.enter(c, f.Name())
+}
+
+func (v *recordingScopedVisitor) LeaveFile(c Cursor, f File) error { return v.leave(c, f.Name()) }
+
+func (v *recordingScopedVisitor) EnterMessage(c Cursor, m Message) (WalkAction, error) {
+	return v.enter(c, m.Name())
+}
+
+func (v *recordingScopedVisitor) LeaveMessage(c Cursor, m Message) error {
+	return v.leave(c, m.Name())
+}
+
+func (v *recordingScopedVisitor) EnterField(c Cursor, f Field) (WalkAction, error) {
+	return v.enter(c, f.Name())
+}
+
+func (v *recordingScopedVisitor) LeaveField(c Cursor, f Field) error { return v.leave(c, f.Name()) }
+
+func dummyScopedTree() *file {
+	f := dummyFile()
+
+	outer := &msg{desc: &descriptor.DescriptorProto{Name: proto.String("Outer")}}
+	inner := &msg{desc: &descriptor.DescriptorProto{Name: proto.String("Inner")}}
+	other := &msg{desc: &descriptor.DescriptorProto{Name: proto.String("Other")}}
+
+	inner.addField(&field{desc: &descriptor.FieldDescriptorProto{Name: proto.String("a")}})
+	inner.addField(&field{desc: &descriptor.FieldDescriptorProto{Name: proto.String("b")}})
+	outer.addMessage(inner)
+	outer.addField(&field{desc: &descriptor.FieldDescriptorProto{Name: proto.String("c")}})
+
+	f.addMessage(outer)
+	f.addMessage(other)
+
+	return f
+}
+
+func TestWalkScoped(t *testing.T) {
+	t.Parallel()
+
+	v := &recordingScopedVisitor{ScopedVisitor: NopScopedVisitor()}
+	require.NoError(t, WalkScoped(v, dummyScopedTree()))
+
+	assert.Equal(t, []string{
+		"enter file.proto 0",
+		"enter Outer 1",
+		"enter Inner 2",
+		"enter a 3",
+		"leave a 3",
+		"enter b 3",
+		"leave b 3",
+		"leave Inner 2",
+		"enter c 2",
+		"leave c 2",
+		"leave Outer 1",
+		"enter Other 1",
+		"leave Other 1",
+		"leave file.proto 0",
+	}, v.log)
+}
+
+func TestWalkScoped_Actions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		actions  map[string]WalkAction
+		expected []string
+	}{
+		{
+			name:    "skip children",
+			actions: map[string]WalkAction{"Inner": WalkSkipChildren},
+			expected: []string{
+				"enter file.proto 0",
+				"enter Outer 1",
+				"enter Inner 2",
+				"leave Inner 2",
+				"enter c 2",
+				"leave c 2",
+				"leave Outer 1",
+				"enter Other 1",
+				"leave Other 1",
+				"leave file.proto 0",
+			},
+		},
+		{
+			name:    "skip siblings",
+			actions: map[string]WalkAction{"Outer": WalkSkipSiblings},
+			expected: []string{
+				"enter file.proto 0",
+				"enter Outer 1",
+				"leave Outer 1",
+				"leave file.proto 0",
+			},
+		},
+		{
+			name:    "skip siblings nested",
+			actions: map[string]WalkAction{"a": WalkSkipSiblings},
+			expected: []string{
+				"enter file.proto 0",
+				"enter Outer 1",
+				"enter Inner 2",
+				"enter a 3",
+				"leave a 3",
+				"leave Inner 2",
+				"enter c 2",
+				"leave c 2",
+				"leave Outer 1",
+				"enter Other 1",
+				"leave Other 1",
+				"leave file.proto 0",
+			},
+		},
+		{
+			name:    "stop",
+			actions: map[string]WalkAction{"a": WalkStop},
+			expected: []string{
+				"enter file.proto 0",
+				"enter Outer 1",
+				"enter Inner 2",
+				"enter a 3",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := &recordingScopedVisitor{ScopedVisitor: NopScopedVisitor(), actions: tc.actions}
+			require.NoError(t, WalkScoped(v, dummyScopedTree()))
+			assert.Equal(t, tc.expected, v.log)
+		})
+	}
+}
+
+func TestWalkScoped_Error(t *testing.T) {
+	t.Parallel()
+
+	e := errors.New("TestWalkScoped_Error")
+	v := &recordingScopedVisitor{ScopedVisitor: NopScopedVisitor(), err: e}
+
+	assert.Equal(t, e, WalkScoped(v, dummyScopedTree()))
+	assert.Equal(t, []string{"enter file.proto 0"}, v.log)
+}
+
+type cursorScopedVisitor struct {
+	ScopedVisitor
+
+	cursor []Node
+	parent Node
+}
+
+func (v *cursorScopedVisitor) EnterField(c Cursor, f Field) (WalkAction, error) {
+	v.cursor = c.Ancestors()
+	v.parent = c.Parent()
+	return WalkContinue, nil
+}
+
+func TestCursor(t *testing.T) {
+	t.Parallel()
+
+	f := dummyScopedTree()
+	v := &cursorScopedVisitor{ScopedVisitor: NopScopedVisitor()}
+	require.NoError(t, WalkScoped(v, f.Package()))
+
+	outer := f.Messages()[0]
+	assert.Equal(t, []Node{f.Package(), f, outer}, v.cursor)
+	assert.Equal(t, outer, v.parent)
+
+	c := cursor{n: f, w: &scopedWalker{}}
+	assert.Equal(t, f, c.Node())
+	assert.Nil(t, c.Parent())
+	assert.Empty(t, c.Ancestors())
+	assert.Zero(t, c.Depth())
+}
+
+func TestNopScopedVisitor(t *testing.T) {
+	t.Parallel()
+
+	nv := NopScopedVisitor()
+	c := cursor{w: &scopedWalker{}}
+
+	for _, fn := range []func() (WalkAction, error){
+		func() (WalkAction, error) { return nv.EnterPackage(c, &pkg{}) },
+		func() (WalkAction, error) { return nv.EnterFile(c, &file{}) },
+		func() (WalkAction, error) { return nv.EnterMessage(c, &msg{}) },
+		func() (WalkAction, error) { return nv.EnterEnum(c, &enum{}) },
+		func() (WalkAction, error) { return nv.EnterEnumValue(c, &enumVal{}) },
+		func() (WalkAction, error) { return nv.EnterField(c, &field{}) },
+		func() (WalkAction, error) { return nv.EnterExtension(c, &ext{}) },
+		func() (WalkAction, error) { return nv.EnterOneOf(c, &oneof{}) },
+		func() (WalkAction, error) { return nv.EnterService(c, &service{}) },
+		func() (WalkAction, error) { return nv.EnterMethod(c, &method{}) },
+	} {
+		act, err := fn()
+		assert.Equal(t, WalkContinue, act)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, nv.LeavePackage(c, &pkg{}))
+	assert.NoError(t, nv.LeaveFile(c, &file{}))
+	assert.NoError(t, nv.LeaveMessage(c, &msg{}))
+	assert.NoError(t, nv.LeaveEnum(c, &enum{}))
+	assert.NoError(t, nv.LeaveEnumValue(c, &enumVal{}))
+	assert.NoError(t, nv.LeaveField(c, &field{}))
+	assert.NoError(t, nv.LeaveExtension(c, &ext{}))
+	assert.NoError(t, nv.LeaveOneOf(c, &oneof{}))
+	assert.NoError(t, nv.LeaveService(c, &service{}))
+	assert.NoError(t, nv.LeaveMethod(c, &method{}))
+}
@@ -25,7 +25,8 @@ type service struct {
 	file    File
 	fqn     string
 
-	info SourceCodeInfo
+	info    SourceCodeInfo
+	optInfo optionLocations
 }
 
 func (s *service) Name() Name                                     { return Name(s.desc.GetName()) }
@@ -34,6 +35,7 @@ func (s *service) Syntax() Syntax                                 { return s.fil
 func (s *service) Package() Package                               { return s.file.Package() }
 func (s *service) File() File                                     { return s.file }
 func (s *service) BuildTarget() bool                              { return s.file.BuildTarget() }
+func (s *service) Deprecated() bool                               { return s.desc.GetOptions().GetDeprecated() }
 func (s *service) SourceCodeInfo() SourceCodeInfo                 { return s.info }
 func (s *service) Descriptor() *descriptor.ServiceDescriptorProto { return s.desc }
 
@@ -101,4 +103,12 @@ func (s *service) childAtPath(path []int32) Entity {
 
 func (s *service) addSourceCodeInfo(info SourceCodeInfo) { s.info = info }
 
+func (s *service) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return s.optInfo.lookup(path)
+}
+
+func (s *service) addOptionSourceCodeInfo(path []int32, info SourceCodeInfo) {
+	s.optInfo = append(s.optInfo, optionLocation{path: path, info: info})
+}
+
 var _ Service = (*service)(nil)
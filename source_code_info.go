@@ -9,18 +9,22 @@ const (
 	messageTypePath           int32 = 4  // FileDescriptorProto.MessageType
 	enumTypePath              int32 = 5  // FileDescriptorProto.EnumType
 	servicePath               int32 = 6  // FileDescriptorProto.Service
+	extensionPath             int32 = 7  // FileDescriptorProto.Extension
 	syntaxPath                int32 = 12 // FileDescriptorProto.Syntax
 	messageTypeFieldPath      int32 = 2  // DescriptorProto.Field
 	messageTypeNestedTypePath int32 = 3  // DescriptorProto.NestedType
 	messageTypeEnumTypePath   int32 = 4  // DescriptorProto.EnumType
+	messageTypeExtRangePath   int32 = 5  // DescriptorProto.ExtensionRange
+	messageTypeExtensionPath  int32 = 6  // DescriptorProto.Extension
 	messageTypeOneofDeclPath  int32 = 8  // DescriptorProto.OneofDecl
+	messageTypeReservedPath   int32 = 9  // DescriptorProto.ReservedRange
 	enumTypeValuePath         int32 = 2  // EnumDescriptorProto.Value
+	enumTypeReservedPath      int32 = 4  // EnumDescriptorProto.ReservedRange
 	serviceTypeMethodPath     int32 = 2  // ServiceDescriptorProto.Method
 )
 
-// SourceCodeInfo represents data about an entity from the source. Currently
-// this only contains information about comments protoc associates with
-// entities.
+// SourceCodeInfo represents data about an entity from the source, including
+// its position in the file and the comments protoc associates with it.
 //
 // All comments have their // or /* */ stripped by protoc. See the
 // SourceCodeInfo documentation for more details about how comments are
@@ -42,10 +46,27 @@ type SourceCodeInfo interface {
 	// a leading comment for another entity, it won't be considered a trailing
 	// comment.
 	TrailingComments() string
+
+	// Directives parses the Directives with the given prefix from the leading
+	// and trailing comments, returning them along with the comment text with
+	// the directive lines removed. Lines starting with the prefix that are not
+	// well-formed directives, such as a "+ item" bullet with the prefix "+",
+	// are left in the comment text. An error is only returned if the prefix is
+	// empty.
+	Directives(prefix string) (Directives, error)
+
+	// StrictDirectives is like Directives, but returns a DirectiveError for the
+	// first line starting with the prefix that is not a well-formed directive.
+	StrictDirectives(prefix string) (Directives, error)
+
+	// Position returns the decoded span of the Location, along with the path
+	// of the file containing it.
+	Position() Position
 }
 
 type sci struct {
 	desc *descriptor.SourceCodeInfo_Location
+	file string
 }
 
 func (info sci) Location() *descriptor.SourceCodeInfo_Location { return info.desc }
@@ -53,4 +74,14 @@ func (info sci) LeadingComments() string                       { return info.des
 func (info sci) LeadingDetachedComments() []string             { return info.desc.GetLeadingDetachedComments() }
 func (info sci) TrailingComments() string                      { return info.desc.GetTrailingComments() }
 
+func (info sci) Position() Position { return newPosition(info.file, info.desc.GetSpan()) }
+
+func (info sci) Directives(prefix string) (Directives, error) {
+	return parseDirectives(info.desc, prefix, false)
+}
+
+func (info sci) StrictDirectives(prefix string) (Directives, error) {
+	return parseDirectives(info.desc, prefix, true)
+}
+
 var _ SourceCodeInfo = sci{}
@@ -17,7 +17,7 @@ func TestSourceCodeInfo(t *testing.T) {
 		LeadingDetachedComments: []string{"detached"},
 	}
 
-	info := sci{desc}
+	info := sci{desc: desc}
 
 	assert.Equal(t, desc, info.Location())
 	assert.Equal(t, "leading", info.LeadingComments())
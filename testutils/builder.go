@@ -0,0 +1,548 @@
+package testutils
+
+import (
+	pgs "github.com/lyft/protoc-gen-star/v2"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	packagePath     int32 = 2 // FileDescriptorProto.Package
+	messageTypePath int32 = 4 // FileDescriptorProto.MessageType
+	enumTypePath    int32 = 5 // FileDescriptorProto.EnumType
+	servicePath     int32 = 6 // FileDescriptorProto.Service
+	extensionPath   int32 = 7 // FileDescriptorProto.Extension
+	msgFieldPath    int32 = 2 // DescriptorProto.Field
+	msgNestedPath   int32 = 3 // DescriptorProto.NestedType
+	msgEnumPath     int32 = 4 // DescriptorProto.EnumType
+	msgExtPath      int32 = 6 // DescriptorProto.Extension
+	msgOneofPath    int32 = 8 // DescriptorProto.OneofDecl
+	enumValuePath   int32 = 2 // EnumDescriptorProto.Value
+	svcMethodPath   int32 = 2 // ServiceDescriptorProto.Method
+)
+
+// BuildSet returns a FileDescriptorSet containing the files constructed by
+// each FileBuilder, in order. Files must be provided after any files they
+// import. The output can be passed directly to pgs.ProcessFileDescriptorSet.
+func BuildSet(files ...*FileBuilder) *descriptor.FileDescriptorSet {
+	fdset := &descriptor.FileDescriptorSet{File: make([]*descriptor.FileDescriptorProto, len(files))}
+	for i, f := range files {
+		fdset.File[i] = f.Build()
+	}
+	return fdset
+}
+
+// LoadFiles resolves an AST from the files constructed by each FileBuilder, as
+// if by BuildSet. The test/benchmark is fatally stopped if there is any error.
+func (l Loader) LoadFiles(t T, files ...*FileBuilder) pgs.AST {
+	return l.loadFDSet(t, BuildSet(files...))
+}
+
+// sourceInfo accumulates the SourceCodeInfo for a FileBuilder. Each declared
+// entity is given a location on its own line, in declaration order.
+type sourceInfo struct {
+	info *descriptor.SourceCodeInfo
+}
+
+func (s *sourceInfo) add(path []int32, name string) *descriptor.SourceCodeInfo_Location {
+	loc := &descriptor.SourceCodeInfo_Location{
+		Path: append([]int32(nil), path...),
+		Span: []int32{int32(len(s.info.Location)), 0, int32(len(name))},
+	}
+	s.info.Location = append(s.info.Location, loc)
+	return loc
+}
+
+func subpath(path []int32, elems ...int32) []int32 {
+	return append(append(make([]int32, 0, len(path)+len(elems)), path...), elems...)
+}
+
+// FileBuilder fluently constructs a FileDescriptorProto. Files default to
+// proto3 syntax. Type names passed to any builder must be fully qualified with
+// a leading dot (eg, ".foo.bar.Baz").
+//
+//	f := testutils.NewFile("foo.proto", "foo").
+//		Message("Req", func(m *testutils.MessageBuilder) {
+//			m.Comment("Req is a request.")
+//			m.Field("id", 1, pgs.Int64T)
+//			m.Map("labels", 2, pgs.StringT, pgs.EnumT).TypeName(".foo.Label")
+//		}).
+//		Enum("Label", func(e *testutils.EnumBuilder) {
+//			e.Value("LABEL_UNSPECIFIED", 0)
+//		})
+type FileBuilder struct {
+	desc *descriptor.FileDescriptorProto
+	src  *sourceInfo
+	pkg  *descriptor.SourceCodeInfo_Location
+}
+
+// NewFile creates a FileBuilder for a file with the provided input path and
+// proto package.
+func NewFile(name, pkg string) *FileBuilder {
+	fb := &FileBuilder{
+		desc: &descriptor.FileDescriptorProto{
+			Name:   proto.String(name),
+			Syntax: proto.String(string(pgs.Proto3)),
+		},
+		src: &sourceInfo{info: &descriptor.SourceCodeInfo{}},
+	}
+
+	if pkg != "" {
+		fb.desc.Package = proto.String(pkg)
+		fb.pkg = fb.src.add([]int32{packagePath}, pkg)
+	}
+
+	return fb
+}
+
+// scope returns the prefix for the fully qualified names of the file's
+// top-level types.
+func (fb *FileBuilder) scope() string {
+	if pkg := fb.desc.GetPackage(); pkg != "" {
+		return "." + pkg
+	}
+	return ""
+}
+
+// Syntax sets the syntax of the file.
+func (fb *FileBuilder) Syntax(s pgs.Syntax) *FileBuilder {
+	if s == pgs.Proto2 {
+		fb.desc.Syntax = nil
+	} else {
+		fb.desc.Syntax = proto.String(string(s))
+	}
+	return fb
+}
+
+// Import adds the input paths of files imported by this file.
+func (fb *FileBuilder) Import(paths ...string) *FileBuilder {
+	fb.desc.Dependency = append(fb.desc.Dependency, paths...)
+	return fb
+}
+
+// GoPackage sets the go_package file option.
+func (fb *FileBuilder) GoPackage(pkg string) *FileBuilder {
+	if fb.desc.Options == nil {
+		fb.desc.Options = &descriptor.FileOptions{}
+	}
+	fb.desc.Options.GoPackage = proto.String(pkg)
+	return fb
+}
+
+// Options sets the options of the file. Any previously set go_package is
+// replaced.
+func (fb *FileBuilder) Options(opts *descriptor.FileOptions) *FileBuilder {
+	fb.desc.Options = opts
+	return fb
+}
+
+// Comment sets the leading comments of the file's package statement. The file
+// must have a package.
+func (fb *FileBuilder) Comment(leading string) *FileBuilder {
+	if fb.pkg != nil {
+		fb.pkg.LeadingComments = proto.String(leading)
+	}
+	return fb
+}
+
+// Message adds a top-level message to the file, configured by fn.
+func (fb *FileBuilder) Message(name string, fn func(m *MessageBuilder)) *FileBuilder {
+	path := []int32{messageTypePath, int32(len(fb.desc.MessageType))}
+	fb.desc.MessageType = append(fb.desc.MessageType, newMessage(fb.src, path, fb.scope(), name, fn))
+	return fb
+}
+
+// Enum adds a top-level enum to the file, configured by fn.
+func (fb *FileBuilder) Enum(name string, fn func(e *EnumBuilder)) *FileBuilder {
+	path := []int32{enumTypePath, int32(len(fb.desc.EnumType))}
+	fb.desc.EnumType = append(fb.desc.EnumType, newEnum(fb.src, path, name, fn))
+	return fb
+}
+
+// Service adds a service to the file, configured by fn.
+func (fb *FileBuilder) Service(name string, fn func(s *ServiceBuilder)) *FileBuilder {
+	path := []int32{servicePath, int32(len(fb.desc.Service))}
+	sb := &ServiceBuilder{
+		desc: &descriptor.ServiceDescriptorProto{Name: proto.String(name)},
+		src:  fb.src,
+		path: path,
+		loc:  fb.src.add(path, name),
+	}
+	if fn != nil {
+		fn(sb)
+	}
+	fb.desc.Service = append(fb.desc.Service, sb.desc)
+	return fb
+}
+
+// Extension adds a top-level extension of the extendee message to the file.
+func (fb *FileBuilder) Extension(extendee, name string, number int32, typ pgs.ProtoType) *FieldBuilder {
+	path := []int32{extensionPath, int32(len(fb.desc.Extension))}
+	f := newField(fb.src, path, name, number, typ)
+	f.desc.Extendee = proto.String(extendee)
+	fb.desc.Extension = append(fb.desc.Extension, f.desc)
+	return f
+}
+
+// Build returns a copy of the constructed FileDescriptorProto. Synthetic
+// oneofs are added for any proto3 optional fields.
+func (fb *FileBuilder) Build() *descriptor.FileDescriptorProto {
+	fd := proto.Clone(fb.desc).(*descriptor.FileDescriptorProto)
+	fd.SourceCodeInfo = proto.Clone(fb.src.info).(*descriptor.SourceCodeInfo)
+
+	for _, m := range fd.MessageType {
+		addSyntheticOneOfs(m)
+	}
+
+	return fd
+}
+
+func addSyntheticOneOfs(m *descriptor.DescriptorProto) {
+	for _, f := range m.Field {
+		if !f.GetProto3Optional() {
+			continue
+		}
+		f.OneofIndex = proto.Int32(int32(len(m.OneofDecl)))
+		m.OneofDecl = append(m.OneofDecl, &descriptor.OneofDescriptorProto{Name: proto.String("_" + f.GetName())})
+	}
+
+	for _, nm := range m.NestedType {
+		addSyntheticOneOfs(nm)
+	}
+}
+
+// MessageBuilder configures a message within a FileBuilder.
+type MessageBuilder struct {
+	desc *descriptor.DescriptorProto
+	src  *sourceInfo
+	path []int32
+	fqn  string
+	loc  *descriptor.SourceCodeInfo_Location
+}
+
+func newMessage(src *sourceInfo, path []int32, scope, name string, fn func(*MessageBuilder)) *descriptor.DescriptorProto {
+	mb := &MessageBuilder{
+		desc: &descriptor.DescriptorProto{Name: proto.String(name)},
+		src:  src,
+		path: path,
+		fqn:  scope + "." + name,
+		loc:  src.add(path, name),
+	}
+	if fn != nil {
+		fn(mb)
+	}
+	return mb.desc
+}
+
+// Comment sets the leading comments of the message.
+func (mb *MessageBuilder) Comment(leading string) *MessageBuilder {
+	mb.loc.LeadingComments = proto.String(leading)
+	return mb
+}
+
+// Options sets the options of the message.
+func (mb *MessageBuilder) Options(opts *descriptor.MessageOptions) *MessageBuilder {
+	mb.desc.Options = opts
+	return mb
+}
+
+// Field adds a singular field to the message. Enum, message and group fields
+// must also have their TypeName set.
+func (mb *MessageBuilder) Field(name string, number int32, typ pgs.ProtoType) *FieldBuilder {
+	path := subpath(mb.path, msgFieldPath, int32(len(mb.desc.Field)))
+	f := newField(mb.src, path, name, number, typ)
+	mb.desc.Field = append(mb.desc.Field, f.desc)
+	return f
+}
+
+// Map adds a map field to the message, along with its synthetic map entry
+// message. If the value is an enum or message, the TypeName of the returned
+// FieldBuilder sets the value's type.
+func (mb *MessageBuilder) Map(name string, number int32, key, value pgs.ProtoType) *FieldBuilder {
+	entryName := pgs.Name(name).UpperCamelCase().String() + "Entry"
+
+	entry := &descriptor.DescriptorProto{
+		Name:    proto.String(entryName),
+		Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("key"), Number: proto.Int32(1), Label: pgs.Optional.ProtoPtr(), Type: key.ProtoPtr()},
+			{Name: proto.String("value"), Number: proto.Int32(2), Label: pgs.Optional.ProtoPtr(), Type: value.ProtoPtr()},
+		},
+	}
+	mb.desc.NestedType = append(mb.desc.NestedType, entry)
+
+	f := mb.Field(name, number, pgs.MessageT).Repeated()
+	f.desc.TypeName = proto.String(mb.fqn + "." + entryName)
+	f.mapValue = entry.Field[1]
+	return f
+}
+
+// OneOf adds a oneof to the message, configured by fn.
+func (mb *MessageBuilder) OneOf(name string, fn func(o *OneOfBuilder)) *MessageBuilder {
+	idx := int32(len(mb.desc.OneofDecl))
+	path := subpath(mb.path, msgOneofPath, idx)
+
+	mb.desc.OneofDecl = append(mb.desc.OneofDecl, &descriptor.OneofDescriptorProto{Name: proto.String(name)})
+	ob := &OneOfBuilder{
+		msg:   mb,
+		index: idx,
+		loc:   mb.src.add(path, name),
+	}
+	if fn != nil {
+		fn(ob)
+	}
+	return mb
+}
+
+// Message adds a nested message, configured by fn.
+func (mb *MessageBuilder) Message(name string, fn func(m *MessageBuilder)) *MessageBuilder {
+	path := subpath(mb.path, msgNestedPath, int32(len(mb.desc.NestedType)))
+	mb.desc.NestedType = append(mb.desc.NestedType, newMessage(mb.src, path, mb.fqn, name, fn))
+	return mb
+}
+
+// Enum adds a nested enum, configured by fn.
+func (mb *MessageBuilder) Enum(name string, fn func(e *EnumBuilder)) *MessageBuilder {
+	path := subpath(mb.path, msgEnumPath, int32(len(mb.desc.EnumType)))
+	mb.desc.EnumType = append(mb.desc.EnumType, newEnum(mb.src, path, name, fn))
+	return mb
+}
+
+// Extension adds an extension of the extendee message, scoped to this
+// message.
+func (mb *MessageBuilder) Extension(extendee, name string, number int32, typ pgs.ProtoType) *FieldBuilder {
+	path := subpath(mb.path, msgExtPath, int32(len(mb.desc.Extension)))
+	f := newField(mb.src, path, name, number, typ)
+	f.desc.Extendee = proto.String(extendee)
+	mb.desc.Extension = append(mb.desc.Extension, f.desc)
+	return f
+}
+
+// OneOfBuilder configures a oneof within a MessageBuilder.
+type OneOfBuilder struct {
+	msg   *MessageBuilder
+	index int32
+	loc   *descriptor.SourceCodeInfo_Location
+}
+
+// Comment sets the leading comments of the oneof.
+func (ob *OneOfBuilder) Comment(leading string) *OneOfBuilder {
+	ob.loc.LeadingComments = proto.String(leading)
+	return ob
+}
+
+// Options sets the options of the oneof.
+func (ob *OneOfBuilder) Options(opts *descriptor.OneofOptions) *OneOfBuilder {
+	ob.msg.desc.OneofDecl[ob.index].Options = opts
+	return ob
+}
+
+// Field adds a field to the message as a member of this oneof.
+func (ob *OneOfBuilder) Field(name string, number int32, typ pgs.ProtoType) *FieldBuilder {
+	f := ob.msg.Field(name, number, typ)
+	f.desc.OneofIndex = proto.Int32(ob.index)
+	return f
+}
+
+// FieldBuilder configures a field or extension.
+type FieldBuilder struct {
+	desc     *descriptor.FieldDescriptorProto
+	loc      *descriptor.SourceCodeInfo_Location
+	mapValue *descriptor.FieldDescriptorProto
+}
+
+func newField(src *sourceInfo, path []int32, name string, number int32, typ pgs.ProtoType) *FieldBuilder {
+	return &FieldBuilder{
+		desc: &descriptor.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    pgs.Optional.ProtoPtr(),
+			Type:     typ.ProtoPtr(),
+			JsonName: proto.String(pgs.Name(name).LowerCamelCase().String()),
+		},
+		loc: src.add(path, name),
+	}
+}
+
+// TypeName sets the fully qualified type of an enum, message or group field.
+// For map fields, this sets the type of the map's value.
+func (fb *FieldBuilder) TypeName(fqn string) *FieldBuilder {
+	if fb.mapValue != nil {
+		fb.mapValue.TypeName = proto.String(fqn)
+	} else {
+		fb.desc.TypeName = proto.String(fqn)
+	}
+	return fb
+}
+
+// Repeated marks the field as repeated.
+func (fb *FieldBuilder) Repeated() *FieldBuilder {
+	fb.desc.Label = pgs.Repeated.ProtoPtr()
+	return fb
+}
+
+// Required marks the field as required. This is only valid for proto2 files.
+func (fb *FieldBuilder) Required() *FieldBuilder {
+	fb.desc.Label = pgs.Required.ProtoPtr()
+	return fb
+}
+
+// Optional marks a field in a proto3 file as having explicit presence. A
+// synthetic oneof is added for the field when the file is built.
+func (fb *FieldBuilder) Optional() *FieldBuilder {
+	fb.desc.Proto3Optional = proto.Bool(true)
+	return fb
+}
+
+// Default sets the proto2 default value of the field, as it would appear in
+// the FieldDescriptorProto.
+func (fb *FieldBuilder) Default(value string) *FieldBuilder {
+	fb.desc.DefaultValue = proto.String(value)
+	return fb
+}
+
+// Comment sets the leading comments of the field.
+func (fb *FieldBuilder) Comment(leading string) *FieldBuilder {
+	fb.loc.LeadingComments = proto.String(leading)
+	return fb
+}
+
+// TrailingComment sets the trailing comments of the field.
+func (fb *FieldBuilder) TrailingComment(trailing string) *FieldBuilder {
+	fb.loc.TrailingComments = proto.String(trailing)
+	return fb
+}
+
+// Options sets the options of the field.
+func (fb *FieldBuilder) Options(opts *descriptor.FieldOptions) *FieldBuilder {
+	fb.desc.Options = opts
+	return fb
+}
+
+// EnumBuilder configures an enum within a FileBuilder.
+type EnumBuilder struct {
+	desc *descriptor.EnumDescriptorProto
+	src  *sourceInfo
+	path []int32
+	loc  *descriptor.SourceCodeInfo_Location
+}
+
+func newEnum(src *sourceInfo, path []int32, name string, fn func(*EnumBuilder)) *descriptor.EnumDescriptorProto {
+	eb := &EnumBuilder{
+		desc: &descriptor.EnumDescriptorProto{Name: proto.String(name)},
+		src:  src,
+		path: path,
+		loc:  src.add(path, name),
+	}
+	if fn != nil {
+		fn(eb)
+	}
+	return eb.desc
+}
+
+// Comment sets the leading comments of the enum.
+func (eb *EnumBuilder) Comment(leading string) *EnumBuilder {
+	eb.loc.LeadingComments = proto.String(leading)
+	return eb
+}
+
+// Options sets the options of the enum.
+func (eb *EnumBuilder) Options(opts *descriptor.EnumOptions) *EnumBuilder {
+	eb.desc.Options = opts
+	return eb
+}
+
+// Value adds a value to the enum.
+func (eb *EnumBuilder) Value(name string, number int32) *EnumValueBuilder {
+	path := subpath(eb.path, enumValuePath, int32(len(eb.desc.Value)))
+	evb := &EnumValueBuilder{
+		desc: &descriptor.EnumValueDescriptorProto{Name: proto.String(name), Number: proto.Int32(number)},
+		loc:  eb.src.add(path, name),
+	}
+	eb.desc.Value = append(eb.desc.Value, evb.desc)
+	return evb
+}
+
+// EnumValueBuilder configures a value within an EnumBuilder.
+type EnumValueBuilder struct {
+	desc *descriptor.EnumValueDescriptorProto
+	loc  *descriptor.SourceCodeInfo_Location
+}
+
+// Comment sets the leading comments of the enum value.
+func (evb *EnumValueBuilder) Comment(leading string) *EnumValueBuilder {
+	evb.loc.LeadingComments = proto.String(leading)
+	return evb
+}
+
+// Options sets the options of the enum value.
+func (evb *EnumValueBuilder) Options(opts *descriptor.EnumValueOptions) *EnumValueBuilder {
+	evb.desc.Options = opts
+	return evb
+}
+
+// ServiceBuilder configures a service within a FileBuilder.
+type ServiceBuilder struct {
+	desc *descriptor.ServiceDescriptorProto
+	src  *sourceInfo
+	path []int32
+	loc  *descriptor.SourceCodeInfo_Location
+}
+
+// Comment sets the leading comments of the service.
+func (sb *ServiceBuilder) Comment(leading string) *ServiceBuilder {
+	sb.loc.LeadingComments = proto.String(leading)
+	return sb
+}
+
+// Options sets the options of the service.
+func (sb *ServiceBuilder) Options(opts *descriptor.ServiceOptions) *ServiceBuilder {
+	sb.desc.Options = opts
+	return sb
+}
+
+// Method adds a method to the service with the fully qualified input and
+// output message types.
+func (sb *ServiceBuilder) Method(name, input, output string) *MethodBuilder {
+	path := subpath(sb.path, svcMethodPath, int32(len(sb.desc.Method)))
+	mb := &MethodBuilder{
+		desc: &descriptor.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(input),
+			OutputType: proto.String(output),
+		},
+		loc: sb.src.add(path, name),
+	}
+	sb.desc.Method = append(sb.desc.Method, mb.desc)
+	return mb
+}
+
+// MethodBuilder configures a method within a ServiceBuilder.
+type MethodBuilder struct {
+	desc *descriptor.MethodDescriptorProto
+	loc  *descriptor.SourceCodeInfo_Location
+}
+
+// ClientStreaming marks the method's input as streaming.
+func (mb *MethodBuilder) ClientStreaming() *MethodBuilder {
+	mb.desc.ClientStreaming = proto.Bool(true)
+	return mb
+}
+
+// ServerStreaming marks the method's output as streaming.
+func (mb *MethodBuilder) ServerStreaming() *MethodBuilder {
+	mb.desc.ServerStreaming = proto.Bool(true)
+	return mb
+}
+
+// Comment sets the leading comments of the method.
+func (mb *MethodBuilder) Comment(leading string) *MethodBuilder {
+	mb.loc.LeadingComments = proto.String(leading)
+	return mb
+}
+
+// Options sets the options of the method.
+func (mb *MethodBuilder) Options(opts *descriptor.MethodOptions) *MethodBuilder {
+	mb.desc.Options = opts
+	return mb
+}
@@ -0,0 +1,120 @@
+package testutils
+
+import (
+	"testing"
+
+	pgs "github.com/lyft/protoc-gen-star/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func dummyBuilders() []*FileBuilder {
+	common := NewFile("common.proto", "common").
+		Enum("Kind", func(e *EnumBuilder) {
+			e.Comment("Kind is a kind.")
+			e.Value("KIND_UNSPECIFIED", 0)
+			e.Value("KIND_OLD", 1).Options(&descriptor.EnumValueOptions{Deprecated: proto.Bool(true)})
+		})
+
+	svc := NewFile("svc.proto", "svc").
+		GoPackage("example.com/svc").
+		Import("common.proto").
+		Comment("package comment").
+		Message("Req", func(m *MessageBuilder) {
+			m.Comment("Req is a request.")
+			m.Field("id", 1, pgs.Int64T).TrailingComment("the id")
+			m.Field("name", 2, pgs.StringT).Optional()
+			m.Map("kinds", 3, pgs.StringT, pgs.EnumT).TypeName(".common.Kind")
+			m.OneOf("choice", func(o *OneOfBuilder) {
+				o.Comment("choice is a oneof.")
+				o.Field("inner", 4, pgs.MessageT).TypeName(".svc.Req.Inner")
+				o.Field("kind", 5, pgs.EnumT).TypeName(".common.Kind")
+			})
+			m.Message("Inner", func(m *MessageBuilder) {
+				m.OneOf("nested", func(o *OneOfBuilder) {
+					o.Field("a", 1, pgs.StringT)
+					o.Field("b", 2, pgs.BytesT)
+				})
+				m.Field("tags", 3, pgs.StringT).Repeated()
+			})
+		}).
+		Message("Res", nil).
+		Service("Svc", func(s *ServiceBuilder) {
+			s.Comment("Svc is a service.")
+			s.Method("Do", ".svc.Req", ".svc.Res").ServerStreaming().Comment("Do does.")
+		})
+
+	return []*FileBuilder{common, svc}
+}
+
+func TestBuildSet(t *testing.T) {
+	t.Parallel()
+
+	fdset := BuildSet(dummyBuilders()...)
+	require.Len(t, fdset.File, 2)
+
+	fd := fdset.File[1]
+	assert.Equal(t, "proto3", fd.GetSyntax())
+	assert.Equal(t, []string{"common.proto"}, fd.Dependency)
+
+	req := fd.MessageType[0]
+	require.Len(t, req.NestedType, 2)
+	assert.Equal(t, "KindsEntry", req.NestedType[0].GetName())
+	assert.True(t, req.NestedType[0].GetOptions().GetMapEntry())
+	assert.Equal(t, ".common.Kind", req.NestedType[0].Field[1].GetTypeName())
+	assert.Equal(t, ".svc.Req.KindsEntry", req.Field[2].GetTypeName())
+
+	require.Len(t, req.OneofDecl, 2)
+	assert.Equal(t, "choice", req.OneofDecl[0].GetName())
+	assert.Equal(t, "_name", req.OneofDecl[1].GetName())
+	assert.Equal(t, int32(1), req.Field[1].GetOneofIndex())
+
+	b := NewFile("p2.proto", "").Syntax(pgs.Proto2)
+	assert.Empty(t, b.Build().GetSyntax())
+	assert.Empty(t, b.Build().GetSourceCodeInfo().GetLocation())
+}
+
+func TestLoader_LoadFiles(t *testing.T) {
+	t.Parallel()
+
+	mt := &mockT{}
+	ast := Loader{}.LoadFiles(mt, dummyBuilders()...)
+	require.False(t, mt.failed, mt.log)
+
+	lookup := func(name string) pgs.Entity {
+		e, ok := ast.Lookup(name)
+		require.True(t, ok, name)
+		return e
+	}
+
+	req := lookup(".svc.Req").(pgs.Message)
+	assert.Equal(t, "Req is a request.", req.SourceCodeInfo().LeadingComments())
+	assert.Len(t, req.RealOneOfs(), 1)
+	assert.Len(t, req.SyntheticOneOfFields(), 1)
+
+	id := lookup(".svc.Req.id").(pgs.Field)
+	assert.Equal(t, "the id", id.SourceCodeInfo().TrailingComments())
+
+	kinds := lookup(".svc.Req.kinds").(pgs.Field)
+	require.True(t, kinds.Type().IsMap())
+	assert.True(t, kinds.Type().Element().IsEnum())
+	assert.Equal(t, ".common.Kind", kinds.Type().Element().Enum().FullyQualifiedName())
+
+	inner := lookup(".svc.Req.Inner").(pgs.Message)
+	require.Len(t, inner.OneOfs(), 1)
+	assert.Len(t, inner.OneOfs()[0].Fields(), 2)
+	assert.True(t, lookup(".svc.Req.Inner.tags").(pgs.Field).Type().IsRepeated())
+
+	choice := lookup(".svc.Req.choice").(pgs.OneOf)
+	assert.Equal(t, "choice is a oneof.", choice.SourceCodeInfo().LeadingComments())
+
+	do := lookup(".svc.Svc.Do").(pgs.Method)
+	assert.True(t, do.ServerStreaming())
+	assert.Equal(t, "Do does.", do.SourceCodeInfo().LeadingComments())
+	assert.Equal(t, ".svc.Res", do.Output().FullyQualifiedName())
+
+	assert.True(t, lookup(".common.Kind.KIND_OLD").Deprecated())
+	assert.Equal(t, "Kind is a kind.", lookup(".common.Kind").SourceCodeInfo().LeadingComments())
+}
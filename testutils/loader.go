@@ -108,6 +108,10 @@ func (l Loader) LoadFDSetReader(t T, r io.Reader) (ast pgs.AST) {
 		return nil
 	}
 
+	return l.loadFDSet(t, fdset)
+}
+
+func (l Loader) loadFDSet(t T, fdset *descriptor.FileDescriptorSet) (ast pgs.AST) {
 	d := pgs.InitMockDebugger()
 	defer func() {
 		// Recovery here is required if either Process panics due to how the MockDebugger
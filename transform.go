@@ -0,0 +1,285 @@
+package pgs
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+// A Transform modifies the descriptors of the CodeGeneratorRequest received
+// from protoc before the AST is hydrated. Transforms can be used to normalize
+// the input to all Modules, such as injecting default options, renaming
+// packages, or stripping internal-only entities.
+type Transform interface {
+	// Name identifies the Transform in debug output.
+	Name() string
+
+	// Transform modifies req in-place. The Parameters are those passed in from
+	// protoc after any ParamMutators have been applied. Any returned error
+	// halts execution of the plugin.
+	Transform(req *plugin_go.CodeGeneratorRequest, params Parameters) error
+}
+
+// TransformFunc creates a Transform with the provided name from fn.
+func TransformFunc(name string, fn func(req *plugin_go.CodeGeneratorRequest, params Parameters) error) Transform {
+	return transformFunc{name: name, fn: fn}
+}
+
+type transformFunc struct {
+	name string
+	fn   func(*plugin_go.CodeGeneratorRequest, Parameters) error
+}
+
+func (t transformFunc) Name() string { return t.name }
+
+func (t transformFunc) Transform(req *plugin_go.CodeGeneratorRequest, params Parameters) error {
+	return t.fn(req, params)
+}
+
+// applyTransforms executes each Transform against req in order, validating
+// that the descriptors are still well-formed after each one. If debug is
+// true, a summary of the changes made by each Transform is also logged; the
+// descriptors are only flattened and compared in that case, as doing so is
+// costly for large requests.
+func applyTransforms(d Debugger, debug bool, req *plugin_go.CodeGeneratorRequest, params Parameters, ts []Transform) {
+	for _, t := range ts {
+		td := d.Push(t.Name())
+
+		var before map[string]proto.Message
+		if debug {
+			before = flattenDescriptors(req.GetProtoFile())
+		}
+
+		td.CheckErr(t.Transform(req, params), "applying transform")
+		td.CheckErr(validateRequest(req), "validating transformed descriptors")
+
+		if !debug {
+			continue
+		}
+
+		s := diffDescriptors(before, flattenDescriptors(req.GetProtoFile()))
+		td.Debugf("transformed descriptors: %s", s)
+		for _, line := range s.details() {
+			td.Debug(line)
+		}
+	}
+}
+
+// validateRequest returns an error if the descriptors in req cannot be
+// resolved into a consistent set of files, or if a file to generate is
+// missing from the request. Requests containing files with a syntax the
+// protodesc package does not support, such as editions, are checked with
+// validateReferences instead.
+func validateRequest(req *plugin_go.CodeGeneratorRequest) error {
+	for _, f := range req.GetProtoFile() {
+		switch f.GetSyntax() {
+		case "", "proto2", string(Proto3):
+		default:
+			return validateReferences(req)
+		}
+	}
+
+	files, err := protodesc.NewFiles(&descriptor.FileDescriptorSet{File: req.GetProtoFile()})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range req.GetFileToGenerate() {
+		if _, err = files.FindFileByPath(name); err != nil {
+			return fmt.Errorf("file to generate %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateReferences is a structural check of req, ensuring that all file
+// dependencies, files to generate, and type references can be resolved within
+// the request.
+func validateReferences(req *plugin_go.CodeGeneratorRequest) error {
+	files := make(map[string]struct{}, len(req.GetProtoFile()))
+	for _, f := range req.GetProtoFile() {
+		files[f.GetName()] = struct{}{}
+	}
+
+	for _, f := range req.GetProtoFile() {
+		for _, dep := range f.GetDependency() {
+			if _, ok := files[dep]; !ok {
+				return fmt.Errorf("%s: dependency %q not found", f.GetName(), dep)
+			}
+		}
+	}
+
+	for _, name := range req.GetFileToGenerate() {
+		if _, ok := files[name]; !ok {
+			return fmt.Errorf("file to generate %q: not found", name)
+		}
+	}
+
+	defs := flattenDescriptors(req.GetProtoFile())
+
+	isType := func(name string) bool {
+		switch defs[name].(type) {
+		case *descriptor.DescriptorProto, *descriptor.EnumDescriptorProto:
+			return true
+		}
+		return false
+	}
+
+	names := make([]string, 0, len(defs))
+	for n := range defs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		var refs []string
+
+		switch d := defs[n].(type) {
+		case *descriptor.FieldDescriptorProto:
+			refs = []string{d.GetTypeName(), d.GetExtendee()}
+		case *descriptor.MethodDescriptorProto:
+			refs = []string{d.GetInputType(), d.GetOutputType()}
+		}
+
+		for _, ref := range refs {
+			if ref != "" && !isType(ref) {
+				return fmt.Errorf("%s references unknown type %s", n, ref)
+			}
+		}
+	}
+
+	return nil
+}
+
+// transformSummary describes the descriptors added, removed, or modified by a
+// Transform, keyed by their fully qualified names.
+type transformSummary struct {
+	added, removed, modified []string
+}
+
+func (s transformSummary) String() string {
+	return fmt.Sprintf("%d added, %d removed, %d modified", len(s.added), len(s.removed), len(s.modified))
+}
+
+func (s transformSummary) details() []string {
+	out := make([]string, 0, len(s.added)+len(s.removed)+len(s.modified))
+	for _, n := range s.added {
+		out = append(out, "+ "+n)
+	}
+	for _, n := range s.removed {
+		out = append(out, "- "+n)
+	}
+	for _, n := range s.modified {
+		out = append(out, "~ "+n)
+	}
+	return out
+}
+
+func diffDescriptors(before, after map[string]proto.Message) (s transformSummary) {
+	for n, a := range after {
+		if b, ok := before[n]; !ok {
+			s.added = append(s.added, n)
+		} else if !proto.Equal(a, b) {
+			s.modified = append(s.modified, n)
+		}
+	}
+
+	for n := range before {
+		if _, ok := after[n]; !ok {
+			s.removed = append(s.removed, n)
+		}
+	}
+
+	sort.Strings(s.added)
+	sort.Strings(s.removed)
+	sort.Strings(s.modified)
+
+	return s
+}
+
+// flattenDescriptors returns a copy of each descriptor within files, keyed by
+// fully qualified name (or input path for files). The descriptors of
+// containers exclude their children, so a change to a nested entity is only
+// attributed to that entity.
+func flattenDescriptors(files []*descriptor.FileDescriptorProto) map[string]proto.Message {
+	out := make(map[string]proto.Message)
+
+	for _, f := range files {
+		fd := &descriptor.FileDescriptorProto{
+			Name:       f.Name,
+			Package:    f.Package,
+			Dependency: f.Dependency,
+			Options:    f.Options,
+			Syntax:     f.Syntax,
+		}
+		out[f.GetName()] = proto.Clone(fd)
+
+		scope := ""
+		if f.GetPackage() != "" {
+			scope = "." + f.GetPackage()
+		}
+
+		for _, m := range f.GetMessageType() {
+			flattenMessage(out, scope, m)
+		}
+		for _, e := range f.GetEnumType() {
+			flattenEnum(out, scope, e)
+		}
+		for _, x := range f.GetExtension() {
+			out[scope+"."+x.GetName()] = proto.Clone(x)
+		}
+		for _, s := range f.GetService() {
+			fqn := scope + "." + s.GetName()
+			out[fqn] = proto.Clone(&descriptor.ServiceDescriptorProto{Name: s.Name, Options: s.Options})
+			for _, m := range s.GetMethod() {
+				out[fqn+"."+m.GetName()] = proto.Clone(m)
+			}
+		}
+	}
+
+	return out
+}
+
+func flattenMessage(out map[string]proto.Message, scope string, m *descriptor.DescriptorProto) {
+	fqn := scope + "." + m.GetName()
+	out[fqn] = proto.Clone(&descriptor.DescriptorProto{
+		Name:           m.Name,
+		Options:        m.Options,
+		OneofDecl:      m.OneofDecl,
+		ExtensionRange: m.ExtensionRange,
+		ReservedRange:  m.ReservedRange,
+		ReservedName:   m.ReservedName,
+	})
+
+	for _, f := range m.GetField() {
+		out[fqn+"."+f.GetName()] = proto.Clone(f)
+	}
+	for _, x := range m.GetExtension() {
+		out[fqn+"."+x.GetName()] = proto.Clone(x)
+	}
+	for _, nm := range m.GetNestedType() {
+		flattenMessage(out, fqn, nm)
+	}
+	for _, e := range m.GetEnumType() {
+		flattenEnum(out, fqn, e)
+	}
+}
+
+func flattenEnum(out map[string]proto.Message, scope string, e *descriptor.EnumDescriptorProto) {
+	fqn := scope + "." + e.GetName()
+	out[fqn] = proto.Clone(&descriptor.EnumDescriptorProto{
+		Name:          e.Name,
+		Options:       e.Options,
+		ReservedRange: e.ReservedRange,
+		ReservedName:  e.ReservedName,
+	})
+
+	for _, v := range e.GetValue() {
+		out[fqn+"."+v.GetName()] = proto.Clone(v)
+	}
+}
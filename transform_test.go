@@ -0,0 +1,187 @@
+package pgs
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+func dummyTransformRequest() *plugin_go.CodeGeneratorRequest {
+	return &plugin_go.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo.proto"},
+		ProtoFile: []*descriptor.FileDescriptorProto{{
+			Name:    proto.String("foo.proto"),
+			Package: proto.String("foo"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptor.DescriptorProto{{
+				Name: proto.String("Msg"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{
+						Name:     proto.String("public"),
+						JsonName: proto.String("public"),
+						Number:   proto.Int32(1),
+						Label:    Optional.ProtoPtr(),
+						Type:     StringT.ProtoPtr(),
+					},
+					{
+						Name:     proto.String("internal"),
+						JsonName: proto.String("internal"),
+						Number:   proto.Int32(2),
+						Label:    Optional.ProtoPtr(),
+						Type:     StringT.ProtoPtr(),
+					},
+				},
+			}},
+		}},
+	}
+}
+
+func TestTransformFunc(t *testing.T) {
+	t.Parallel()
+
+	called := false
+	tr := TransformFunc("foo", func(req *plugin_go.CodeGeneratorRequest, params Parameters) error {
+		called = true
+		assert.Equal(t, "bar", params.Str("baz"))
+		return nil
+	})
+
+	assert.Equal(t, "foo", tr.Name())
+	assert.NoError(t, tr.Transform(nil, Parameters{"baz": "bar"}))
+	assert.True(t, called)
+}
+
+func TestApplyTransforms(t *testing.T) {
+	t.Parallel()
+
+	strip := TransformFunc("strip", func(req *plugin_go.CodeGeneratorRequest, _ Parameters) error {
+		msg := req.ProtoFile[0].MessageType[0]
+		msg.Field = msg.Field[:1]
+		return nil
+	})
+
+	deprecate := TransformFunc("deprecate", func(req *plugin_go.CodeGeneratorRequest, _ Parameters) error {
+		req.ProtoFile[0].MessageType[0].Options = &descriptor.MessageOptions{Deprecated: proto.Bool(true)}
+		return nil
+	})
+
+	d := InitMockDebugger()
+	req := dummyTransformRequest()
+	applyTransforms(d, true, req, Parameters{}, []Transform{strip, deprecate})
+
+	require.False(t, d.Failed())
+	require.NoError(t, d.Err())
+	assert.Len(t, req.ProtoFile[0].MessageType[0].Field, 1)
+	assert.True(t, req.ProtoFile[0].MessageType[0].GetOptions().GetDeprecated())
+
+	out, _ := ioutil.ReadAll(d.Output())
+	assert.Contains(t, string(out), "[strip] transformed descriptors: 0 added, 1 removed, 0 modified")
+	assert.Contains(t, string(out), "- .foo.Msg.internal")
+	assert.Contains(t, string(out), "[deprecate] transformed descriptors: 0 added, 0 removed, 1 modified")
+	assert.Contains(t, string(out), "~ .foo.Msg")
+
+	t.Run("without debug", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		req := dummyTransformRequest()
+		applyTransforms(d, false, req, Parameters{}, []Transform{strip})
+
+		require.NoError(t, d.Err())
+		assert.Len(t, req.ProtoFile[0].MessageType[0].Field, 1)
+
+		out, _ := ioutil.ReadAll(d.Output())
+		assert.Empty(t, out)
+	})
+}
+
+func TestApplyTransforms_Error(t *testing.T) {
+	t.Parallel()
+
+	err := errors.New("TestApplyTransforms_Error")
+	tr := TransformFunc("err", func(*plugin_go.CodeGeneratorRequest, Parameters) error { return err })
+
+	d := InitMockDebugger()
+	applyTransforms(d, true, dummyTransformRequest(), Parameters{}, []Transform{tr})
+
+	assert.True(t, d.Exited())
+	assert.Equal(t, err, d.Err())
+}
+
+func TestApplyTransforms_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tr := TransformFunc("invalid", func(req *plugin_go.CodeGeneratorRequest, _ Parameters) error {
+		req.ProtoFile[0].MessageType[0].Field[1].TypeName = proto.String(".foo.Missing")
+		req.ProtoFile[0].MessageType[0].Field[1].Type = MessageT.ProtoPtr()
+		return nil
+	})
+
+	d := InitMockDebugger()
+	applyTransforms(d, true, dummyTransformRequest(), Parameters{}, []Transform{tr})
+
+	assert.True(t, d.Exited())
+	assert.Error(t, d.Err())
+}
+
+func TestValidateRequest(t *testing.T) {
+	t.Parallel()
+
+	req := dummyTransformRequest()
+	assert.NoError(t, validateRequest(req))
+
+	req.FileToGenerate = append(req.FileToGenerate, "bar.proto")
+	assert.Error(t, validateRequest(req))
+}
+
+func TestValidateRequest_Editions(t *testing.T) {
+	t.Parallel()
+
+	req := dummyTransformRequest()
+	req.ProtoFile[0].Syntax = proto.String(string(Editions))
+	req.ProtoFile[0].MessageType[0].Field[1].Type = MessageT.ProtoPtr()
+	req.ProtoFile[0].MessageType[0].Field[1].TypeName = proto.String(".foo.Msg")
+	assert.NoError(t, validateRequest(req))
+
+	d := InitMockDebugger()
+	applyTransforms(d, true, req, Parameters{visibilityKey: "public"}, []Transform{dummyVisibilityFilter()})
+	assert.NoError(t, d.Err())
+	assert.False(t, d.Exited())
+
+	req.ProtoFile[0].MessageType[0].Field[1].TypeName = proto.String(".foo.Missing")
+	assert.EqualError(t, validateRequest(req), ".foo.Msg.internal references unknown type .foo.Missing")
+
+	req = dummyTransformRequest()
+	req.ProtoFile[0].Syntax = proto.String(string(Editions))
+	req.ProtoFile[0].Dependency = []string{"missing.proto"}
+	assert.Error(t, validateRequest(req))
+
+	req = dummyTransformRequest()
+	req.ProtoFile[0].Syntax = proto.String(string(Editions))
+	req.FileToGenerate = append(req.FileToGenerate, "bar.proto")
+	assert.Error(t, validateRequest(req))
+}
+
+func TestStandardWorkflow_Init_Transforms(t *testing.T) {
+	t.Parallel()
+
+	b, err := proto.Marshal(dummyTransformRequest())
+	require.NoError(t, err)
+
+	g := Init(ProtocInput(bytes.NewReader(b)))
+	g.RegisterTransform(TransformFunc("rename", func(req *plugin_go.CodeGeneratorRequest, _ Parameters) error {
+		req.ProtoFile[0].Package = proto.String("vendored.foo")
+		return nil
+	}))
+
+	ast := g.AST()
+	_, ok := ast.Lookup(".vendored.foo.Msg")
+	assert.True(t, ok)
+}
@@ -0,0 +1,835 @@
+package pgs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/runtime/protoimpl"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+const (
+	visibilityKey = "visibility"
+
+	dependencyPath     int32 = 3  // FileDescriptorProto.Dependency
+	weakDependencyPath int32 = 11 // FileDescriptorProto.WeakDependency
+)
+
+// A VisibilityFunc returns the visibility level declared on an element of a
+// proto file, given its options message and source location, either of which
+// may be nil. An empty string is returned if no level is declared. For files,
+// the source location is that of the package statement.
+type VisibilityFunc func(opts proto.Message, loc *descriptor.SourceCodeInfo_Location) (string, error)
+
+// VisibilityDirective returns a VisibilityFunc that reads the level from the
+// value of a comment Directive with the given prefix and key. For example,
+// VisibilityDirective("+", "visibility") reads "internal" from the comment
+// line `+visibility=internal`.
+func VisibilityDirective(prefix, key string) VisibilityFunc {
+	return func(_ proto.Message, loc *descriptor.SourceCodeInfo_Location) (string, error) {
+		if loc == nil {
+			return "", nil
+		}
+
+		dirs, err := parseDirectives(loc, prefix, false)
+		if err != nil {
+			return "", err
+		}
+
+		d, _ := dirs.Lookup(key)
+		return d.Value, nil
+	}
+}
+
+// VisibilityOption returns a VisibilityFunc that reads the level from a custom
+// option. The option must be a string or an enum, in which case the name of
+// the enum value is used as the level.
+func VisibilityOption(ext *protoimpl.ExtensionInfo) VisibilityFunc {
+	return func(opts proto.Message, _ *descriptor.SourceCodeInfo_Location) (string, error) {
+		if opts == nil || reflect.ValueOf(opts).IsNil() || !extractor.HasExtension(opts, ext) {
+			return "", nil
+		}
+
+		switch v := extractor.GetExtension(opts, ext).(type) {
+		case string:
+			return v, nil
+		case protoreflect.Enum:
+			ev := v.Descriptor().Values().ByNumber(v.Number())
+			if ev == nil {
+				return "", fmt.Errorf("unknown value %d for visibility option %s", v.Number(), ext.TypeDescriptor().FullName())
+			}
+			return string(ev.Name()), nil
+		default:
+			return "", fmt.Errorf("visibility option %s must be a string or enum, got %T", ext.TypeDescriptor().FullName(), v)
+		}
+	}
+}
+
+// VisibilityFilter is a Transform that removes the entities less visible than
+// the level selected by the "visibility" parameter, such as
+// `--star_out=visibility=public:.`, producing a pruned AST for all Modules. If
+// the parameter is not set, the descriptors are left unchanged.
+//
+// Entities that do not declare a visibility inherit the level of their parent.
+// Files to generate that do not declare one use the Default, while their
+// dependencies use the most visible level. Fields of a removed OneOf
+// are removed as well, as are OneOfs whose Fields are all removed.
+//
+// Within the files to generate, Messages and Enums that were reachable from a
+// removed entity, but can no longer be reached from any remaining one, are
+// also removed unless they declare their own visibility. This includes types
+// that only reference themselves or each other. Imports that are no longer required by a file to generate are
+// dropped. An error is returned if any remaining entity references a removed
+// type.
+type VisibilityFilter struct {
+	// Levels lists the visibility levels from least to most visible, such as
+	// "internal", "partner" and "public". Levels are matched case-insensitively.
+	Levels []string
+
+	// Default is the visibility of the files to generate that do not declare
+	// one. If empty, the most visible level is used. Dependencies without a
+	// declared visibility always use the most visible level.
+	Default string
+
+	// Sources are consulted in order for the visibility declared on each
+	// entity. The first non-empty level returned is used.
+	Sources []VisibilityFunc
+}
+
+// Name satisfies the Transform interface.
+func (vf VisibilityFilter) Name() string { return "visibility" }
+
+// Transform satisfies the Transform interface.
+func (vf VisibilityFilter) Transform(req *plugin_go.CodeGeneratorRequest, params Parameters) error {
+	lvl := params.Str(visibilityKey)
+	if lvl == "" {
+		return nil
+	}
+
+	min, ok := vf.levelIndex(lvl)
+	if !ok {
+		return fmt.Errorf("unknown visibility level %q, expected one of %v", lvl, vf.Levels)
+	}
+
+	def := len(vf.Levels) - 1
+	if vf.Default != "" {
+		if def, ok = vf.levelIndex(vf.Default); !ok {
+			return fmt.Errorf("unknown default visibility level %q, expected one of %v", vf.Default, vf.Levels)
+		}
+	}
+
+	p := &visibilityPruner{
+		VisibilityFilter: vf,
+		min:              min,
+		def:              def,
+		removed:          make(map[string]bool),
+		explicit:         make(map[string]bool),
+		types:            make(map[string]proto.Message),
+		typeFiles:        make(map[string]string),
+		targets:          make(map[string]bool),
+	}
+
+	return p.prune(req)
+}
+
+func (vf VisibilityFilter) levelIndex(lvl string) (int, bool) {
+	for i, l := range vf.Levels {
+		if strings.EqualFold(l, lvl) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+type visibilityPruner struct {
+	VisibilityFilter
+
+	min, def int
+
+	removed   map[string]bool          // FQNs of removed elements
+	explicit  map[string]bool          // FQNs of elements declaring a visibility
+	types     map[string]proto.Message // Message and Enum descriptors by FQN
+	typeFiles map[string]string        // the file declaring each type
+	targets   map[string]bool          // the files to generate
+}
+
+func (p *visibilityPruner) prune(req *plugin_go.CodeGeneratorRequest) error {
+	for _, name := range req.GetFileToGenerate() {
+		p.targets[name] = true
+	}
+
+	for _, fd := range req.GetProtoFile() {
+		p.indexTypes(fd.GetName(), fileScope(fd), fd.GetMessageType(), fd.GetEnumType())
+	}
+
+	for _, fd := range req.GetProtoFile() {
+		if err := p.decideFile(fd); err != nil {
+			return fmt.Errorf("%s: %w", fd.GetName(), err)
+		}
+	}
+
+	p.removeUnreachable(req)
+
+	var errs []string
+	for _, fd := range req.GetProtoFile() {
+		eachReference(fd, func(from, to string) {
+			if !p.removed[from] && p.removed[to] {
+				errs = append(errs, fmt.Sprintf("%s references removed type %s", from, to))
+			}
+		})
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("visibility %q: %s", p.Levels[p.min], strings.Join(errs, "; "))
+	}
+
+	for _, fd := range req.GetProtoFile() {
+		p.applyFile(fd)
+	}
+
+	return nil
+}
+
+func (p *visibilityPruner) indexTypes(file, scope string, msgs []*descriptor.DescriptorProto, enums []*descriptor.EnumDescriptorProto) {
+	for _, m := range msgs {
+		fqn := scope + "." + m.GetName()
+		p.types[fqn], p.typeFiles[fqn] = m, file
+		p.indexTypes(file, fqn, m.GetNestedType(), m.GetEnumType())
+	}
+
+	for _, e := range enums {
+		fqn := scope + "." + e.GetName()
+		p.types[fqn], p.typeFiles[fqn] = e, file
+	}
+}
+
+// level returns the visibility of the element with the given FQN, or
+// inherited if it does not declare one.
+func (p *visibilityPruner) level(fqn string, opts proto.Message, loc *descriptor.SourceCodeInfo_Location, inherited int) (int, error) {
+	for _, src := range p.Sources {
+		lvl, err := src(opts, loc)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", fqn, err)
+		}
+
+		if lvl == "" {
+			continue
+		}
+
+		idx, ok := p.levelIndex(lvl)
+		if !ok {
+			return 0, fmt.Errorf("%s: unknown visibility level %q", fqn, lvl)
+		}
+
+		p.explicit[fqn] = true
+		return idx, nil
+	}
+
+	return inherited, nil
+}
+
+func (p *visibilityPruner) decideFile(fd *descriptor.FileDescriptorProto) error {
+	locs := make(map[string]*descriptor.SourceCodeInfo_Location)
+	for _, loc := range fd.GetSourceCodeInfo().GetLocation() {
+		locs[pathKey(loc.GetPath())] = loc
+	}
+
+	d := &visibilityDecider{visibilityPruner: p, locs: locs}
+
+	def := len(p.Levels) - 1
+	if p.targets[fd.GetName()] {
+		def = p.def
+	}
+
+	lvl, err := p.level(fd.GetName(), fd.GetOptions(), locs[pathKey([]int32{packagePath})], def)
+	if err != nil {
+		return err
+	}
+
+	scope := fileScope(fd)
+
+	for i, m := range fd.GetMessageType() {
+		if err = d.message(scope, m, []int32{messageTypePath, int32(i)}, lvl); err != nil {
+			return err
+		}
+	}
+
+	for i, e := range fd.GetEnumType() {
+		if err = d.enum(scope, e, []int32{enumTypePath, int32(i)}, lvl); err != nil {
+			return err
+		}
+	}
+
+	for i, x := range fd.GetExtension() {
+		if err = d.field(scope, x, []int32{extensionPath, int32(i)}, lvl); err != nil {
+			return err
+		}
+	}
+
+	for i, s := range fd.GetService() {
+		if err = d.service(scope, s, []int32{servicePath, int32(i)}, lvl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// visibilityDecider marks the elements of a single file that are less visible
+// than the minimum level as removed.
+type visibilityDecider struct {
+	*visibilityPruner
+	locs map[string]*descriptor.SourceCodeInfo_Location
+}
+
+func (d *visibilityDecider) loc(path []int32) *descriptor.SourceCodeInfo_Location {
+	return d.locs[pathKey(path)]
+}
+
+func (d *visibilityDecider) message(scope string, m *descriptor.DescriptorProto, path []int32, parent int) error {
+	fqn := scope + "." + m.GetName()
+
+	lvl, err := d.level(fqn, m.GetOptions(), d.loc(path), parent)
+	if err != nil {
+		return err
+	}
+
+	if lvl < d.min {
+		d.removeType(fqn)
+		return nil
+	}
+
+	oneofs := make([]int, len(m.GetOneofDecl()))
+	for i, o := range m.GetOneofDecl() {
+		ofqn := fqn + "." + o.GetName()
+		if oneofs[i], err = d.level(ofqn, o.GetOptions(), d.loc(subpath(path, messageTypeOneofDeclPath, int32(i))), lvl); err != nil {
+			return err
+		}
+		d.removed[ofqn] = oneofs[i] < d.min
+	}
+
+	for i, f := range m.GetField() {
+		inherited := lvl
+		if f.OneofIndex != nil {
+			if inherited = oneofs[f.GetOneofIndex()]; inherited < d.min {
+				d.removed[fqn+"."+f.GetName()] = true
+				continue
+			}
+		}
+
+		if err = d.field(fqn, f, subpath(path, messageTypeFieldPath, int32(i)), inherited); err != nil {
+			return err
+		}
+	}
+
+	for i, nm := range m.GetNestedType() {
+		if err = d.message(fqn, nm, subpath(path, messageTypeNestedTypePath, int32(i)), lvl); err != nil {
+			return err
+		}
+	}
+
+	for i, e := range m.GetEnumType() {
+		if err = d.enum(fqn, e, subpath(path, messageTypeEnumTypePath, int32(i)), lvl); err != nil {
+			return err
+		}
+	}
+
+	for i, x := range m.GetExtension() {
+		if err = d.field(fqn, x, subpath(path, messageTypeExtensionPath, int32(i)), lvl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *visibilityDecider) field(scope string, f *descriptor.FieldDescriptorProto, path []int32, parent int) error {
+	fqn := scope + "." + f.GetName()
+
+	lvl, err := d.level(fqn, f.GetOptions(), d.loc(path), parent)
+	if err != nil {
+		return err
+	}
+
+	d.removed[fqn] = lvl < d.min
+	return nil
+}
+
+func (d *visibilityDecider) enum(scope string, e *descriptor.EnumDescriptorProto, path []int32, parent int) error {
+	fqn := scope + "." + e.GetName()
+
+	lvl, err := d.level(fqn, e.GetOptions(), d.loc(path), parent)
+	if err != nil {
+		return err
+	}
+
+	if lvl < d.min {
+		d.removeType(fqn)
+		return nil
+	}
+
+	for i, v := range e.GetValue() {
+		vfqn := fqn + "." + v.GetName()
+
+		vl, err := d.level(vfqn, v.GetOptions(), d.loc(subpath(path, enumTypeValuePath, int32(i))), lvl)
+		if err != nil {
+			return err
+		}
+
+		d.removed[vfqn] = vl < d.min
+	}
+
+	return nil
+}
+
+func (d *visibilityDecider) service(scope string, s *descriptor.ServiceDescriptorProto, path []int32, parent int) error {
+	fqn := scope + "." + s.GetName()
+
+	lvl, err := d.level(fqn, s.GetOptions(), d.loc(path), parent)
+	if err != nil {
+		return err
+	}
+
+	d.removed[fqn] = lvl < d.min
+
+	for i, m := range s.GetMethod() {
+		mfqn := fqn + "." + m.GetName()
+
+		ml, err := d.level(mfqn, m.GetOptions(), d.loc(subpath(path, serviceTypeMethodPath, int32(i))), lvl)
+		if err != nil {
+			return err
+		}
+
+		d.removed[mfqn] = lvl < d.min || ml < d.min
+	}
+
+	return nil
+}
+
+// removeType marks the Message or Enum with the given FQN as removed, along
+// with all of its descendants.
+func (p *visibilityPruner) removeType(fqn string) {
+	p.removed[fqn] = true
+
+	switch t := p.types[fqn].(type) {
+	case *descriptor.DescriptorProto:
+		for _, f := range t.GetField() {
+			p.removed[fqn+"."+f.GetName()] = true
+		}
+		for _, x := range t.GetExtension() {
+			p.removed[fqn+"."+x.GetName()] = true
+		}
+		for _, o := range t.GetOneofDecl() {
+			p.removed[fqn+"."+o.GetName()] = true
+		}
+		for _, nm := range t.GetNestedType() {
+			p.removeType(fqn + "." + nm.GetName())
+		}
+		for _, e := range t.GetEnumType() {
+			p.removeType(fqn + "." + e.GetName())
+		}
+	case *descriptor.EnumDescriptorProto:
+		for _, v := range t.GetValue() {
+			p.removed[fqn+"."+v.GetName()] = true
+		}
+	}
+}
+
+// removeUnreachable removes the types declared in the files to generate that
+// were reachable from a removed entity, but can no longer be reached from any
+// remaining one. Reachability is marked from the entities that are kept, so
+// types that only reference themselves or each other are removed together.
+func (p *visibilityPruner) removeUnreachable(req *plugin_go.CodeGeneratorRequest) {
+	var (
+		refs          = make(map[string][]string) // referenced types by the type declaring the reference
+		roots, hidden []string                    // types referenced by kept non-types and removed entities
+	)
+
+	for _, fd := range req.GetProtoFile() {
+		eachReference(fd, func(from, to string) {
+			owner := p.parentType(from)
+			switch {
+			case p.removed[from]:
+				hidden = append(hidden, to)
+			case owner != "":
+				refs[owner] = append(refs[owner], to)
+			default:
+				roots = append(roots, to)
+			}
+		})
+	}
+
+	candidates := make(map[string]bool)
+
+	var collect, walk func(fqn string)
+	collect = func(fqn string) {
+		if !candidates[fqn] && p.types[fqn] != nil {
+			candidates[fqn] = true
+			walk(fqn)
+		}
+	}
+	walk = func(fqn string) {
+		for _, to := range refs[fqn] {
+			collect(to)
+		}
+		for _, child := range p.nestedTypes(fqn) {
+			walk(child)
+		}
+	}
+
+	for _, to := range hidden {
+		collect(to)
+	}
+
+	prunable := func(fqn string) bool {
+		return candidates[fqn] && !p.explicit[fqn] && p.targets[p.typeFiles[fqn]]
+	}
+
+	marked := make(map[string]bool)
+
+	var mark func(fqn string)
+	mark = func(fqn string) {
+		if marked[fqn] || p.removed[fqn] || p.types[fqn] == nil {
+			return
+		}
+		marked[fqn] = true
+
+		// a type is removed along with its parent, and keeps its children
+		// unless they may be pruned themselves
+		if parent := p.parentType(fqn); parent != "" {
+			mark(parent)
+		}
+		for _, child := range p.nestedTypes(fqn) {
+			if !prunable(child) {
+				mark(child)
+			}
+		}
+		for _, to := range refs[fqn] {
+			mark(to)
+		}
+	}
+
+	names := make([]string, 0, len(p.types))
+	for name := range p.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if p.parentType(name) == "" && !prunable(name) {
+			mark(name)
+		}
+	}
+	for _, to := range roots {
+		mark(to)
+	}
+
+	for _, name := range names {
+		if !marked[name] && !p.removed[name] && prunable(name) {
+			p.removeType(name)
+		}
+	}
+}
+
+// parentType returns the FQN of the Message declaring the element with the
+// given FQN, or an empty string if it is not declared within a Message.
+func (p *visibilityPruner) parentType(fqn string) string {
+	i := strings.LastIndexByte(fqn, '.')
+	if i <= 0 {
+		return ""
+	}
+
+	if _, ok := p.types[fqn[:i]].(*descriptor.DescriptorProto); ok {
+		return fqn[:i]
+	}
+	return ""
+}
+
+// nestedTypes returns the FQNs of the Messages and Enums declared directly
+// within the Message with the given FQN.
+func (p *visibilityPruner) nestedTypes(fqn string) []string {
+	m, ok := p.types[fqn].(*descriptor.DescriptorProto)
+	if !ok {
+		return nil
+	}
+
+	out := make([]string, 0, len(m.GetNestedType())+len(m.GetEnumType()))
+	for _, nm := range m.GetNestedType() {
+		out = append(out, fqn+"."+nm.GetName())
+	}
+	for _, e := range m.GetEnumType() {
+		out = append(out, fqn+"."+e.GetName())
+	}
+	return out
+}
+
+func (p *visibilityPruner) applyFile(fd *descriptor.FileDescriptorProto) {
+	scope := fileScope(fd)
+	remap := make(map[string][]int32)
+
+	var before map[string]bool
+	if p.targets[fd.GetName()] {
+		before = p.dependencies(fd, false)
+	}
+
+	fd.MessageType = pruneSlice(fd.MessageType, []int32{messageTypePath}, []int32{messageTypePath}, remap,
+		func(_ int, m *descriptor.DescriptorProto, oldPath, newPath []int32) bool {
+			fqn := scope + "." + m.GetName()
+			if p.removed[fqn] {
+				return false
+			}
+			p.applyMessage(fqn, m, oldPath, newPath, remap)
+			return true
+		})
+
+	fd.EnumType = pruneSlice(fd.EnumType, []int32{enumTypePath}, []int32{enumTypePath}, remap,
+		func(_ int, e *descriptor.EnumDescriptorProto, oldPath, newPath []int32) bool {
+			fqn := scope + "." + e.GetName()
+			if p.removed[fqn] {
+				return false
+			}
+			p.applyEnum(fqn, e, oldPath, newPath, remap)
+			return true
+		})
+
+	fd.Extension = pruneSlice(fd.Extension, []int32{extensionPath}, []int32{extensionPath}, remap,
+		func(_ int, x *descriptor.FieldDescriptorProto, _, _ []int32) bool {
+			return !p.removed[scope+"."+x.GetName()]
+		})
+
+	fd.Service = pruneSlice(fd.Service, []int32{servicePath}, []int32{servicePath}, remap,
+		func(_ int, s *descriptor.ServiceDescriptorProto, oldPath, newPath []int32) bool {
+			fqn := scope + "." + s.GetName()
+			if p.removed[fqn] {
+				return false
+			}
+			s.Method = pruneSlice(s.Method, subpath(oldPath, serviceTypeMethodPath), subpath(newPath, serviceTypeMethodPath), remap,
+				func(_ int, m *descriptor.MethodDescriptorProto, _, _ []int32) bool {
+					return !p.removed[fqn+"."+m.GetName()]
+				})
+			return true
+		})
+
+	if before != nil {
+		p.applyDependencies(fd, before, remap)
+	}
+
+	if info := fd.GetSourceCodeInfo(); info != nil {
+		locs := info.Location[:0]
+		for _, loc := range info.Location {
+			if path, ok := remapPath(loc.GetPath(), remap); ok {
+				loc.Path = path
+				locs = append(locs, loc)
+			}
+		}
+		info.Location = locs
+	}
+}
+
+func (p *visibilityPruner) applyMessage(fqn string, m *descriptor.DescriptorProto, oldPath, newPath []int32, remap map[string][]int32) {
+	fields := make([]int, len(m.GetOneofDecl()))
+
+	m.Field = pruneSlice(m.Field, subpath(oldPath, messageTypeFieldPath), subpath(newPath, messageTypeFieldPath), remap,
+		func(_ int, f *descriptor.FieldDescriptorProto, _, _ []int32) bool {
+			if p.removed[fqn+"."+f.GetName()] {
+				return false
+			}
+			if f.OneofIndex != nil {
+				fields[f.GetOneofIndex()]++
+			}
+			return true
+		})
+
+	oneofs := make([]int32, len(m.GetOneofDecl()))
+	m.OneofDecl = pruneSlice(m.OneofDecl, subpath(oldPath, messageTypeOneofDeclPath), subpath(newPath, messageTypeOneofDeclPath), remap,
+		func(i int, o *descriptor.OneofDescriptorProto, _, newPath []int32) bool {
+			oneofs[i] = newPath[len(newPath)-1]
+			return fields[i] > 0 && !p.removed[fqn+"."+o.GetName()]
+		})
+
+	for _, f := range m.Field {
+		if f.OneofIndex != nil {
+			f.OneofIndex = proto.Int32(oneofs[f.GetOneofIndex()])
+		}
+	}
+
+	m.NestedType = pruneSlice(m.NestedType, subpath(oldPath, messageTypeNestedTypePath), subpath(newPath, messageTypeNestedTypePath), remap,
+		func(_ int, nm *descriptor.DescriptorProto, o, n []int32) bool {
+			nfqn := fqn + "." + nm.GetName()
+			if p.removed[nfqn] {
+				return false
+			}
+			p.applyMessage(nfqn, nm, o, n, remap)
+			return true
+		})
+
+	m.EnumType = pruneSlice(m.EnumType, subpath(oldPath, messageTypeEnumTypePath), subpath(newPath, messageTypeEnumTypePath), remap,
+		func(_ int, e *descriptor.EnumDescriptorProto, o, n []int32) bool {
+			efqn := fqn + "." + e.GetName()
+			if p.removed[efqn] {
+				return false
+			}
+			p.applyEnum(efqn, e, o, n, remap)
+			return true
+		})
+
+	m.Extension = pruneSlice(m.Extension, subpath(oldPath, messageTypeExtensionPath), subpath(newPath, messageTypeExtensionPath), remap,
+		func(_ int, x *descriptor.FieldDescriptorProto, _, _ []int32) bool {
+			return !p.removed[fqn+"."+x.GetName()]
+		})
+}
+
+func (p *visibilityPruner) applyEnum(fqn string, e *descriptor.EnumDescriptorProto, oldPath, newPath []int32, remap map[string][]int32) {
+	e.Value = pruneSlice(e.Value, subpath(oldPath, enumTypeValuePath), subpath(newPath, enumTypeValuePath), remap,
+		func(_ int, v *descriptor.EnumValueDescriptorProto, _, _ []int32) bool {
+			return !p.removed[fqn+"."+v.GetName()]
+		})
+}
+
+// dependencies returns the files declaring the types referenced by fd. If
+// retained is true, only references from remaining entities are considered.
+func (p *visibilityPruner) dependencies(fd *descriptor.FileDescriptorProto, retained bool) map[string]bool {
+	deps := make(map[string]bool)
+	eachReference(fd, func(from, to string) {
+		if !retained || !p.removed[from] {
+			deps[p.typeFiles[to]] = true
+		}
+	})
+	return deps
+}
+
+// applyDependencies drops the non-public imports of fd that were required by
+// its types before pruning, but are no longer.
+func (p *visibilityPruner) applyDependencies(fd *descriptor.FileDescriptorProto, before map[string]bool, remap map[string][]int32) {
+	after := p.dependencies(fd, true)
+
+	public := make(map[int32]bool)
+	for _, i := range fd.GetPublicDependency() {
+		public[i] = true
+	}
+
+	indices := make([]int32, len(fd.GetDependency()))
+	fd.Dependency = pruneSlice(fd.Dependency, []int32{dependencyPath}, []int32{dependencyPath}, remap,
+		func(i int, dep string, _, newPath []int32) bool {
+			indices[i] = newPath[len(newPath)-1]
+			return public[int32(i)] || !before[dep] || after[dep]
+		})
+
+	for i, dep := range fd.PublicDependency {
+		fd.PublicDependency[i] = indices[dep]
+	}
+
+	fd.WeakDependency = pruneSlice(fd.WeakDependency, []int32{weakDependencyPath}, []int32{weakDependencyPath}, remap,
+		func(_ int, dep int32, _, _ []int32) bool {
+			return remap[pathKey([]int32{dependencyPath, dep})] != nil
+		})
+	for i, dep := range fd.WeakDependency {
+		fd.WeakDependency[i] = indices[dep]
+	}
+}
+
+// pruneSlice removes the elements of list for which keep returns false,
+// recording the new path of each element (or nil if removed) in remap. The
+// old and new paths of the elements are formed from oldBase and newBase,
+// respectively.
+func pruneSlice[T any](list []T, oldBase, newBase []int32, remap map[string][]int32, keep func(i int, el T, oldPath, newPath []int32) bool) []T {
+	out := list[:0]
+	for i, el := range list {
+		oldPath := subpath(oldBase, int32(i))
+		newPath := subpath(newBase, int32(len(out)))
+
+		if keep(i, el, oldPath, newPath) {
+			remap[pathKey(oldPath)] = newPath
+			out = append(out, el)
+		} else {
+			remap[pathKey(oldPath)] = nil
+		}
+	}
+
+	return out
+}
+
+// remapPath resolves the new path of a source location from the remapped
+// paths of the elements containing it. False is returned if the location is
+// within a removed element.
+func remapPath(path []int32, remap map[string][]int32) ([]int32, bool) {
+	for n := len(path); n > 0; n-- {
+		np, ok := remap[pathKey(path[:n])]
+		if !ok {
+			continue
+		}
+
+		if np == nil {
+			return nil, false
+		}
+
+		return subpath(np, path[n:]...), true
+	}
+
+	return path, true
+}
+
+// eachReference calls fn with the FQN of each element in fd that references a
+// type, along with that type's FQN.
+func eachReference(fd *descriptor.FileDescriptorProto, fn func(from, to string)) {
+	scope := fileScope(fd)
+
+	field := func(scope string, f *descriptor.FieldDescriptorProto) {
+		fqn := scope + "." + f.GetName()
+		if f.GetTypeName() != "" {
+			fn(fqn, f.GetTypeName())
+		}
+		if f.GetExtendee() != "" {
+			fn(fqn, f.GetExtendee())
+		}
+	}
+
+	var message func(scope string, m *descriptor.DescriptorProto)
+	message = func(scope string, m *descriptor.DescriptorProto) {
+		fqn := scope + "." + m.GetName()
+		for _, f := range m.GetField() {
+			field(fqn, f)
+		}
+		for _, x := range m.GetExtension() {
+			field(fqn, x)
+		}
+		for _, nm := range m.GetNestedType() {
+			message(fqn, nm)
+		}
+	}
+
+	for _, m := range fd.GetMessageType() {
+		message(scope, m)
+	}
+
+	for _, x := range fd.GetExtension() {
+		field(scope, x)
+	}
+
+	for _, s := range fd.GetService() {
+		for _, m := range s.GetMethod() {
+			fqn := scope + "." + s.GetName() + "." + m.GetName()
+			fn(fqn, m.GetInputType())
+			fn(fqn, m.GetOutputType())
+		}
+	}
+}
+
+func fileScope(fd *descriptor.FileDescriptorProto) string {
+	if pkg := fd.GetPackage(); pkg != "" {
+		return "." + pkg
+	}
+	return ""
+}
+
+func subpath(path []int32, elems ...int32) []int32 {
+	return append(append(make([]int32, 0, len(path)+len(elems)), path...), elems...)
+}
+
+func pathKey(path []int32) string { return fmt.Sprint(path) }
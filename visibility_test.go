@@ -0,0 +1,280 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+func dummyVisibilityRequest() *plugin_go.CodeGeneratorRequest {
+	fld := func(name string, num int32, typ ProtoType, typeName string) *descriptor.FieldDescriptorProto {
+		fd := &descriptor.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    Optional.ProtoPtr(),
+			Type:     typ.ProtoPtr(),
+		}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		return fd
+	}
+
+	oneof := func(fd *descriptor.FieldDescriptorProto, idx int32) *descriptor.FieldDescriptorProto {
+		fd.OneofIndex = proto.Int32(idx)
+		return fd
+	}
+
+	loc := func(comment string, path ...int32) *descriptor.SourceCodeInfo_Location {
+		return &descriptor.SourceCodeInfo_Location{Path: path, Span: []int32{0, 0, 1}, LeadingComments: proto.String(comment)}
+	}
+
+	internal := "+visibility=internal\n"
+
+	return &plugin_go.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo.proto"},
+		ProtoFile: []*descriptor.FileDescriptorProto{
+			{
+				Name:        proto.String("other.proto"),
+				Package:     proto.String("other"),
+				Syntax:      proto.String("proto3"),
+				MessageType: []*descriptor.DescriptorProto{{Name: proto.String("Other")}},
+			},
+			{
+				Name:        proto.String("bar.proto"),
+				Package:     proto.String("bar"),
+				Syntax:      proto.String("proto3"),
+				MessageType: []*descriptor.DescriptorProto{{Name: proto.String("Bar")}},
+			},
+			{
+				Name:       proto.String("foo.proto"),
+				Package:    proto.String("foo"),
+				Syntax:     proto.String("proto3"),
+				Dependency: []string{"other.proto", "bar.proto"},
+				MessageType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("Public"),
+						Field: []*descriptor.FieldDescriptorProto{
+							fld("id", 1, Int64T, ""),
+							fld("secret", 2, MessageT, ".foo.Detail"),
+							fld("other", 3, MessageT, ".other.Other"),
+							fld("bar", 4, MessageT, ".bar.Bar"),
+							oneof(fld("a", 5, StringT, ""), 0),
+							oneof(fld("b", 6, StringT, ""), 0),
+							oneof(fld("c", 7, StringT, ""), 1),
+							fld("kept", 8, MessageT, ".foo.Kept"),
+						},
+						OneofDecl: []*descriptor.OneofDescriptorProto{
+							{Name: proto.String("choice")},
+							{Name: proto.String("hidden")},
+						},
+					},
+					{Name: proto.String("Detail")},
+					{Name: proto.String("Kept")},
+					{Name: proto.String("Hidden")},
+				},
+				Service: []*descriptor.ServiceDescriptorProto{{
+					Name: proto.String("Svc"),
+					Method: []*descriptor.MethodDescriptorProto{
+						{Name: proto.String("Admin"), InputType: proto.String(".foo.Hidden"), OutputType: proto.String(".foo.Hidden")},
+						{Name: proto.String("Get"), InputType: proto.String(".foo.Public"), OutputType: proto.String(".foo.Public")},
+					},
+				}},
+				SourceCodeInfo: &descriptor.SourceCodeInfo{Location: []*descriptor.SourceCodeInfo_Location{
+					loc("public", messageTypePath, 0),
+					loc(internal, messageTypePath, 0, messageTypeFieldPath, 1),
+					loc(internal, messageTypePath, 0, messageTypeFieldPath, 2),
+					loc("bar field", messageTypePath, 0, messageTypeFieldPath, 3),
+					loc(internal, messageTypePath, 0, messageTypeFieldPath, 5),
+					loc("c field", messageTypePath, 0, messageTypeFieldPath, 6),
+					loc(internal, messageTypePath, 0, messageTypeOneofDeclPath, 1),
+					loc("kept", messageTypePath, 2),
+					loc(internal, messageTypePath, 3),
+					loc(internal, servicePath, 0, serviceTypeMethodPath, 0),
+					loc("get", servicePath, 0, serviceTypeMethodPath, 1),
+					loc("import bar", dependencyPath, 1),
+				}},
+			},
+		},
+	}
+}
+
+func dummyVisibilityFilter() VisibilityFilter {
+	return VisibilityFilter{
+		Levels:  []string{"internal", "public"},
+		Sources: []VisibilityFunc{VisibilityDirective("+", "visibility")},
+	}
+}
+
+func TestVisibilityFilter_Transform(t *testing.T) {
+	t.Parallel()
+
+	vf := dummyVisibilityFilter()
+	assert.Equal(t, "visibility", vf.Name())
+
+	req := dummyVisibilityRequest()
+	require.NoError(t, vf.Transform(req, Parameters{visibilityKey: "PUBLIC"}))
+	require.NoError(t, validateRequest(req))
+
+	fd := req.ProtoFile[2]
+	assert.Equal(t, []string{"bar.proto"}, fd.Dependency)
+
+	d := InitMockDebugger()
+	ast := ProcessCodeGeneratorRequest(d, req)
+	require.False(t, d.Failed())
+
+	for _, name := range []string{".foo.Public.secret", ".foo.Public.other", ".foo.Public.b",
+		".foo.Public.c", ".foo.Public.hidden", ".foo.Detail", ".foo.Hidden", ".foo.Svc.Admin"} {
+		_, ok := ast.Lookup(name)
+		assert.False(t, ok, name)
+	}
+
+	lookup := func(name string) Entity {
+		e, ok := ast.Lookup(name)
+		require.True(t, ok, name)
+		return e
+	}
+
+	pub := lookup(".foo.Public").(Message)
+	assert.Equal(t, "public", pub.SourceCodeInfo().LeadingComments())
+	assert.Len(t, pub.Fields(), 4)
+	assert.Len(t, pub.OneOfs(), 1)
+	assert.Len(t, pub.OneOfs()[0].Fields(), 1)
+
+	assert.Equal(t, "bar field", lookup(".foo.Public.bar").SourceCodeInfo().LeadingComments())
+	assert.Equal(t, "kept", lookup(".foo.Kept").SourceCodeInfo().LeadingComments())
+	assert.Equal(t, "get", lookup(".foo.Svc.Get").SourceCodeInfo().LeadingComments())
+	assert.Equal(t, "import bar", fd.SourceCodeInfo.Location[len(fd.SourceCodeInfo.Location)-1].GetLeadingComments())
+	assert.Equal(t, []int32{dependencyPath, 0}, fd.SourceCodeInfo.Location[len(fd.SourceCodeInfo.Location)-1].GetPath())
+}
+
+func TestVisibilityFilter_Transform_Cycles(t *testing.T) {
+	t.Parallel()
+
+	ref := func(name, typeName string) *descriptor.FieldDescriptorProto {
+		return &descriptor.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(1),
+			Label:    Optional.ProtoPtr(),
+			Type:     MessageT.ProtoPtr(),
+			TypeName: proto.String(typeName),
+		}
+	}
+
+	req := dummyVisibilityRequest()
+	fd := req.ProtoFile[2]
+	fd.MessageType[1].Field = []*descriptor.FieldDescriptorProto{ref("next", ".foo.Detail"), ref("ping", ".foo.Ping")}
+	fd.MessageType = append(fd.MessageType,
+		&descriptor.DescriptorProto{Name: proto.String("Ping"), Field: []*descriptor.FieldDescriptorProto{ref("pong", ".foo.Pong")}},
+		&descriptor.DescriptorProto{Name: proto.String("Pong"), Field: []*descriptor.FieldDescriptorProto{ref("ping", ".foo.Ping")}},
+	)
+
+	require.NoError(t, dummyVisibilityFilter().Transform(req, Parameters{visibilityKey: "public"}))
+	require.NoError(t, validateRequest(req))
+
+	var names []string
+	for _, m := range fd.MessageType {
+		names = append(names, m.GetName())
+	}
+	assert.Equal(t, []string{"Public", "Kept"}, names)
+}
+
+func TestVisibilityFilter_Transform_NoParam(t *testing.T) {
+	t.Parallel()
+
+	req := dummyVisibilityRequest()
+	orig := proto.Clone(req)
+
+	require.NoError(t, dummyVisibilityFilter().Transform(req, Parameters{}))
+	assert.True(t, proto.Equal(orig, req))
+
+	require.NoError(t, dummyVisibilityFilter().Transform(req, Parameters{visibilityKey: "internal"}))
+	assert.True(t, proto.Equal(orig, req))
+}
+
+func TestVisibilityFilter_Transform_Errors(t *testing.T) {
+	t.Parallel()
+
+	vf := dummyVisibilityFilter()
+
+	assert.Error(t, vf.Transform(dummyVisibilityRequest(), Parameters{visibilityKey: "partner"}))
+
+	vf.Default = "partner"
+	assert.Error(t, vf.Transform(dummyVisibilityRequest(), Parameters{visibilityKey: "public"}))
+
+	t.Run("unknown level", func(t *testing.T) {
+		t.Parallel()
+
+		req := dummyVisibilityRequest()
+		req.ProtoFile[2].SourceCodeInfo.Location[0].LeadingComments = proto.String("+visibility=partner")
+		assert.EqualError(t, dummyVisibilityFilter().Transform(req, Parameters{visibilityKey: "public"}),
+			`foo.proto: .foo.Public: unknown visibility level "partner"`)
+	})
+
+	t.Run("removed reference", func(t *testing.T) {
+		t.Parallel()
+
+		req := dummyVisibilityRequest()
+		req.ProtoFile[2].MessageType[0].Field[0] = &descriptor.FieldDescriptorProto{
+			Name:     proto.String("id"),
+			Number:   proto.Int32(1),
+			Label:    Optional.ProtoPtr(),
+			Type:     MessageT.ProtoPtr(),
+			TypeName: proto.String(".foo.Hidden"),
+		}
+
+		assert.EqualError(t, dummyVisibilityFilter().Transform(req, Parameters{visibilityKey: "public"}),
+			`visibility "public": .foo.Public.id references removed type .foo.Hidden`)
+	})
+}
+
+func TestVisibilityFilter_Transform_Default(t *testing.T) {
+	t.Parallel()
+
+	vf := dummyVisibilityFilter()
+	vf.Default = "internal"
+
+	req := dummyVisibilityRequest()
+	require.NoError(t, vf.Transform(req, Parameters{visibilityKey: "public"}))
+	require.NoError(t, validateRequest(req))
+
+	assert.Empty(t, req.ProtoFile[2].MessageType)
+	assert.Len(t, req.ProtoFile[0].MessageType, 1, "dependencies should not use the default")
+	assert.Len(t, req.ProtoFile[1].MessageType, 1, "dependencies should not use the default")
+}
+
+func TestVisibilityOption(t *testing.T) {
+	t.Parallel()
+
+	fn := VisibilityOption(nil)
+
+	lvl, err := fn((*descriptor.MessageOptions)(nil), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, lvl)
+}
+
+func TestRemapPath(t *testing.T) {
+	t.Parallel()
+
+	remap := map[string][]int32{
+		pathKey([]int32{4, 0}): {4, 0},
+		pathKey([]int32{4, 1}): nil,
+		pathKey([]int32{4, 2}): {4, 1},
+	}
+
+	p, ok := remapPath([]int32{4, 2, 2, 0, 1}, remap)
+	assert.True(t, ok)
+	assert.Equal(t, []int32{4, 1, 2, 0, 1}, p)
+
+	_, ok = remapPath([]int32{4, 1, 1}, remap)
+	assert.False(t, ok)
+
+	p, ok = remapPath([]int32{12}, remap)
+	assert.True(t, ok)
+	assert.Equal(t, []int32{12}, p)
+}
@@ -0,0 +1,145 @@
+package pgs
+
+// VisitFunc is a Visitor composed of optional callbacks for each Node type.
+// Nil callbacks are skipped, and the children of every Node are always
+// visited. Any error returned by a callback will immediately halt execution.
+//
+//	err := pgs.Walk(pgs.VisitFunc{
+//		Message: func(m pgs.Message) error { ... },
+//		Field:   func(f pgs.Field) error { ... },
+//	}, file)
+type VisitFunc struct {
+	Package   func(Package) error
+	File      func(File) error
+	Message   func(Message) error
+	Enum      func(Enum) error
+	EnumValue func(EnumValue) error
+	Field     func(Field) error
+	Extension func(Extension) error
+	OneOf     func(OneOf) error
+	Service   func(Service) error
+	Method    func(Method) error
+}
+
+// VisitPackage satisfies the Visitor interface, calling vf.Package if set.
+func (vf VisitFunc) VisitPackage(p Package) (Visitor, error) {
+	if vf.Package == nil {
+		return vf, nil
+	}
+	return vf, vf.Package(p)
+}
+
+// VisitFile satisfies the Visitor interface, calling vf.File if set.
+func (vf VisitFunc) VisitFile(f File) (Visitor, error) {
+	if vf.File == nil {
+		return vf, nil
+	}
+	return vf, vf.File(f)
+}
+
+// VisitMessage satisfies the Visitor interface, calling vf.Message if set.
+func (vf VisitFunc) VisitMessage(m Message) (Visitor, error) {
+	if vf.Message == nil {
+		return vf, nil
+	}
+	return vf, vf.Message(m)
+}
+
+// VisitEnum satisfies the Visitor interface, calling vf.Enum if set.
+func (vf VisitFunc) VisitEnum(e Enum) (Visitor, error) {
+	if vf.Enum == nil {
+		return vf, nil
+	}
+	return vf, vf.Enum(e)
+}
+
+// VisitEnumValue satisfies the Visitor interface, calling vf.EnumValue if set.
+func (vf VisitFunc) VisitEnumValue(ev EnumValue) (Visitor, error) {
+	if vf.EnumValue == nil {
+		return vf, nil
+	}
+	return vf, vf.EnumValue(ev)
+}
+
+// VisitField satisfies the Visitor interface, calling vf.Field if set.
+func (vf VisitFunc) VisitField(f Field) (Visitor, error) {
+	if vf.Field == nil {
+		return vf, nil
+	}
+	return vf, vf.Field(f)
+}
+
+// VisitExtension satisfies the Visitor interface, calling vf.Extension if set.
+func (vf VisitFunc) VisitExtension(e Extension) (Visitor, error) {
+	if vf.Extension == nil {
+		return vf, nil
+	}
+	return vf, vf.Extension(e)
+}
+
+// VisitOneOf satisfies the Visitor interface, calling vf.OneOf if set.
+func (vf VisitFunc) VisitOneOf(o OneOf) (Visitor, error) {
+	if vf.OneOf == nil {
+		return vf, nil
+	}
+	return vf, vf.OneOf(o)
+}
+
+// VisitService satisfies the Visitor interface, calling vf.Service if set.
+func (vf VisitFunc) VisitService(s Service) (Visitor, error) {
+	if vf.Service == nil {
+		return vf, nil
+	}
+	return vf, vf.Service(s)
+}
+
+// VisitMethod satisfies the Visitor interface, calling vf.Method if set.
+func (vf VisitFunc) VisitMethod(m Method) (Visitor, error) {
+	if vf.Method == nil {
+		return vf, nil
+	}
+	return vf, vf.Method(m)
+}
+
+// Collect walks Node n and returns it and all of its descendants of type T
+// for which pred returns true, in the order they are visited by Walk. If pred
+// is nil, all entities of type T are returned. The WalkOptions are passed
+// through to Walk.
+//
+//	msgs := pgs.Collect(file, func(m pgs.Message) bool { return m.IsMapEntry() })
+//
+// Note that an Extension is also a Field, so Collect[Field] includes any
+// visited Extensions.
+func Collect[T Entity](n Node, pred func(T) bool, opts ...WalkOption) []T {
+	c := &collector[T]{pred: pred}
+	_ = Walk(c, n, opts...) // collector never errors
+	return c.out
+}
+
+type collector[T Entity] struct {
+	pred func(T) bool
+	out  []T
+}
+
+func (c *collector[T]) add(n Node) (Visitor, error) {
+	if e, ok := n.(T); ok && (c.pred == nil || c.pred(e)) {
+		c.out = append(c.out, e)
+	}
+	return c, nil
+}
+
+func (c *collector[T]) VisitPackage(p Package) (Visitor, error)      { return c.add(p) }
+func (c *collector[T]) VisitFile(f File) (Visitor, error)            { return c.add(f) }
+func (c *collector[T]) VisitMessage(m Message) (Visitor, error)      { return c.add(m) }
+func (c *collector[T]) VisitEnum(e Enum) (Visitor, error)            { return c.add(e) }
+func (c *collector[T]) VisitEnumValue(ev EnumValue) (Visitor, error) { return c.add(ev) }
+func (c *collector[T]) VisitField(f Field) (Visitor, error)          { return c.add(f) }
+func (c *collector[T]) VisitExtension(e Extension) (Visitor, error)  { return c.add(e) }
+func (c *collector[T]) VisitOneOf(o OneOf) (Visitor, error)          { return c.add(o) }
+func (c *collector[T]) VisitService(s Service) (Visitor, error)      { return c.add(s) }
+func (c *collector[T]) VisitMethod(m Method) (Visitor, error)        { return c.add(m) }
+
+var (
+	_ Visitor = VisitFunc{}
+	_ Visitor = (*collector[Entity])(nil)
+)
@@ -0,0 +1,116 @@
+package pgs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisitFunc(t *testing.T) {
+	t.Parallel()
+
+	var msgs, flds []string
+	vf := VisitFunc{
+		Message: func(m Message) error {
+			msgs = append(msgs, m.Name().String())
+			return nil
+		},
+		Field: func(f Field) error {
+			flds = append(flds, f.Name().String())
+			return nil
+		},
+	}
+
+	require.NoError(t, Walk(vf, dummyScopedTree().Package()))
+	assert.Equal(t, []string{"Outer", "Inner", "Other"}, msgs)
+	assert.Equal(t, []string{"a", "b", "c"}, flds)
+}
+
+func TestVisitFunc_Error(t *testing.T) {
+	t.Parallel()
+
+	e := errors.New("TestVisitFunc_Error")
+	calls := 0
+	vf := VisitFunc{Field: func(Field) error {
+		calls++
+		return e
+	}}
+
+	assert.Equal(t, e, Walk(vf, dummyScopedTree()))
+	assert.Equal(t, 1, calls)
+}
+
+func TestVisitFunc_Callbacks(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	call := func() error { calls++; return nil }
+
+	empty := VisitFunc{}
+	vf := VisitFunc{
+		Package:   func(Package) error { return call() },
+		File:      func(File) error { return call() },
+		Message:   func(Message) error { return call() },
+		Enum:      func(Enum) error { return call() },
+		EnumValue: func(EnumValue) error { return call() },
+		Field:     func(Field) error { return call() },
+		Extension: func(Extension) error { return call() },
+		OneOf:     func(OneOf) error { return call() },
+		Service:   func(Service) error { return call() },
+		Method:    func(Method) error { return call() },
+	}
+
+	for _, v := range []VisitFunc{empty, vf} {
+		for _, fn := range []func() (Visitor, error){
+			func() (Visitor, error) { return v.VisitPackage(&pkg{}) },
+			func() (Visitor, error) { return v.VisitFile(&file{}) },
+			func() (Visitor, error) { return v.VisitMessage(&msg{}) },
+			func() (Visitor, error) { return v.VisitEnum(&enum{}) },
+			func() (Visitor, error) { return v.VisitEnumValue(&enumVal{}) },
+			func() (Visitor, error) { return v.VisitField(&field{}) },
+			func() (Visitor, error) { return v.VisitExtension(&ext{}) },
+			func() (Visitor, error) { return v.VisitOneOf(&oneof{}) },
+			func() (Visitor, error) { return v.VisitService(&service{}) },
+			func() (Visitor, error) { return v.VisitMethod(&method{}) },
+		} {
+			w, err := fn()
+			assert.NotNil(t, w)
+			assert.NoError(t, err)
+		}
+	}
+
+	assert.Equal(t, 10, calls)
+}
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	f := dummyScopedTree()
+
+	msgs := Collect[Message](f, nil)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "Outer", msgs[0].Name().String())
+
+	flds := Collect(f, func(fld Field) bool { return fld.Name() != "b" })
+	require.Len(t, flds, 2)
+	assert.Equal(t, "a", flds[0].Name().String())
+	assert.Equal(t, "c", flds[1].Name().String())
+
+	files := Collect[File](f.Package(), nil)
+	assert.Equal(t, []File{f}, files)
+
+	assert.Len(t, Collect[Entity](f, nil), 7)
+	assert.Empty(t, Collect[Service](f, nil))
+}
+
+func TestCollect_Options(t *testing.T) {
+	t.Parallel()
+
+	f := dummyWalkTree()
+
+	assert.Len(t, Collect[Message](f, nil), 1)
+	assert.Len(t, Collect[Message](f, nil, IncludeMapEntries()), 2)
+	assert.Len(t, Collect[Extension](f, nil, ExtendeeExtensions()), 1)
+}
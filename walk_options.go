@@ -0,0 +1,194 @@
+package pgs
+
+// WalkOption configures which Nodes are visited by Walk and WalkScoped.
+type WalkOption func(o *walkOptions)
+
+type walkOptions struct {
+	mapEntries    bool
+	skipSynthetic bool
+	targetsOnly   bool
+	followImports bool
+	extendee      bool
+
+	seen map[Node]struct{}
+}
+
+// IncludeMapEntries visits the synthetic map entry Messages of each Message,
+// immediately after its nested Messages. These are skipped by default.
+func IncludeMapEntries() WalkOption { return func(o *walkOptions) { o.mapEntries = true } }
+
+// SkipSyntheticOneOfs skips the synthetic OneOfs generated for proto3 optional
+// fields. The fields themselves are still visited.
+func SkipSyntheticOneOfs() WalkOption { return func(o *walkOptions) { o.skipSynthetic = true } }
+
+// BuildTargetsOnly skips any Entity (and its children) that is not a build
+// target. Packages are still visited, but only their target Files are.
+func BuildTargetsOnly() WalkOption { return func(o *walkOptions) { o.targetsOnly = true } }
+
+// FollowImports visits the Files imported by each visited File after its own
+// children, and the Packages imported by each visited Package after its Files.
+// Each File and Package is visited at most once per walk.
+func FollowImports() WalkOption {
+	return func(o *walkOptions) {
+		o.followImports = true
+		o.seen = make(map[Node]struct{})
+	}
+}
+
+// ExtendeeExtensions visits the Extensions applied to each Message after its
+// defined Extensions. An Extension defined within a visited scope may be
+// visited twice: once from where it is defined, and again from its extendee.
+func ExtendeeExtensions() WalkOption { return func(o *walkOptions) { o.extendee = true } }
+
+func newWalkOptions(opts []WalkOption) *walkOptions {
+	o := &walkOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// include returns true if Node n should be visited.
+func (o *walkOptions) include(n Node) bool {
+	if o.targetsOnly {
+		if e, ok := n.(Entity); ok && !e.BuildTarget() {
+			return false
+		}
+	}
+
+	if o.skipSynthetic {
+		if oo, ok := n.(OneOf); ok && oo.IsSynthetic() {
+			return false
+		}
+	}
+
+	if o.followImports {
+		switch n.(type) {
+		case Package, File:
+			if _, seen := o.seen[n]; seen {
+				return false
+			}
+			o.seen[n] = struct{}{}
+		}
+	}
+
+	return true
+}
+
+// children returns the children of n in the same order they are visited by
+// the accept methods used by Walk, including any additional Nodes enabled by
+// the options.
+func (o *walkOptions) children(n Node) (out []Node) {
+	switch n := n.(type) {
+	case Package:
+		for _, f := range n.Files() {
+			out = append(out, f)
+		}
+		if o.followImports {
+			for _, p := range n.Imports() {
+				out = append(out, p)
+			}
+		}
+	case File:
+		for _, e := range n.Enums() {
+			out = append(out, e)
+		}
+		for _, m := range n.Messages() {
+			out = append(out, m)
+		}
+		for _, s := range n.Services() {
+			out = append(out, s)
+		}
+		for _, ext := range n.DefinedExtensions() {
+			out = append(out, ext)
+		}
+		if o.followImports {
+			for _, f := range n.Imports() {
+				out = append(out, f)
+			}
+		}
+	case Message:
+		for _, e := range n.Enums() {
+			out = append(out, e)
+		}
+		for _, m := range n.Messages() {
+			out = append(out, m)
+		}
+		if o.mapEntries {
+			for _, m := range n.MapEntries() {
+				out = append(out, m)
+			}
+		}
+		for _, f := range n.Fields() {
+			out = append(out, f)
+		}
+		for _, oo := range n.OneOfs() {
+			out = append(out, oo)
+		}
+		for _, ext := range n.DefinedExtensions() {
+			out = append(out, ext)
+		}
+		if o.extendee {
+			for _, ext := range n.Extensions() {
+				out = append(out, ext)
+			}
+		}
+	case Enum:
+		for _, ev := range n.Values() {
+			out = append(out, ev)
+		}
+	case Service:
+		for _, m := range n.Methods() {
+			out = append(out, m)
+		}
+	}
+
+	return out
+}
+
+// walk visits n and its children with v, honoring the options. It mirrors the
+// accept methods on each Node.
+func (o *walkOptions) walk(v Visitor, n Node) (err error) {
+	if v == nil || !o.include(n) {
+		return nil
+	}
+
+	switch n := n.(type) {
+	case Package:
+		v, err = v.VisitPackage(n)
+	case File:
+		v, err = v.VisitFile(n)
+	case Message:
+		v, err = v.VisitMessage(n)
+	case Enum:
+		v, err = v.VisitEnum(n)
+	case EnumValue:
+		v, err = v.VisitEnumValue(n)
+	case Extension:
+		v, err = v.VisitExtension(n)
+	case Field:
+		v, err = v.VisitField(n)
+	case OneOf:
+		// OneOf fields are visited from the Message, not the OneOf.
+		_, err = v.VisitOneOf(n)
+		return err
+	case Service:
+		v, err = v.VisitService(n)
+	case Method:
+		v, err = v.VisitMethod(n)
+	default:
+		return n.accept(v)
+	}
+
+	if err != nil || v == nil {
+		return err
+	}
+
+	for _, ch := range o.children(n) {
+		if err = o.walk(v, ch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+type namingVisitor struct {
+	names []string
+}
+
+func (v *namingVisitor) add(n string) (Visitor, error) {
+	v.names = append(v.names, n)
+	return v, nil
+}
+
+func (v *namingVisitor) VisitPackage(p Package) (Visitor, error) {
+	return v.add(p.ProtoName().String())
+}
+func (v *namingVisitor) VisitFile(f File) (Visitor, error)           { return v.add(f.Name().String()) }
+func (v *namingVisitor) VisitMessage(m Message) (Visitor, error)     { return v.add(m.Name().String()) }
+func (v *namingVisitor) VisitEnum(e Enum) (Visitor, error)           { return v.add(e.Name().String()) }
+func (v *namingVisitor) VisitEnumValue(e EnumValue) (Visitor, error) { return v.add(e.Name().String()) }
+func (v *namingVisitor) VisitField(f Field) (Visitor, error)         { return v.add(f.Name().String()) }
+func (v *namingVisitor) VisitExtension(e Extension) (Visitor, error) { return v.add(e.Name().String()) }
+func (v *namingVisitor) VisitOneOf(o OneOf) (Visitor, error)         { return v.add(o.Name().String()) }
+func (v *namingVisitor) VisitService(s Service) (Visitor, error)     { return v.add(s.Name().String()) }
+func (v *namingVisitor) VisitMethod(m Method) (Visitor, error)       { return v.add(m.Name().String()) }
+
+// dummyWalkTree returns a build target File importing a File from another
+// Package, which defines an Extension of the target's Message.
+func dummyWalkTree() *file {
+	f := dummyFile()
+	f.buildTarget = true
+
+	depPkg := &pkg{fd: &descriptor.FileDescriptorProto{Package: proto.String("dep_pkg")}}
+	dep := &file{desc: &descriptor.FileDescriptorProto{
+		Package: proto.String("dep_pkg"),
+		Syntax:  proto.String(string(Proto3)),
+		Name:    proto.String("dep.proto"),
+	}}
+	depPkg.addFile(dep)
+	dep.addMessage(&msg{desc: &descriptor.DescriptorProto{Name: proto.String("Dep")}})
+	f.addFileDependency(dep)
+
+	m := &msg{desc: &descriptor.DescriptorProto{Name: proto.String("Outer")}}
+	m.addMapEntry(&msg{desc: &descriptor.DescriptorProto{Name: proto.String("MapEntry")}})
+
+	fld := &field{desc: &descriptor.FieldDescriptorProto{
+		Name:           proto.String("opt"),
+		Proto3Optional: proto.Bool(true),
+	}}
+	o := &oneof{desc: &descriptor.OneofDescriptorProto{Name: proto.String("_opt")}}
+	m.addField(fld)
+	m.addOneOf(o)
+	o.addField(fld)
+	f.addMessage(m)
+
+	e := &ext{
+		field:  field{desc: &descriptor.FieldDescriptorProto{Name: proto.String("ext")}},
+		parent: dep,
+	}
+	e.setExtendee(m)
+	dep.addDefExtension(e)
+	m.addExtension(e)
+
+	return f
+}
+
+func TestWalk_Options(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		opts     []WalkOption
+		expected []string
+	}{
+		{
+			name:     "default",
+			expected: []string{"file.proto", "Outer", "opt", "_opt"},
+		},
+		{
+			name:     "map entries",
+			opts:     []WalkOption{IncludeMapEntries()},
+			expected: []string{"file.proto", "Outer", "MapEntry", "opt", "_opt"},
+		},
+		{
+			name:     "skip synthetic oneofs",
+			opts:     []WalkOption{SkipSyntheticOneOfs()},
+			expected: []string{"file.proto", "Outer", "opt"},
+		},
+		{
+			name:     "extendee extensions",
+			opts:     []WalkOption{ExtendeeExtensions()},
+			expected: []string{"file.proto", "Outer", "opt", "_opt", "ext"},
+		},
+		{
+			name:     "follow imports",
+			opts:     []WalkOption{FollowImports()},
+			expected: []string{"file.proto", "Outer", "opt", "_opt", "dep.proto", "Dep", "ext"},
+		},
+		{
+			name:     "build targets only",
+			opts:     []WalkOption{FollowImports(), BuildTargetsOnly()},
+			expected: []string{"file.proto", "Outer", "opt", "_opt"},
+		},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := &namingVisitor{}
+			require.NoError(t, Walk(v, dummyWalkTree(), tc.opts...))
+			assert.Equal(t, tc.expected, v.names)
+		})
+	}
+}
+
+func TestWalk_FollowImports_Once(t *testing.T) {
+	t.Parallel()
+
+	f := dummyWalkTree()
+	v := &namingVisitor{}
+	require.NoError(t, Walk(v, f.Package(), FollowImports()))
+
+	assert.Equal(t, []string{
+		"pkg_name", "file.proto", "Outer", "opt", "_opt", "dep.proto", "Dep", "ext",
+		"dep_pkg",
+	}, v.names)
+}
+
+func TestWalk_Options_NilVisitor(t *testing.T) {
+	t.Parallel()
+
+	v := &mockVisitor{}
+	require.NoError(t, Walk(v, dummyWalkTree(), IncludeMapEntries()))
+
+	assert.Equal(t, 1, v.file)
+	assert.Zero(t, v.message)
+}
+
+func TestWalkScoped_Options(t *testing.T) {
+	t.Parallel()
+
+	v := &recordingScopedVisitor{ScopedVisitor: NopScopedVisitor()}
+	require.NoError(t, WalkScoped(v, dummyWalkTree(), IncludeMapEntries(), FollowImports()))
+
+	assert.Equal(t, []string{
+		"enter file.proto 0",
+		"enter Outer 1",
+		"enter MapEntry 2",
+		"leave MapEntry 2",
+		"enter opt 2",
+		"leave opt 2",
+		"leave Outer 1",
+		"enter dep.proto 1",
+		"enter Dep 2",
+		"leave Dep 2",
+		"leave dep.proto 1",
+		"leave file.proto 0",
+	}, v.log)
+}
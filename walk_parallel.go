@@ -0,0 +1,176 @@
+package pgs
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// A VisitorFactory creates a new Visitor. WalkParallel calls the factory once
+// per worker goroutine, so the returned Visitors do not need to be safe for
+// concurrent use.
+type VisitorFactory func() Visitor
+
+// WalkErrors aggregates the errors returned while walking multiple Nodes with
+// WalkParallel. The errors are ordered by the position of the Node that
+// produced them.
+type WalkErrors []error
+
+// Error satisfies the error interface, joining each error's message.
+func (e WalkErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// WalkParallel applies Walk against each of the provided nodes (typically
+// Files or Packages), fanning out across the specified number of worker
+// goroutines. If workers is not positive, runtime.GOMAXPROCS(0) is used. Each
+// worker walks its nodes with a Visitor created by factory. The AST may be
+// shared by the Visitors, as its lazily computed values, such as
+// Message.Dependents and AST.PackageGraph, are safe for concurrent use.
+//
+// An error from one Node does not halt the walk of the others; all errors are
+// returned together as WalkErrors. If ctx is cancelled, no further Nodes are
+// walked, any in-progress walks halt at their next Node, and ctx.Err() is
+// included in the returned WalkErrors.
+func WalkParallel(ctx context.Context, factory VisitorFactory, nodes []Node, workers int, opts ...WalkOption) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(nodes) {
+		workers = len(nodes)
+	}
+
+	errs := make([]error, len(nodes))
+	jobs := make(chan int)
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			v := ctxVisitor{ctx: ctx, v: factory()}
+			for idx := range jobs {
+				errs[idx] = Walk(v, nodes[idx], opts...)
+			}
+		}()
+	}
+
+feed:
+	for i := range nodes {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var out WalkErrors
+	ctxErr := ctx.Err()
+	for _, err := range errs {
+		if err != nil && err != ctxErr {
+			out = append(out, err)
+		}
+	}
+
+	if ctxErr != nil {
+		out = append(out, ctxErr)
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	return out
+}
+
+// ctxVisitor wraps a Visitor, halting the walk once its context is done.
+type ctxVisitor struct {
+	ctx context.Context
+	v   Visitor
+}
+
+func (cv ctxVisitor) wrap(v Visitor, err error) (Visitor, error) {
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return ctxVisitor{ctx: cv.ctx, v: v}, nil
+}
+
+func (cv ctxVisitor) VisitPackage(p Package) (Visitor, error) {
+	if err := cv.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cv.wrap(cv.v.VisitPackage(p))
+}
+
+func (cv ctxVisitor) VisitFile(f File) (Visitor, error) {
+	if err := cv.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cv.wrap(cv.v.VisitFile(f))
+}
+
+func (cv ctxVisitor) VisitMessage(m Message) (Visitor, error) {
+	if err := cv.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cv.wrap(cv.v.VisitMessage(m))
+}
+
+func (cv ctxVisitor) VisitEnum(e Enum) (Visitor, error) {
+	if err := cv.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cv.wrap(cv.v.VisitEnum(e))
+}
+
+func (cv ctxVisitor) VisitEnumValue(e EnumValue) (Visitor, error) {
+	if err := cv.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cv.wrap(cv.v.VisitEnumValue(e))
+}
+
+func (cv ctxVisitor) VisitField(f Field) (Visitor, error) {
+	if err := cv.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cv.wrap(cv.v.VisitField(f))
+}
+
+func (cv ctxVisitor) VisitExtension(e Extension) (Visitor, error) {
+	if err := cv.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cv.wrap(cv.v.VisitExtension(e))
+}
+
+func (cv ctxVisitor) VisitOneOf(o OneOf) (Visitor, error) {
+	if err := cv.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cv.wrap(cv.v.VisitOneOf(o))
+}
+
+func (cv ctxVisitor) VisitService(s Service) (Visitor, error) {
+	if err := cv.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cv.wrap(cv.v.VisitService(s))
+}
+
+func (cv ctxVisitor) VisitMethod(m Method) (Visitor, error) {
+	if err := cv.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cv.wrap(cv.v.VisitMethod(m))
+}
+
+var _ Visitor = ctxVisitor{}
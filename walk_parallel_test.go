@@ -0,0 +1,195 @@
+package pgs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+type countingVisitor struct {
+	Visitor
+
+	mtx      *sync.Mutex
+	messages map[string]int
+	fail     string
+}
+
+func (v countingVisitor) VisitFile(f File) (Visitor, error) {
+	if f.Name().String() == v.fail {
+		return nil, fmt.Errorf("failed %s", f.Name())
+	}
+	return v, nil
+}
+
+func (v countingVisitor) VisitMessage(m Message) (Visitor, error) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	v.messages[m.Name().String()]++
+	return nil, nil
+}
+
+func dummyParallelNodes(n int) []Node {
+	nodes := make([]Node, n)
+	for i := range nodes {
+		f := &file{desc: &descriptor.FileDescriptorProto{Name: proto.String(fmt.Sprintf("f%d.proto", i))}}
+		f.addMessage(&msg{desc: &descriptor.DescriptorProto{Name: proto.String(fmt.Sprintf("M%d", i))}})
+		nodes[i] = f
+	}
+	return nodes
+}
+
+func TestWalkParallel(t *testing.T) {
+	t.Parallel()
+
+	mtx := &sync.Mutex{}
+	messages := map[string]int{}
+	factories := 0
+
+	factory := func() Visitor {
+		mtx.Lock()
+		defer mtx.Unlock()
+		factories++
+		return countingVisitor{Visitor: NilVisitor(), mtx: mtx, messages: messages}
+	}
+
+	nodes := dummyParallelNodes(20)
+	require.NoError(t, WalkParallel(context.Background(), factory, nodes, 4))
+
+	assert.Len(t, messages, 20)
+	for _, n := range messages {
+		assert.Equal(t, 1, n)
+	}
+	assert.Equal(t, 4, factories)
+}
+
+func TestWalkParallel_Errors(t *testing.T) {
+	t.Parallel()
+
+	mtx := &sync.Mutex{}
+	messages := map[string]int{}
+
+	factory := func() Visitor {
+		return countingVisitor{Visitor: NilVisitor(), mtx: mtx, messages: messages, fail: "f3.proto"}
+	}
+
+	err := WalkParallel(context.Background(), factory, dummyParallelNodes(5), 0)
+	require.Error(t, err)
+
+	var errs WalkErrors
+	require.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, err, "failed f3.proto")
+	assert.Len(t, messages, 4, "other nodes are still walked")
+}
+
+func TestWalkParallel_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mtx := &sync.Mutex{}
+	messages := map[string]int{}
+
+	factory := func() Visitor {
+		return countingVisitor{Visitor: NilVisitor(), mtx: mtx, messages: messages}
+	}
+
+	err := WalkParallel(ctx, factory, dummyParallelNodes(10), 2)
+	require.Error(t, err)
+	assert.EqualError(t, err, context.Canceled.Error())
+	assert.Empty(t, messages)
+}
+
+func TestWalkParallel_Empty(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, WalkParallel(context.Background(), NilVisitor, nil, 4))
+}
+
+func TestWalkErrors_Error(t *testing.T) {
+	t.Parallel()
+
+	err := WalkErrors{errors.New("foo"), errors.New("bar")}
+	assert.EqualError(t, err, "foo; bar")
+}
+
+type cacheVisitor struct {
+	Visitor
+	ast AST
+}
+
+func (v cacheVisitor) VisitFile(f File) (Visitor, error) {
+	f.Dependents()
+	return v, nil
+}
+
+func (v cacheVisitor) VisitMessage(m Message) (Visitor, error) {
+	m.Dependents()
+	m.Dependencies()
+	PackageGraphOf(v.ast)
+	return nil, nil
+}
+
+func (v cacheVisitor) VisitEnum(e Enum) (Visitor, error) {
+	e.Dependents()
+	return nil, nil
+}
+
+func TestWalkParallel_LazyCaches(t *testing.T) {
+	t.Parallel()
+
+	shared := &file{desc: &descriptor.FileDescriptorProto{Name: proto.String("shared.proto")}}
+	a := &msg{desc: &descriptor.DescriptorProto{Name: proto.String("A")}, fqn: ".A"}
+	b := &msg{desc: &descriptor.DescriptorProto{Name: proto.String("B")}, fqn: ".B"}
+	e := &enum{desc: &descriptor.EnumDescriptorProto{Name: proto.String("E")}}
+	shared.addMessage(a)
+	shared.addMessage(b)
+	shared.addEnum(e)
+
+	// A and B reference each other, forming a cycle
+	a.addDependency(b)
+	a.addDependent(b)
+	b.addDependency(a)
+	b.addDependent(a)
+	e.addDependent(a)
+
+	nodes := []Node{shared}
+	files := []File{shared}
+	for i := 0; i < 8; i++ {
+		f := &file{desc: &descriptor.FileDescriptorProto{Name: proto.String(fmt.Sprintf("f%d.proto", i))}}
+		f.fileDependencies = []File{shared}
+		shared.dependents = append(shared.dependents, f)
+
+		m := &msg{desc: &descriptor.DescriptorProto{Name: proto.String("M")}, fqn: fmt.Sprintf(".M%d", i)}
+		f.addMessage(m)
+		m.addDependency(a)
+		a.addDependent(m)
+		e.addDependent(m)
+
+		nodes = append(nodes, f)
+		files = append(files, f)
+	}
+
+	ast := &graph{packages: map[string]Package{"": &pkg{fd: shared.desc, files: files}}}
+	for _, f := range files {
+		f.(*file).pkg = ast.packages[""]
+	}
+
+	factory := func() Visitor { return cacheVisitor{Visitor: NilVisitor(), ast: ast} }
+	require.NoError(t, WalkParallel(context.Background(), factory, nodes, 4))
+
+	assert.Len(t, a.Dependents(), 9)
+	assert.Len(t, a.Dependencies(), 1)
+	assert.Len(t, b.Dependents(), 9)
+	assert.Len(t, e.Dependents(), 10)
+	assert.Len(t, shared.Dependents(), 8)
+	assert.Len(t, PackageGraphOf(ast).Packages(), 1)
+}
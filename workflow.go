@@ -41,6 +41,19 @@ func (wf *standardWorkflow) Init(g *Generator) AST {
 		pm(wf.params)
 	}
 
+	if len(wf.transforms) > 0 {
+		wf.Debug("applying transforms")
+		applyTransforms(wf.Debugger, wf.debug, req, wf.params, wf.transforms)
+	}
+
+	if wf.provenance != nil {
+		wf.provenance.setRequest(req, wf.params)
+	}
+
+	if path := wf.params.Archive(); path != "" {
+		wf.persister.SetArchive(path)
+	}
+
 	if wf.BiDi {
 		return ProcessCodeGeneratorRequestBidirectional(g, req)
 	}
@@ -54,6 +67,12 @@ func (wf *standardWorkflow) Run(ast AST) (arts []Artifact) {
 	wf.Debug("initializing modules")
 	for _, m := range wf.mods {
 		m.InitContext(ctx.Push(m.Name()))
+		if fm, ok := m.(fsModule); ok {
+			fm.setFS(wf.fs)
+		}
+		if am, ok := m.(astModule); ok {
+			am.setAST(ast)
+		}
 	}
 
 	wf.Debug("executing modules")
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/protobuf/proto"
 	plugin_go "google.golang.org/protobuf/types/pluginpb"
@@ -49,6 +50,24 @@ func TestStandardWorkflow_Run(t *testing.T) {
 	assert.True(t, m.executed)
 }
 
+func TestStandardWorkflow_Run_BuiltinModules(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	g := Init(FileSystem(fs))
+	g.workflow = &standardWorkflow{Generator: g}
+	g.params = Parameters{}
+
+	m := PackageLayering().(*layeringModule)
+	g.RegisterModule(m)
+
+	ast := &graph{}
+	g.workflow.Run(ast)
+
+	assert.Equal(t, fs, m.fs)
+	assert.Equal(t, ast, m.ast)
+}
+
 func TestStandardWorkflow_Persist(t *testing.T) {
 	t.Parallel()
 
@@ -98,3 +117,39 @@ type dummyWorkflow struct {
 func (wf *dummyWorkflow) Init(g *Generator) AST   { wf.initted = true; return wf.AST }
 func (wf *dummyWorkflow) Run(ast AST) []Artifact  { wf.run = true; return wf.Artifacts }
 func (wf *dummyWorkflow) Persist(arts []Artifact) { wf.persisted = true }
+
+func TestStandardWorkflow_Init_Provenance(t *testing.T) {
+	t.Parallel()
+
+	req := &plugin_go.CodeGeneratorRequest{
+		FileToGenerate:  []string{"foo"},
+		CompilerVersion: &plugin_go.Version{Major: proto.Int32(3)},
+	}
+	b, err := proto.Marshal(req)
+	assert.NoError(t, err)
+
+	g := Init(ProtocInput(bytes.NewReader(b)), ProvenanceHeaders("protoc-gen-foo", ""))
+	g.workflow.Init(g)
+
+	assert.Equal(t, "v3.0.0", g.provenance.compiler)
+	assert.Equal(t, []string{"foo"}, g.provenance.sources)
+}
+
+func TestStandardWorkflow_Init_Archive(t *testing.T) {
+	t.Parallel()
+
+	req := &plugin_go.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo"},
+		Parameter:      proto.String("archive=out/sdk.zip"),
+	}
+	b, err := proto.Marshal(req)
+	assert.NoError(t, err)
+
+	g := Init(ProtocInput(bytes.NewReader(b)))
+	g.workflow.Init(g)
+
+	ar := g.persister.(*stdPersister).archive
+	if assert.NotNil(t, ar) {
+		assert.Equal(t, "out/sdk.zip", ar.path)
+	}
+}